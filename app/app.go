@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"github.com/spf13/viper"
 
@@ -123,6 +124,9 @@ type WasmApp struct {
 	sm *module.SimulationManager
 }
 
+// envWasmQueryDebug overrides the query_debug setting of the wasm config
+const envWasmQueryDebug = "WASMD_WASM_QUERY_DEBUG"
+
 // WasmWrapper allows us to use namespacing in the config file
 // This is only used for parsing in the app, x/wasm expects WasmConfig
 type WasmWrapper struct {
@@ -167,6 +171,7 @@ func NewWasmApp(
 	govSubspace := app.paramsKeeper.Subspace(gov.DefaultParamspace).WithKeyTable(gov.ParamKeyTable())
 	crisisSubspace := app.paramsKeeper.Subspace(crisis.DefaultParamspace)
 	evidenceSubspace := app.paramsKeeper.Subspace(evidence.DefaultParamspace)
+	wasmSubspace := app.paramsKeeper.Subspace(wasm.DefaultParamspace)
 
 	// add keepers
 	app.accountKeeper = auth.NewAccountKeeper(app.cdc, keys[auth.StoreKey], authSubspace, auth.ProtoBaseAccount)
@@ -195,8 +200,14 @@ func NewWasmApp(
 		fmt.Println("error while reading wasm config:", err.Error())
 	}
 	wasmConfig := wasmWrap.Wasm
+	// verbose query errors can be enabled without touching the config file, e.g. on a local dev chain
+	if v, ok := os.LookupEnv(envWasmQueryDebug); ok {
+		if wasmConfig.QueryDebug, err = strconv.ParseBool(v); err != nil {
+			fmt.Printf("error while reading %s: %s\n", envWasmQueryDebug, err.Error())
+		}
+	}
 
-	app.wasmKeeper = wasm.NewKeeper(app.cdc, keys[wasm.StoreKey], app.accountKeeper, app.bankKeeper, wasmRouter, wasmDir, wasmConfig)
+	app.wasmKeeper = wasm.NewKeeper(app.cdc, keys[wasm.StoreKey], wasmSubspace, app.accountKeeper, app.bankKeeper, wasmRouter, wasmDir, wasmConfig)
 
 	// create evidence keeper with evidence router
 	app.evidenceKeeper = evidence.NewKeeper(
@@ -209,8 +220,9 @@ func NewWasmApp(
 	// register the proposal types
 	govRouter := gov.NewRouter()
 	govRouter.AddRoute(gov.RouterKey, gov.ProposalHandler).
-		AddRoute(params.RouterKey, params.NewParamChangeProposalHandler(app.paramsKeeper)).
-		AddRoute(distr.RouterKey, distr.NewCommunityPoolSpendProposalHandler(app.distrKeeper))
+		AddRoute(params.RouterKey, wasm.NewParamChangeProposalHandler(app.wasmKeeper, params.NewParamChangeProposalHandler(app.paramsKeeper))).
+		AddRoute(distr.RouterKey, distr.NewCommunityPoolSpendProposalHandler(app.distrKeeper)).
+		AddRoute(wasm.RouterKey, wasm.NewWasmProposalHandler(app.wasmKeeper))
 	app.govKeeper = gov.NewKeeper(
 		app.cdc, keys[gov.StoreKey], govSubspace,
 		app.supplyKeeper, &stakingKeeper, gov.DefaultCodespace, govRouter,
@@ -242,7 +254,8 @@ func NewWasmApp(
 	// During begin block slashing happens after distr.BeginBlocker so that
 	// there is nothing left over in the validator fee pool, so as to keep the
 	// CanWithdrawInvariant invariant.
-	app.mm.SetOrderBeginBlockers(mint.ModuleName, distr.ModuleName, slashing.ModuleName)
+	// The wasm module resets the index of the contracts executed in the block.
+	app.mm.SetOrderBeginBlockers(mint.ModuleName, distr.ModuleName, slashing.ModuleName, wasm.ModuleName)
 
 	app.mm.SetOrderEndBlockers(crisis.ModuleName, gov.ModuleName, staking.ModuleName)
 
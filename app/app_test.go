@@ -1,17 +1,26 @@
 package app
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"os"
 	"testing"
 
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/require"
 	"github.com/tendermint/tendermint/libs/log"
 	db "github.com/tendermint/tm-db"
 
 	"github.com/cosmos/cosmos-sdk/codec"
 	"github.com/cosmos/cosmos-sdk/simapp"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+	"github.com/cosmos/cosmos-sdk/x/params"
 
 	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmwasm/wasmd/x/wasm"
 )
 
 func TestWasmdExport(t *testing.T) {
@@ -36,6 +45,178 @@ func TestBlackListedAddrs(t *testing.T) {
 	}
 }
 
+func TestWasmParamChangeProposal(t *testing.T) {
+	db := db.NewMemDB()
+	gapp := NewWasmApp(log.NewTMLogger(log.NewSyncWriter(os.Stdout)), db, nil, true, 0)
+	require.NoError(t, setGenesis(gapp))
+	ctx := gapp.BaseApp.NewContext(true, abci.Header{})
+	gapp.wasmKeeper.SetParams(ctx, wasm.DefaultParams())
+
+	h := wasm.NewParamChangeProposalHandler(gapp.wasmKeeper, params.NewParamChangeProposalHandler(gapp.paramsKeeper))
+	specs := map[string]struct {
+		srcKey             []byte
+		srcValue           string
+		expErr             bool
+		expMaxWasmCodeSize uint64
+	}{
+		"valid code size": {
+			srcKey:             wasm.ParamStoreKeyMaxWasmCodeSize,
+			srcValue:           `"1000"`,
+			expMaxWasmCodeSize: 1000,
+		},
+		"zero code size": {
+			srcKey:             wasm.ParamStoreKeyMaxWasmCodeSize,
+			srcValue:           `"0"`,
+			expErr:             true,
+			expMaxWasmCodeSize: wasm.DefaultMaxWasmCodeSize,
+		},
+		"zero init msg size": {
+			srcKey:             wasm.ParamStoreKeyMaxInitMsgSize,
+			srcValue:           `"0"`,
+			expErr:             true,
+			expMaxWasmCodeSize: wasm.DefaultMaxWasmCodeSize,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			ctx, _ := ctx.CacheContext()
+			proposal := params.ParameterChangeProposal{
+				Title:       "Foo",
+				Description: "Bar",
+				Changes: []params.ParamChange{{
+					Subspace: wasm.DefaultParamspace,
+					Key:      string(spec.srcKey),
+					Value:    spec.srcValue,
+				}},
+			}
+			err := h(ctx, proposal)
+			if spec.expErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			p := gapp.wasmKeeper.GetParams(ctx)
+			require.NoError(t, p.ValidateBasic())
+			require.Equal(t, spec.expMaxWasmCodeSize, p.MaxWasmCodeSize)
+		})
+	}
+}
+
+func TestWasmQueryContractStateAtHeight(t *testing.T) {
+	db := db.NewMemDB()
+	gapp := NewWasmApp(log.NewTMLogger(log.NewSyncWriter(os.Stdout)), db, nil, true, 0)
+	require.NoError(t, setGenesis(gapp))
+
+	wasmCode, err := ioutil.ReadFile("../x/wasm/internal/keeper/testdata/contract.wasm")
+	require.NoError(t, err)
+	creator := sdk.AccAddress([]byte("creator_____________"))
+	initMsg, err := json.Marshal(map[string]sdk.AccAddress{"verifier": creator, "beneficiary": creator})
+	require.NoError(t, err)
+
+	// block with the old state
+	ctx := beginBlock(gapp)
+	gapp.wasmKeeper.SetParams(ctx, wasm.DefaultParams())
+	acc := auth.NewBaseAccountWithAddress(creator)
+	gapp.accountKeeper.SetAccount(ctx, &acc)
+	codeID, err := gapp.wasmKeeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+	contractAddr, err := gapp.wasmKeeper.Instantiate(ctx, codeID, creator, nil, initMsg, "demo contract", false, nil)
+	require.NoError(t, err)
+	require.NoError(t, gapp.wasmKeeper.ImportContractState(ctx, contractAddr, []wasm.Model{{Key: []byte("foo"), Value: []byte("old")}}))
+	oldHeight := endBlock(gapp)
+
+	// block with the new state
+	ctx = beginBlock(gapp)
+	require.NoError(t, gapp.wasmKeeper.ImportContractState(ctx, contractAddr, []wasm.Model{{Key: []byte("foo"), Value: []byte("new")}}))
+	newHeight := endBlock(gapp)
+
+	specs := map[string]struct {
+		srcHeight int64
+		expValue  string
+	}{
+		"old height":    {srcHeight: oldHeight, expValue: "old"},
+		"new height":    {srcHeight: newHeight, expValue: "new"},
+		"latest height": {srcHeight: 0, expValue: "new"},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			res := gapp.Query(abci.RequestQuery{
+				Path:   fmt.Sprintf("custom/%s/%s/%s/%s", wasm.QuerierRoute, wasm.QueryGetContractState, contractAddr, wasm.QueryMethodContractStateRaw),
+				Data:   []byte("foo"),
+				Height: spec.srcHeight,
+			})
+			require.True(t, res.IsOK(), res.Log)
+			var models []wasm.Model
+			require.NoError(t, json.Unmarshal(res.Value, &models))
+			require.Len(t, models, 1)
+			require.Equal(t, spec.expValue, string(models[0].Value))
+		})
+	}
+}
+
+func TestWasmExecutedThisBlockIsReset(t *testing.T) {
+	viper.Set("wasm.executed_contracts_index", true)
+	defer viper.Set("wasm.executed_contracts_index", false)
+	db := db.NewMemDB()
+	gapp := NewWasmApp(log.NewTMLogger(log.NewSyncWriter(os.Stdout)), db, nil, true, 0)
+	require.NoError(t, setGenesis(gapp))
+
+	wasmCode, err := ioutil.ReadFile("../x/wasm/internal/keeper/testdata/contract.wasm")
+	require.NoError(t, err)
+	creator := sdk.AccAddress([]byte("creator_____________"))
+	initMsg, err := json.Marshal(map[string]sdk.AccAddress{"verifier": creator, "beneficiary": creator})
+	require.NoError(t, err)
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+
+	queryExecuted := func() wasm.ExecutedThisBlockResponse {
+		res := gapp.Query(abci.RequestQuery{Path: fmt.Sprintf("custom/%s/%s", wasm.QuerierRoute, wasm.QueryExecutedThisBlock)})
+		require.True(t, res.IsOK(), res.Log)
+		var executed wasm.ExecutedThisBlockResponse
+		require.NoError(t, json.Unmarshal(res.Value, &executed))
+		return executed
+	}
+
+	// block with an execution
+	ctx := beginBlock(gapp)
+	gapp.wasmKeeper.SetParams(ctx, wasm.DefaultParams())
+	acc := auth.NewBaseAccountWithAddress(creator)
+	require.NoError(t, acc.SetCoins(deposit))
+	gapp.accountKeeper.SetAccount(ctx, &acc)
+	codeID, err := gapp.wasmKeeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+	contractAddr, err := gapp.wasmKeeper.Instantiate(ctx, codeID, creator, nil, initMsg, "demo contract", false, deposit)
+	require.NoError(t, err)
+	_, err = gapp.wasmKeeper.Execute(ctx, contractAddr, creator, []byte(`{}`), nil)
+	require.NoError(t, err)
+	execHeight := endBlock(gapp)
+
+	executed := queryExecuted()
+	require.Equal(t, execHeight, executed.Height)
+	require.Equal(t, []string{contractAddr.String()}, executed.Contracts)
+
+	// empty block
+	beginBlock(gapp)
+	emptyHeight := endBlock(gapp)
+
+	executed = queryExecuted()
+	require.Equal(t, emptyHeight, executed.Height)
+	require.Empty(t, executed.Contracts)
+}
+
+// beginBlock starts the next block and returns a context on its deliver state
+func beginBlock(gapp *WasmApp) sdk.Context {
+	header := abci.Header{Height: gapp.LastBlockHeight() + 1}
+	gapp.BeginBlock(abci.RequestBeginBlock{Header: header})
+	return gapp.BaseApp.NewContext(false, header)
+}
+
+// endBlock ends and commits the current block and returns its height
+func endBlock(gapp *WasmApp) int64 {
+	gapp.EndBlock(abci.RequestEndBlock{})
+	gapp.Commit()
+	return gapp.LastBlockHeight()
+}
+
 func setGenesis(gapp *WasmApp) error {
 	genesisState := simapp.NewDefaultGenesisState()
 	stateBytes, err := codec.MarshalJSONIndent(gapp.cdc, genesisState)
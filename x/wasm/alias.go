@@ -11,74 +11,252 @@ import (
 )
 
 const (
-	ModuleName                    = types.ModuleName
-	StoreKey                      = types.StoreKey
-	TStoreKey                     = types.TStoreKey
-	QuerierRoute                  = types.QuerierRoute
-	RouterKey                     = types.RouterKey
-	MaxWasmSize                   = types.MaxWasmSize
-	GasMultiplier                 = keeper.GasMultiplier
-	MaxGas                        = keeper.MaxGas
-	QueryListContracts            = keeper.QueryListContracts
-	QueryGetContract              = keeper.QueryGetContract
-	QueryGetContractState         = keeper.QueryGetContractState
-	QueryGetCode                  = keeper.QueryGetCode
-	QueryListCode                 = keeper.QueryListCode
-	QueryMethodContractStateSmart = keeper.QueryMethodContractStateSmart
-	QueryMethodContractStateAll   = keeper.QueryMethodContractStateAll
-	QueryMethodContractStateRaw   = keeper.QueryMethodContractStateRaw
+	ModuleName                           = types.ModuleName
+	StoreKey                             = types.StoreKey
+	TStoreKey                            = types.TStoreKey
+	QuerierRoute                         = types.QuerierRoute
+	RouterKey                            = types.RouterKey
+	ProposalTypeStoreCode                = types.ProposalTypeStoreCode
+	ProposalTypeMigrateContract          = types.ProposalTypeMigrateContract
+	ProposalTypePinCodes                 = types.ProposalTypePinCodes
+	ProposalTypeUnpinCodes               = types.ProposalTypeUnpinCodes
+	ProposalTypeImportContractState      = types.ProposalTypeImportContractState
+	ProposalTypeUpdateInstantiateConfig  = types.ProposalTypeUpdateInstantiateConfig
+	ProposalTypeDeprecateCode            = types.ProposalTypeDeprecateCode
+	ProposalTypeMigrateAllContracts      = types.ProposalTypeMigrateAllContracts
+	DefaultParamspace                    = types.DefaultParamspace
+	DefaultMaxWasmCodeSize               = types.DefaultMaxWasmCodeSize
+	DefaultMaxInitMsgSize                = types.DefaultMaxInitMsgSize
+	DefaultMaxExecuteMsgSize             = types.DefaultMaxExecuteMsgSize
+	DefaultUploadGasPerByte              = types.DefaultUploadGasPerByte
+	DefaultMaxLabelSize                  = types.DefaultMaxLabelSize
+	DefaultMaxFundsCoins                 = types.DefaultMaxFundsCoins
+	DefaultContractStoreReadGasPerByte   = types.DefaultContractStoreReadGasPerByte
+	DefaultContractStoreWriteGasPerByte  = types.DefaultContractStoreWriteGasPerByte
+	DefaultMaxContractGas                = types.DefaultMaxContractGas
+	DefaultMaxQueryResultEntries         = types.DefaultMaxQueryResultEntries
+	StakingMsgTypeDelegate               = types.StakingMsgTypeDelegate
+	StakingMsgTypeUndelegate             = types.StakingMsgTypeUndelegate
+	StakingMsgTypeRedelegate             = types.StakingMsgTypeRedelegate
+	MaxLabelSize                         = types.MaxLabelSize
+	MaxFundsCoins                        = types.MaxFundsCoins
+	MaxFundsAmountBits                   = types.MaxFundsAmountBits
+	BuildTagRegex                        = types.BuildTagRegex
+	BuildImageName                       = types.BuildImageName
+	MaxSaltSize                          = types.MaxSaltSize
+	AccessTypeNobody                     = types.AccessTypeNobody
+	AccessTypeOnlyAddress                = types.AccessTypeOnlyAddress
+	AccessTypeEverybody                  = types.AccessTypeEverybody
+	GasMultiplier                        = keeper.GasMultiplier
+	MaxGas                               = keeper.MaxGas
+	QueryListContracts                   = keeper.QueryListContracts
+	QueryListContractsByCode             = keeper.QueryListContractsByCode
+	QueryContractsByCodeDetail           = keeper.QueryContractsByCodeDetail
+	QueryContractsCreatedAfter           = keeper.QueryContractsCreatedAfter
+	QueryGetContract                     = keeper.QueryGetContract
+	QueryGetContractInfos                = keeper.QueryGetContractInfos
+	QueryContractByLabel                 = keeper.QueryContractByLabel
+	QueryGetContractState                = keeper.QueryGetContractState
+	QueryContractStateJSON               = keeper.QueryContractStateJSON
+	QueryContractHistory                 = keeper.QueryContractHistory
+	QueryContractBalance                 = keeper.QueryContractBalance
+	QueryContractCodeInfo                = keeper.QueryContractCodeInfo
+	QueryContractCodeIDs                 = keeper.QueryContractCodeIDs
+	QueryContractMigratable              = keeper.QueryContractMigratable
+	QuerySimulateExecute                 = keeper.QuerySimulateExecute
+	QueryContractMetrics                 = keeper.QueryContractMetrics
+	QueryExecutedThisBlock               = keeper.QueryExecutedThisBlock
+	QueryGetCode                         = keeper.QueryGetCode
+	QueryGetCodeInfo                     = keeper.QueryGetCodeInfo
+	QueryListCode                        = keeper.QueryListCode
+	QueryListCodeByCreator               = keeper.QueryListCodeByCreator
+	QueryCodeByHash                      = keeper.QueryCodeByHash
+	QueryContractsCount                  = keeper.QueryContractsCount
+	QueryCodesCount                      = keeper.QueryCodesCount
+	QueryCodeUsage                       = keeper.QueryCodeUsage
+	QueryCodeStorageStats                = keeper.QueryCodeStorageStats
+	QueryPinnedCodes                     = keeper.QueryPinnedCodes
+	QueryOrphanCodes                     = keeper.QueryOrphanCodes
+	QueryCodePinned                      = keeper.QueryCodePinned
+	QueryCodeHasBytecode                 = keeper.QueryCodeHasBytecode
+	QueryCodeVerification                = keeper.QueryCodeVerification
+	QueryMethodContractStateSmart        = keeper.QueryMethodContractStateSmart
+	QueryMethodContractStateAll          = keeper.QueryMethodContractStateAll
+	QueryMethodContractStateAllPrefix    = keeper.QueryMethodContractStateAllPrefix
+	QueryMethodContractStateAllPaginated = keeper.QueryMethodContractStateAllPaginated
+	QueryMethodContractStateRaw          = keeper.QueryMethodContractStateRaw
+	QueryMethodContractStateRawBatch     = keeper.QueryMethodContractStateRawBatch
+	InitContractCodeHistoryType          = types.InitContractCodeHistoryType
+	MigrateContractCodeHistoryType       = types.MigrateContractCodeHistoryType
+	GenesisContractCodeHistoryType       = types.GenesisContractCodeHistoryType
+	EventTypeStoreCode                   = types.EventTypeStoreCode
+	EventTypeInstantiate                 = types.EventTypeInstantiate
+	EventTypeExecute                     = types.EventTypeExecute
+	EventTypeMigrate                     = types.EventTypeMigrate
+	EventTypePinCode                     = types.EventTypePinCode
+	EventTypeUnpinCode                   = types.EventTypeUnpinCode
+	EventTypeImportContractState         = types.EventTypeImportContractState
+	EventTypeUpdateInstantiateConfig     = types.EventTypeUpdateInstantiateConfig
+	EventTypeDeprecateCode               = types.EventTypeDeprecateCode
+	EventTypeInstantiateDeprecated       = types.EventTypeInstantiateDeprecated
+	EventTypeContractReentrancy          = types.EventTypeContractReentrancy
+	AttributeKeyContract                 = types.AttributeKeyContract
+	AttributeKeyCodeID                   = types.AttributeKeyCodeID
+	AttributeKeyCreator                  = types.AttributeKeyCreator
+	AttributeKeySender                   = types.AttributeKeySender
+	AttributeKeyCodeReused               = types.AttributeKeyCodeReused
+	AttributeKeyFunds                    = types.AttributeKeyFunds
 )
 
 var (
 	// functions aliases
-	RegisterCodec             = types.RegisterCodec
-	ValidateGenesis           = types.ValidateGenesis
-	GetCodeKey                = types.GetCodeKey
-	GetContractAddressKey     = types.GetContractAddressKey
-	GetContractStorePrefixKey = types.GetContractStorePrefixKey
-	NewCodeInfo               = types.NewCodeInfo
-	NewParams                 = types.NewParams
-	NewWasmCoins              = types.NewWasmCoins
-	NewContractInfo           = types.NewContractInfo
-	CosmosResult              = types.CosmosResult
-	DefaultWasmConfig         = types.DefaultWasmConfig
-	InitGenesis               = keeper.InitGenesis
-	ExportGenesis             = keeper.ExportGenesis
-	NewKeeper                 = keeper.NewKeeper
-	NewQuerier                = keeper.NewQuerier
-	MakeTestCodec             = keeper.MakeTestCodec
-	CreateTestInput           = keeper.CreateTestInput
+	RegisterCodec                           = types.RegisterCodec
+	ValidateGenesis                         = types.ValidateGenesis
+	ValidateModels                          = types.ValidateModels
+	ValidateBuilder                         = types.ValidateBuilder
+	GetCodeKey                              = types.GetCodeKey
+	GetContractAddressKey                   = types.GetContractAddressKey
+	GetContractStorePrefixKey               = types.GetContractStorePrefixKey
+	GetContractByCodeIDSecondaryIndexPrefix = types.GetContractByCodeIDSecondaryIndexPrefix
+	GetContractByCodeIDSecondaryIndexKey    = types.GetContractByCodeIDSecondaryIndexKey
+	NewCodeInfo                             = types.NewCodeInfo
+	NewParams                               = types.NewParams
+	NewWasmCoins                            = types.NewWasmCoins
+	NewContractInfo                         = types.NewContractInfo
+	NewAbsoluteTxPosition                   = types.NewAbsoluteTxPosition
+	NewMultiWasmHooks                       = types.NewMultiWasmHooks
+	OnlyAddress                             = types.OnlyAddress
+	GetContractHistoryStoreKey              = types.GetContractHistoryStoreKey
+	GetPinnedCodeIndexPrefix                = types.GetPinnedCodeIndexPrefix
+	GetCodeByCreatorSecondaryIndexPrefix    = types.GetCodeByCreatorSecondaryIndexPrefix
+	GetCodeByCreatorSecondaryIndexKey       = types.GetCodeByCreatorSecondaryIndexKey
+	GetCodeByHashSecondaryIndexPrefix       = types.GetCodeByHashSecondaryIndexPrefix
+	GetCodeByHashSecondaryIndexKey          = types.GetCodeByHashSecondaryIndexKey
+	GetContractByLabelSecondaryIndexPrefix  = types.GetContractByLabelSecondaryIndexPrefix
+	GetContractByLabelSecondaryIndexKey     = types.GetContractByLabelSecondaryIndexKey
+	GetContractCreatedSecondaryIndexKey     = types.GetContractCreatedSecondaryIndexKey
+	CosmosResult                            = types.CosmosResult
+	DefaultWasmConfig                       = types.DefaultWasmConfig
+	ParamKeyTable                           = types.ParamKeyTable
+	DefaultParams                           = types.DefaultParams
+	ToSDKError                              = types.ToSDKError
+	InitGenesis                             = keeper.InitGenesis
+	ExportGenesis                           = keeper.ExportGenesis
+	NewKeeper                               = keeper.NewKeeper
+	PredictableContractAddress              = keeper.PredictableContractAddress
+	NewQuerier                              = keeper.NewQuerier
+	MakeTestCodec                           = keeper.MakeTestCodec
+	CreateTestInput                         = keeper.CreateTestInput
 
 	// variable aliases
-	ModuleCdc            = types.ModuleCdc
-	DefaultCodespace     = types.DefaultCodespace
-	ErrCreateFailed      = types.ErrCreateFailed
-	ErrAccountExists     = types.ErrAccountExists
-	ErrInstantiateFailed = types.ErrInstantiateFailed
-	ErrExecuteFailed     = types.ErrExecuteFailed
-	ErrGasLimit          = types.ErrGasLimit
-	ErrInvalidGenesis    = types.ErrInvalidGenesis
-	ErrNotFound          = types.ErrNotFound
-	ErrQueryFailed       = types.ErrQueryFailed
-	KeyLastCodeID        = types.KeyLastCodeID
-	KeyLastInstanceID    = types.KeyLastInstanceID
-	CodeKeyPrefix        = types.CodeKeyPrefix
-	ContractKeyPrefix    = types.ContractKeyPrefix
-	ContractStorePrefix  = types.ContractStorePrefix
+	ModuleCdc                            = types.ModuleCdc
+	DefaultCodespace                     = types.DefaultCodespace
+	ErrCreateFailed                      = types.ErrCreateFailed
+	ErrAccountExists                     = types.ErrAccountExists
+	ErrInstantiateFailed                 = types.ErrInstantiateFailed
+	ErrExecuteFailed                     = types.ErrExecuteFailed
+	ErrGasLimit                          = types.ErrGasLimit
+	ErrInvalidGenesis                    = types.ErrInvalidGenesis
+	ErrNotFound                          = types.ErrNotFound
+	ErrQueryFailed                       = types.ErrQueryFailed
+	ErrMigrationFailed                   = types.ErrMigrationFailed
+	ErrInvalidWasmCode                   = types.ErrInvalidWasmCode
+	ErrCodeTooLarge                      = types.ErrCodeTooLarge
+	ErrInvalidSource                     = types.ErrInvalidSource
+	ErrInvalidBuilder                    = types.ErrInvalidBuilder
+	ErrInvalidLabel                      = types.ErrInvalidLabel
+	ErrInvalidMsg                        = types.ErrInvalidMsg
+	ErrMaxContractGas                    = types.ErrMaxContractGas
+	ErrQueryResultTooLarge               = types.ErrQueryResultTooLarge
+	ErrBytecodePruned                    = types.ErrBytecodePruned
+	ErrReentrancy                        = types.ErrReentrancy
+	KeyLastCodeID                        = types.KeyLastCodeID
+	KeyLastInstanceID                    = types.KeyLastInstanceID
+	CodeKeyPrefix                        = types.CodeKeyPrefix
+	ContractKeyPrefix                    = types.ContractKeyPrefix
+	ContractStorePrefix                  = types.ContractStorePrefix
+	ContractByCodeIDSecondaryIndexPrefix = types.ContractByCodeIDSecondaryIndexPrefix
+	ContractHistoryStorePrefix           = types.ContractHistoryStorePrefix
+	PinnedCodeIndexPrefix                = types.PinnedCodeIndexPrefix
+	CodeByCreatorSecondaryIndexPrefix    = types.CodeByCreatorSecondaryIndexPrefix
+	CodeByHashSecondaryIndexPrefix       = types.CodeByHashSecondaryIndexPrefix
+	ContractByLabelSecondaryIndexPrefix  = types.ContractByLabelSecondaryIndexPrefix
+	ContractCreatedSecondaryIndexPrefix  = types.ContractCreatedSecondaryIndexPrefix
+	ParamStoreKeyMaxWasmCodeSize         = types.ParamStoreKeyMaxWasmCodeSize
+	ParamStoreKeyMaxInitMsgSize          = types.ParamStoreKeyMaxInitMsgSize
+	ParamStoreKeyMaxExecuteMsgSize       = types.ParamStoreKeyMaxExecuteMsgSize
+	ParamStoreKeyUploadGasPerByte        = types.ParamStoreKeyUploadGasPerByte
+	AllowEverybody                       = types.AllowEverybody
+	AllowNobody                          = types.AllowNobody
 )
 
 type (
-	GenesisState           = types.GenesisState
-	Code                   = types.Code
-	Contract               = types.Contract
-	MsgStoreCode           = types.MsgStoreCode
-	MsgInstantiateContract = types.MsgInstantiateContract
-	MsgExecuteContract     = types.MsgExecuteContract
-	Model                  = types.Model
-	CodeInfo               = types.CodeInfo
-	ContractInfo           = types.ContractInfo
-	WasmConfig             = types.WasmConfig
-	Keeper                 = keeper.Keeper
-	GetCodeResponse        = keeper.GetCodeResponse
-	ListCodeResponse       = keeper.ListCodeResponse
+	GenesisState                     = types.GenesisState
+	Code                             = types.Code
+	Contract                         = types.Contract
+	MsgStoreCode                     = types.MsgStoreCode
+	MsgInstantiateContract           = types.MsgInstantiateContract
+	MsgStoreCodeAndInstantiate       = types.MsgStoreCodeAndInstantiate
+	MsgInstantiateContract2          = types.MsgInstantiateContract2
+	MsgExecuteContract               = types.MsgExecuteContract
+	MsgMigrateContract               = types.MsgMigrateContract
+	MsgUpdateAdmin                   = types.MsgUpdateAdmin
+	MsgClearAdmin                    = types.MsgClearAdmin
+	MsgUpdateInstantiateConfig       = types.MsgUpdateInstantiateConfig
+	MsgDeprecateCode                 = types.MsgDeprecateCode
+	StoreCodeProposal                = types.StoreCodeProposal
+	MigrateContractProposal          = types.MigrateContractProposal
+	PinCodesProposal                 = types.PinCodesProposal
+	UnpinCodesProposal               = types.UnpinCodesProposal
+	ImportContractStateProposal      = types.ImportContractStateProposal
+	UpdateInstantiateConfigProposal  = types.UpdateInstantiateConfigProposal
+	DeprecateCodeProposal            = types.DeprecateCodeProposal
+	MigrateAllContractsProposal      = types.MigrateAllContractsProposal
+	Model                            = types.Model
+	CodeInfo                         = types.CodeInfo
+	CodeStorageStats                 = types.CodeStorageStats
+	ContractInfo                     = types.ContractInfo
+	AbsoluteTxPosition               = types.AbsoluteTxPosition
+	WasmHooks                        = types.WasmHooks
+	MultiWasmHooks                   = types.MultiWasmHooks
+	Sequence                         = types.Sequence
+	AccessType                       = types.AccessType
+	AccessConfig                     = types.AccessConfig
+	ContractCodeHistoryEntry         = types.ContractCodeHistoryEntry
+	ContractCodeHistoryOperationType = types.ContractCodeHistoryOperationType
+	WasmConfig                       = types.WasmConfig
+	Params                           = types.Params
+	Keeper                           = keeper.Keeper
+	GetCodeRequest                   = keeper.GetCodeRequest
+	GetCodeResponse                  = keeper.GetCodeResponse
+	CountResponse                    = keeper.CountResponse
+	PinnedCodesResponse              = keeper.PinnedCodesResponse
+	OrphanCodesResponse              = keeper.OrphanCodesResponse
+	CodePinnedResponse               = keeper.CodePinnedResponse
+	CodeHasBytecodeResponse          = keeper.CodeHasBytecodeResponse
+	CodeVerificationResponse         = keeper.CodeVerificationResponse
+	SimulateExecuteResponse          = keeper.SimulateExecuteResponse
+	ContractMetricsResponse          = keeper.ContractMetricsResponse
+	ExecutedThisBlockResponse        = keeper.ExecutedThisBlockResponse
+	CodeMetrics                      = keeper.CodeMetrics
+	ContractMetrics                  = keeper.ContractMetrics
+	InvocationStats                  = keeper.InvocationStats
+	ContractByLabelResponse          = keeper.ContractByLabelResponse
+	ListCodeResponse                 = keeper.ListCodeResponse
+	ListCodeRequest                  = keeper.ListCodeRequest
+	CodeListResponse                 = keeper.CodeListResponse
+	ListContractsRequest             = keeper.ListContractsRequest
+	ContractListResponse             = keeper.ContractListResponse
+	ListContractsByCodeRequest       = keeper.ListContractsByCodeRequest
+	ContractsByCodeResponse          = keeper.ContractsByCodeResponse
+	ContractSummariesResponse        = keeper.ContractSummariesResponse
+	ContractStatePageRequest         = keeper.ContractStatePageRequest
+	ContractStatePageResponse        = keeper.ContractStatePageResponse
+	ContractsCreatedAfterRequest     = keeper.ContractsCreatedAfterRequest
+	ContractsCreatedAfterResponse    = keeper.ContractsCreatedAfterResponse
+	ContractInfoWithAddress          = keeper.ContractInfoWithAddress
+	ContractSummary                  = keeper.ContractSummary
+	ContractCodeInfoResponse         = keeper.ContractCodeInfoResponse
+	ContractCodeIDsResponse          = keeper.ContractCodeIDsResponse
+	ContractMigratableResponse       = keeper.ContractMigratableResponse
 )
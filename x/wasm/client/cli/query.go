@@ -1,6 +1,8 @@
 package cli
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
@@ -22,6 +24,17 @@ import (
 	"github.com/cosmwasm/wasmd/x/wasm/internal/types"
 )
 
+const (
+	flagPage     = "page"
+	flagOffset   = "offset"
+	flagLimit    = "limit"
+	flagWithInfo = "with-info"
+
+	flagCompressed = "compressed"
+
+	flagStartAfterKey = "start-after-key"
+)
+
 func GetQueryCmd(cdc *codec.Codec) *cobra.Command {
 	queryCmd := &cobra.Command{
 		Use:                        types.ModuleName,
@@ -30,27 +43,636 @@ func GetQueryCmd(cdc *codec.Codec) *cobra.Command {
 		SuggestionsMinimumDistance: 2,
 		RunE:                       client.ValidateCmd,
 	}
-	queryCmd.AddCommand(client.GetCommands(
-		GetCmdListCode(cdc),
-		GetCmdQueryCode(cdc),
-		GetCmdListContracts(cdc),
-		GetCmdGetContractInfo(cdc),
-		GetCmdGetContractState(cdc),
-	)...)
-	return queryCmd
+	queryCmd.AddCommand(client.GetCommands(
+		GetCmdListCode(cdc),
+		GetCmdQueryCode(cdc),
+		GetCmdQueryCodeInfo(cdc),
+		GetCmdListCodeByCreator(cdc),
+		GetCmdQueryCodeByHash(cdc),
+		GetCmdListPinnedCode(cdc),
+		GetCmdListOrphanCode(cdc),
+		GetCmdQueryCodePinned(cdc),
+		GetCmdQueryCodeHasBytecode(cdc),
+		GetCmdQueryCodeVerification(cdc),
+		GetCmdQueryCodeUsage(cdc),
+		GetCmdListContracts(cdc),
+		GetCmdListContractByCode(cdc),
+		GetCmdListContractsByCodeDetailed(cdc),
+		GetCmdListContractsCreatedAfter(cdc),
+		GetCmdGetContractInfo(cdc),
+		GetCmdGetContractInfos(cdc),
+		GetCmdGetContractByLabel(cdc),
+		GetCmdGetContractHistory(cdc),
+		GetCmdGetContractBalance(cdc),
+		GetCmdGetContractCodeInfo(cdc),
+		GetCmdGetContractCodeIDs(cdc),
+		GetCmdGetContractMigratable(cdc),
+		GetCmdGetContractState(cdc),
+		GetCmdSimulateExecute(cdc),
+		GetCmdQueryContractMetrics(cdc),
+		GetCmdQueryExecutedThisBlock(cdc),
+		GetCmdQueryCodeStorageStats(cdc),
+	)...)
+	return queryCmd
+}
+
+// GetCmdListCode lists all wasm code uploaded
+func GetCmdListCode(cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list-code",
+		Short: "List all wasm bytecode on the chain",
+		Long:  "List all wasm bytecode on the chain",
+		Args:  cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+
+			queryData, err := codeListPageData(cmd)
+			if err != nil {
+				return err
+			}
+
+			route := fmt.Sprintf("custom/%s/%s", types.QuerierRoute, keeper.QueryListCode)
+			res, _, err := cliCtx.QueryWithData(route, queryData)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(res))
+			return nil
+		},
+	}
+	cmd.Flags().Uint64(flagOffset, 0, "Number of results to skip")
+	cmd.Flags().Uint64(flagLimit, 100, "Query number of results returned")
+	return cmd
+}
+
+// codeListPageData encodes the offset and limit flags of a code listing command as query data
+func codeListPageData(cmd *cobra.Command) ([]byte, error) {
+	offset, err := cmd.Flags().GetUint64(flagOffset)
+	if err != nil {
+		return nil, err
+	}
+	limit, err := cmd.Flags().GetUint64(flagLimit)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(keeper.ListCodeRequest{Offset: offset, Limit: limit})
+}
+
+// GetCmdQueryCode returns the bytecode for a given contract
+func GetCmdQueryCode(cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "code [code_id] [output filename]",
+		Short: "Downloads wasm bytecode for given code id",
+		Long:  "Downloads wasm bytecode for given code id",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+
+			codeID, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return err
+			}
+			compressed, err := cmd.Flags().GetBool(flagCompressed)
+			if err != nil {
+				return err
+			}
+			queryData, err := json.Marshal(keeper.GetCodeRequest{Compressed: compressed})
+			if err != nil {
+				return err
+			}
+
+			route := fmt.Sprintf("custom/%s/%s/%d", types.QuerierRoute, keeper.QueryGetCode, codeID)
+			res, _, err := cliCtx.QueryWithData(route, queryData)
+			if err != nil {
+				return err
+			}
+
+			if len(res) == 0 {
+				return fmt.Errorf("contract not found")
+			}
+			var code keeper.GetCodeResponse
+			err = json.Unmarshal(res, &code)
+			if err != nil {
+				return err
+			}
+
+			if len(code.Code) == 0 {
+				return fmt.Errorf("contract not found")
+			}
+			if code.Compressed {
+				zr, err := gzip.NewReader(bytes.NewReader(code.Code))
+				if err != nil {
+					return err
+				}
+				if code.Code, err = ioutil.ReadAll(zr); err != nil {
+					return err
+				}
+			}
+
+			fmt.Printf("Downloading wasm code to %s\n", args[1])
+			return ioutil.WriteFile(args[1], code.Code, 0644)
+		},
+	}
+	cmd.Flags().Bool(flagCompressed, false, "Transfer the bytecode gzip compressed and uncompress it locally")
+	return cmd
+}
+
+// GetCmdQueryCodeInfo returns the metadata for a given code without the bytecode
+func GetCmdQueryCodeInfo(cdc *codec.Codec) *cobra.Command {
+	return &cobra.Command{
+		Use:   "code-info [code_id]",
+		Short: "Prints out metadata of a code id",
+		Long:  "Prints out metadata of a code id",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+
+			codeID, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return err
+			}
+
+			route := fmt.Sprintf("custom/%s/%s/%d", types.QuerierRoute, keeper.QueryGetCodeInfo, codeID)
+			res, _, err := cliCtx.Query(route)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(res))
+			return nil
+		},
+	}
+}
+
+// GetCmdListCodeByCreator lists the wasm code uploaded by a creator
+func GetCmdListCodeByCreator(cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list-code-by-creator [bech32_address]",
+		Short: "List wasm bytecode uploaded by the given creator",
+		Long:  "List wasm bytecode uploaded by the given creator",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+
+			creator, err := sdk.AccAddressFromBech32(args[0])
+			if err != nil {
+				return err
+			}
+			queryData, err := codeListPageData(cmd)
+			if err != nil {
+				return err
+			}
+
+			route := fmt.Sprintf("custom/%s/%s/%s", types.QuerierRoute, keeper.QueryListCodeByCreator, creator.String())
+			res, _, err := cliCtx.QueryWithData(route, queryData)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(res))
+			return nil
+		},
+	}
+	cmd.Flags().Uint64(flagOffset, 0, "Number of results to skip")
+	cmd.Flags().Uint64(flagLimit, 100, "Query number of results returned")
+	return cmd
+}
+
+// GetCmdQueryCodeByHash lists the wasm code with the given hex encoded code hash
+func GetCmdQueryCodeByHash(cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "code-by-hash [hex_code_hash]",
+		Short: "List wasm bytecode with the given code hash",
+		Long:  "List all wasm bytecode with the given code hash, to find uploads of the same code under different code ids",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+
+			codeHash, err := hex.DecodeString(args[0])
+			if err != nil {
+				return err
+			}
+			queryData, err := codeListPageData(cmd)
+			if err != nil {
+				return err
+			}
+
+			route := fmt.Sprintf("custom/%s/%s/%X", types.QuerierRoute, keeper.QueryCodeByHash, codeHash)
+			res, _, err := cliCtx.QueryWithData(route, queryData)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(res))
+			return nil
+		},
+	}
+	cmd.Flags().Uint64(flagOffset, 0, "Number of results to skip")
+	cmd.Flags().Uint64(flagLimit, 100, "Query number of results returned")
+	return cmd
+}
+
+// GetCmdListPinnedCode lists the pinned code ids
+func GetCmdListPinnedCode(cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pinned",
+		Short: "List pinned code ids",
+		Long:  "List pinned code ids",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+
+			queryData, err := codeListPageData(cmd)
+			if err != nil {
+				return err
+			}
+
+			route := fmt.Sprintf("custom/%s/%s", types.QuerierRoute, keeper.QueryPinnedCodes)
+			res, _, err := cliCtx.QueryWithData(route, queryData)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(res))
+			return nil
+		},
+	}
+	cmd.Flags().Uint64(flagOffset, 0, "Number of results to skip")
+	cmd.Flags().Uint64(flagLimit, 100, "Query number of results returned")
+	return cmd
+}
+
+// GetCmdListOrphanCode lists the code ids without any contract
+func GetCmdListOrphanCode(cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "orphan-codes",
+		Short: "List code ids that have no contract instantiated",
+		Long:  "List code ids that have no contract instantiated. These are candidates for pruning the bytecode.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+
+			queryData, err := codeListPageData(cmd)
+			if err != nil {
+				return err
+			}
+
+			route := fmt.Sprintf("custom/%s/%s", types.QuerierRoute, keeper.QueryOrphanCodes)
+			res, _, err := cliCtx.QueryWithData(route, queryData)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(res))
+			return nil
+		},
+	}
+	cmd.Flags().Uint64(flagOffset, 0, "Number of results to skip")
+	cmd.Flags().Uint64(flagLimit, 100, "Query number of results returned")
+	return cmd
+}
+
+// GetCmdQueryCodePinned prints whether a code id is pinned
+func GetCmdQueryCodePinned(cdc *codec.Codec) *cobra.Command {
+	return &cobra.Command{
+		Use:   "code-pinned [code_id]",
+		Short: "Prints out whether a code id is pinned",
+		Long:  "Prints out whether a code id is pinned",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+
+			codeID, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return err
+			}
+
+			route := fmt.Sprintf("custom/%s/%s/%d", types.QuerierRoute, keeper.QueryCodePinned, codeID)
+			res, _, err := cliCtx.Query(route)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(res))
+			return nil
+		},
+	}
+}
+
+// GetCmdQueryCodeHasBytecode prints whether the wasm bytecode of a code id is still present
+func GetCmdQueryCodeHasBytecode(cdc *codec.Codec) *cobra.Command {
+	return &cobra.Command{
+		Use:   "code-has-bytecode [code_id]",
+		Short: "Prints out whether the wasm bytecode of a code id is present",
+		Long:  "Prints out whether the wasm bytecode of a code id is present",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+
+			codeID, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return err
+			}
+
+			route := fmt.Sprintf("custom/%s/%s/%d", types.QuerierRoute, keeper.QueryCodeHasBytecode, codeID)
+			res, _, err := cliCtx.Query(route)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(res))
+			return nil
+		},
+	}
+}
+
+// GetCmdQueryCodeUsage prints the number of contracts of a code id
+func GetCmdQueryCodeUsage(cdc *codec.Codec) *cobra.Command {
+	return &cobra.Command{
+		Use:   "code-usage [code_id]",
+		Short: "Prints out the number of contracts of a code id",
+		Long:  "Prints out the number of contracts that currently run the code id, migrated contracts are counted for their new code",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+
+			codeID, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return err
+			}
+
+			route := fmt.Sprintf("custom/%s/%s/%d", types.QuerierRoute, keeper.QueryCodeUsage, codeID)
+			res, _, err := cliCtx.Query(route)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(res))
+			return nil
+		},
+	}
+}
+
+// GetCmdQueryCodeVerification prints the code hash, source and builder of a code id
+func GetCmdQueryCodeVerification(cdc *codec.Codec) *cobra.Command {
+	return &cobra.Command{
+		Use:   "code-verification [code_id]",
+		Short: "Prints out the code hash, source and builder to verify a code id against its source",
+		Long:  "Prints out the code hash, source and builder to verify a code id against its source",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+
+			codeID, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return err
+			}
+
+			route := fmt.Sprintf("custom/%s/%s/%d", types.QuerierRoute, keeper.QueryCodeVerification, codeID)
+			res, _, err := cliCtx.Query(route)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(res))
+			return nil
+		},
+	}
+}
+
+// GetCmdListContracts lists all instantiated contracts
+func GetCmdListContracts(cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list-contracts",
+		Short: "List addresses of all instantiated contracts on the chain",
+		Long:  "List addresses of all instantiated contracts on the chain",
+		Args:  cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+
+			page, err := cmd.Flags().GetUint64(flagPage)
+			if err != nil {
+				return err
+			}
+			limit, err := cmd.Flags().GetUint64(flagLimit)
+			if err != nil {
+				return err
+			}
+			withInfo, err := cmd.Flags().GetBool(flagWithInfo)
+			if err != nil {
+				return err
+			}
+			queryData, err := json.Marshal(keeper.ListContractsRequest{Page: page, Limit: limit, WithInfo: withInfo})
+			if err != nil {
+				return err
+			}
+
+			route := fmt.Sprintf("custom/%s/%s", types.QuerierRoute, keeper.QueryListContracts)
+			res, _, err := cliCtx.QueryWithData(route, queryData)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(res))
+			return nil
+		},
+	}
+	cmd.Flags().Uint64(flagPage, 1, "Query a specific page of paginated results")
+	cmd.Flags().Uint64(flagLimit, 100, "Query number of results returned per page")
+	cmd.Flags().Bool(flagWithInfo, false, "Include the full contract info of every contract")
+	return cmd
+}
+
+// GetCmdListContractByCode lists a page of the contracts instantiated from the given code id
+func GetCmdListContractByCode(cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list-contract-by-code [code_id]",
+		Short: "List the contracts on the chain for given code id",
+		Long:  "List a page of the contracts on the chain for given code id",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+
+			codeID, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return err
+			}
+			queryData, err := contractsByCodePageData(cmd)
+			if err != nil {
+				return err
+			}
+
+			route := fmt.Sprintf("custom/%s/%s/%d", types.QuerierRoute, keeper.QueryListContractsByCode, codeID)
+			res, _, err := cliCtx.QueryWithData(route, queryData)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(res))
+			return nil
+		},
+	}
+	cmd.Flags().Uint64(flagPage, 1, "Query a specific page of paginated results")
+	cmd.Flags().Uint64(flagLimit, 100, "Query number of results returned per page")
+	return cmd
+}
+
+// contractsByCodePageData returns the pagination payload of the contracts by code queries from the flags
+func contractsByCodePageData(cmd *cobra.Command) ([]byte, error) {
+	page, err := cmd.Flags().GetUint64(flagPage)
+	if err != nil {
+		return nil, err
+	}
+	limit, err := cmd.Flags().GetUint64(flagLimit)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(keeper.ListContractsByCodeRequest{Page: page, Limit: limit})
+}
+
+// GetCmdListContractsByCodeDetailed lists the address, label and admin of a page of the contracts of a code
+func GetCmdListContractsByCodeDetailed(cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list-contracts-by-code-detailed [code_id]",
+		Short: "List address, label and admin of the contracts for given code id",
+		Long:  "List address, label and admin of a page of the contracts for given code id",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+
+			codeID, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return err
+			}
+			queryData, err := contractsByCodePageData(cmd)
+			if err != nil {
+				return err
+			}
+
+			route := fmt.Sprintf("custom/%s/%s/%d", types.QuerierRoute, keeper.QueryContractsByCodeDetail, codeID)
+			res, _, err := cliCtx.QueryWithData(route, queryData)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(res))
+			return nil
+		},
+	}
+	cmd.Flags().Uint64(flagPage, 1, "Query a specific page of paginated results")
+	cmd.Flags().Uint64(flagLimit, 100, "Query number of results returned per page")
+	return cmd
+}
+
+// GetCmdListContractsCreatedAfter lists the contracts created after a block height
+func GetCmdListContractsCreatedAfter(cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list-contracts-created-after [height]",
+		Short: "List the contracts created after the given block height",
+		Long:  "List a page of the contracts created after the given block height, in the order they were created. Pass the returned next_key as --start-after-key to get the following page",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+
+			height, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return err
+			}
+			startAfterStr, err := cmd.Flags().GetString(flagStartAfterKey)
+			if err != nil {
+				return err
+			}
+			startAfter, err := base64.StdEncoding.DecodeString(startAfterStr)
+			if err != nil {
+				return err
+			}
+			limit, err := cmd.Flags().GetUint64(flagLimit)
+			if err != nil {
+				return err
+			}
+			queryData, err := json.Marshal(keeper.ContractsCreatedAfterRequest{StartAfterKey: startAfter, Limit: limit})
+			if err != nil {
+				return err
+			}
+
+			route := fmt.Sprintf("custom/%s/%s/%d", types.QuerierRoute, keeper.QueryContractsCreatedAfter, height)
+			res, _, err := cliCtx.QueryWithData(route, queryData)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(res))
+			return nil
+		},
+	}
+	cmd.Flags().String(flagStartAfterKey, "", "Base64 encoded key to start after, empty for the first page")
+	cmd.Flags().Uint64(flagLimit, 100, "Query number of results returned per page")
+	return cmd
+}
+
+// GetCmdGetContractInfo gets details about a given contract
+func GetCmdGetContractInfo(cdc *codec.Codec) *cobra.Command {
+	return &cobra.Command{
+		Use:   "contract [bech32_address]",
+		Short: "Prints out metadata of a contract given its address",
+		Long:  "Prints out metadata of a contract given its address",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+
+			addr, err := sdk.AccAddressFromBech32(args[0])
+			if err != nil {
+				return err
+			}
+
+			route := fmt.Sprintf("custom/%s/%s/%s", types.QuerierRoute, keeper.QueryGetContract, addr.String())
+			res, _, err := cliCtx.Query(route)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(res))
+			return nil
+		},
+	}
+}
+
+// GetCmdGetContractInfos prints the metadata of multiple contracts with a single query
+func GetCmdGetContractInfos(cdc *codec.Codec) *cobra.Command {
+	return &cobra.Command{
+		Use:   "contracts [bech32_address...]",
+		Short: "Prints out metadata of multiple contracts given their addresses",
+		Long:  "Prints out metadata of multiple contracts given their addresses, in the order of the arguments. Unknown contracts are null",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+
+			addrs := make([]string, len(args))
+			for i, arg := range args {
+				addr, err := sdk.AccAddressFromBech32(arg)
+				if err != nil {
+					return err
+				}
+				addrs[i] = addr.String()
+			}
+			queryData, err := json.Marshal(addrs)
+			if err != nil {
+				return err
+			}
+
+			route := fmt.Sprintf("custom/%s/%s", types.QuerierRoute, keeper.QueryGetContractInfos)
+			res, _, err := cliCtx.QueryWithData(route, queryData)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(res))
+			return nil
+		},
+	}
 }
 
-// GetCmdListCode lists all wasm code uploaded
-func GetCmdListCode(cdc *codec.Codec) *cobra.Command {
+// GetCmdGetContractByLabel prints the newest contract of a creator with the given label
+func GetCmdGetContractByLabel(cdc *codec.Codec) *cobra.Command {
 	return &cobra.Command{
-		Use:   "list-code",
-		Short: "List all wasm bytecode on the chain",
-		Long:  "List all wasm bytecode on the chain",
-		Args:  cobra.ExactArgs(0),
+		Use:   "contract-by-label [bech32_creator_address] [label]",
+		Short: "Prints out the newest contract of a creator with the given label",
+		Long:  "Prints out the newest contract of a creator with the given label. Labels are not unique, has_duplicates is set when there are more contracts with this label",
+		Args:  cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cliCtx := context.NewCLIContext().WithCodec(cdc)
 
-			route := fmt.Sprintf("custom/%s/%s", types.QuerierRoute, keeper.QueryListCode)
+			creator, err := sdk.AccAddressFromBech32(args[0])
+			if err != nil {
+				return err
+			}
+
+			route := fmt.Sprintf("custom/%s/%s/%s/%s", types.QuerierRoute, keeper.QueryContractByLabel, creator.String(), args[1])
 			res, _, err := cliCtx.Query(route)
 			if err != nil {
 				return err
@@ -61,57 +683,100 @@ func GetCmdListCode(cdc *codec.Codec) *cobra.Command {
 	}
 }
 
-// GetCmdQueryCode returns the bytecode for a given contract
-func GetCmdQueryCode(cdc *codec.Codec) *cobra.Command {
+// GetCmdGetContractHistory prints the code history of a given contract
+func GetCmdGetContractHistory(cdc *codec.Codec) *cobra.Command {
 	return &cobra.Command{
-		Use:   "code [code_id] [output filename]",
-		Short: "Downloads wasm bytecode for given code id",
-		Long:  "Downloads wasm bytecode for given code id",
-		Args:  cobra.ExactArgs(2),
+		Use:   "contract-history [bech32_address]",
+		Short: "Prints out the code history of a contract given its address",
+		Long:  "Prints out the code history of a contract given its address",
+		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cliCtx := context.NewCLIContext().WithCodec(cdc)
 
-			codeID, err := strconv.ParseUint(args[0], 10, 64)
+			addr, err := sdk.AccAddressFromBech32(args[0])
 			if err != nil {
 				return err
 			}
 
-			route := fmt.Sprintf("custom/%s/%s/%d", types.QuerierRoute, keeper.QueryGetCode, codeID)
+			route := fmt.Sprintf("custom/%s/%s/%s", types.QuerierRoute, keeper.QueryContractHistory, addr.String())
 			res, _, err := cliCtx.Query(route)
 			if err != nil {
 				return err
 			}
+			fmt.Println(string(res))
+			return nil
+		},
+	}
+}
 
-			if len(res) == 0 {
-				return fmt.Errorf("contract not found")
+// GetCmdGetContractBalance prints the coins held by a given contract
+func GetCmdGetContractBalance(cdc *codec.Codec) *cobra.Command {
+	return &cobra.Command{
+		Use:   "contract-balance [bech32_address]",
+		Short: "Prints out the balance of a contract given its address",
+		Long:  "Prints out the balance of a contract given its address",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+
+			addr, err := sdk.AccAddressFromBech32(args[0])
+			if err != nil {
+				return err
 			}
-			var code keeper.GetCodeResponse
-			err = json.Unmarshal(res, &code)
+
+			route := fmt.Sprintf("custom/%s/%s/%s", types.QuerierRoute, keeper.QueryContractBalance, addr.String())
+			res, _, err := cliCtx.Query(route)
 			if err != nil {
 				return err
 			}
+			fmt.Println(string(res))
+			return nil
+		},
+	}
+}
 
-			if len(code.Code) == 0 {
-				return fmt.Errorf("contract not found")
+// GetCmdGetContractCodeInfo prints the contract info together with the info of its code
+func GetCmdGetContractCodeInfo(cdc *codec.Codec) *cobra.Command {
+	return &cobra.Command{
+		Use:   "contract-code-info [bech32_address]",
+		Short: "Prints out metadata of a contract and its code given the contract address",
+		Long:  "Prints out metadata of a contract and its code given the contract address",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+
+			addr, err := sdk.AccAddressFromBech32(args[0])
+			if err != nil {
+				return err
 			}
 
-			fmt.Printf("Downloading wasm code to %s\n", args[1])
-			return ioutil.WriteFile(args[1], code.Code, 0644)
+			route := fmt.Sprintf("custom/%s/%s/%s", types.QuerierRoute, keeper.QueryContractCodeInfo, addr.String())
+			res, _, err := cliCtx.Query(route)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(res))
+			return nil
 		},
 	}
 }
 
-// GetCmdListContracts lists all instantiated contracts
-func GetCmdListContracts(cdc *codec.Codec) *cobra.Command {
+// GetCmdGetContractCodeIDs prints the code id a contract was instantiated with and the one it runs now
+func GetCmdGetContractCodeIDs(cdc *codec.Codec) *cobra.Command {
 	return &cobra.Command{
-		Use:   "list-contracts",
-		Short: "List addresses of all instantiated contracts on the chain",
-		Long:  "List addresses of all instantiated contracts on the chain",
-		Args:  cobra.ExactArgs(0),
+		Use:   "contract-code-ids [bech32_address]",
+		Short: "Prints out the original and the current code id of a contract",
+		Long:  "Prints out the original and the current code id of a contract",
+		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cliCtx := context.NewCLIContext().WithCodec(cdc)
 
-			route := fmt.Sprintf("custom/%s/%s", types.QuerierRoute, keeper.QueryListContracts)
+			addr, err := sdk.AccAddressFromBech32(args[0])
+			if err != nil {
+				return err
+			}
+
+			route := fmt.Sprintf("custom/%s/%s/%s", types.QuerierRoute, keeper.QueryContractCodeIDs, addr.String())
 			res, _, err := cliCtx.Query(route)
 			if err != nil {
 				return err
@@ -122,12 +787,12 @@ func GetCmdListContracts(cdc *codec.Codec) *cobra.Command {
 	}
 }
 
-// GetCmdGetContractInfo gets details about a given contract
-func GetCmdGetContractInfo(cdc *codec.Codec) *cobra.Command {
+// GetCmdGetContractMigratable prints whether the contract has an admin that can migrate it
+func GetCmdGetContractMigratable(cdc *codec.Codec) *cobra.Command {
 	return &cobra.Command{
-		Use:   "contract [bech32_address]",
-		Short: "Prints out metadata of a contract given its address",
-		Long:  "Prints out metadata of a contract given its address",
+		Use:   "contract-migratable [bech32_address]",
+		Short: "Prints out whether a contract can be migrated by its admin",
+		Long:  "Prints out whether a contract has an admin and can be migrated by it. Governance can migrate any contract",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cliCtx := context.NewCLIContext().WithCodec(cdc)
@@ -137,7 +802,7 @@ func GetCmdGetContractInfo(cdc *codec.Codec) *cobra.Command {
 				return err
 			}
 
-			route := fmt.Sprintf("custom/%s/%s/%s", types.QuerierRoute, keeper.QueryGetContract, addr.String())
+			route := fmt.Sprintf("custom/%s/%s/%s", types.QuerierRoute, keeper.QueryContractMigratable, addr.String())
 			res, _, err := cliCtx.Query(route)
 			if err != nil {
 				return err
@@ -159,7 +824,10 @@ func GetCmdGetContractState(cdc *codec.Codec) *cobra.Command {
 	}
 	cmd.AddCommand(client.GetCommands(
 		GetCmdGetContractStateAll(cdc),
+		GetCmdGetContractStateAllPrefix(cdc),
+		GetCmdGetContractStateAllPaginated(cdc),
 		GetCmdGetContractStateRaw(cdc),
+		GetCmdGetContractStateJSON(cdc),
 		GetCmdGetContractStateSmart(cdc),
 	)...)
 	return cmd
@@ -191,6 +859,81 @@ func GetCmdGetContractStateAll(cdc *codec.Codec) *cobra.Command {
 	}
 }
 
+func GetCmdGetContractStateAllPrefix(cdc *codec.Codec) *cobra.Command {
+	decoder := newArgDecoder(base64.StdEncoding.DecodeString)
+	cmd := &cobra.Command{
+		Use:   "all-prefix [bech32_address] [key_prefix]",
+		Short: "Prints out the internal state of a contract for all keys with the given prefix",
+		Long:  "Prints out the internal state of a contract for all keys with the given prefix. The prefix is base64 encoded by default",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+
+			addr, err := sdk.AccAddressFromBech32(args[0])
+			if err != nil {
+				return err
+			}
+			queryData, err := decoder.DecodeString(args[1])
+			if err != nil {
+				return err
+			}
+			route := fmt.Sprintf("custom/%s/%s/%s/%s", types.QuerierRoute, keeper.QueryGetContractState, addr.String(), keeper.QueryMethodContractStateAllPrefix)
+			res, _, err := cliCtx.QueryWithData(route, queryData)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(res))
+			return nil
+		},
+	}
+	decoder.RegisterFlags(cmd.PersistentFlags(), "key prefix argument")
+	return cmd
+}
+
+func GetCmdGetContractStateAllPaginated(cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "all-paginated [bech32_address]",
+		Short: "Prints out a page of the internal state of a contract given its address",
+		Long:  "Prints out a page of the internal state of a contract given its address. Pass the returned next_key as --start-after-key to get the following page",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+
+			addr, err := sdk.AccAddressFromBech32(args[0])
+			if err != nil {
+				return err
+			}
+			startAfterStr, err := cmd.Flags().GetString(flagStartAfterKey)
+			if err != nil {
+				return err
+			}
+			startAfter, err := base64.StdEncoding.DecodeString(startAfterStr)
+			if err != nil {
+				return err
+			}
+			limit, err := cmd.Flags().GetUint64(flagLimit)
+			if err != nil {
+				return err
+			}
+			queryData, err := json.Marshal(keeper.ContractStatePageRequest{StartAfterKey: startAfter, Limit: limit})
+			if err != nil {
+				return err
+			}
+
+			route := fmt.Sprintf("custom/%s/%s/%s/%s", types.QuerierRoute, keeper.QueryGetContractState, addr.String(), keeper.QueryMethodContractStateAllPaginated)
+			res, _, err := cliCtx.QueryWithData(route, queryData)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(res))
+			return nil
+		},
+	}
+	cmd.Flags().String(flagStartAfterKey, "", "Base64 encoded key to start after, empty for the first page")
+	cmd.Flags().Uint64(flagLimit, 100, "Query number of results returned per page")
+	return cmd
+}
+
 func GetCmdGetContractStateRaw(cdc *codec.Codec) *cobra.Command {
 	decoder := newArgDecoder(hex.DecodeString)
 	cmd := &cobra.Command{
@@ -222,6 +965,38 @@ func GetCmdGetContractStateRaw(cdc *codec.Codec) *cobra.Command {
 	return cmd
 }
 
+// GetCmdGetContractStateJSON prints the value stored for a key of a contract. The query fails when the value is not json.
+func GetCmdGetContractStateJSON(cdc *codec.Codec) *cobra.Command {
+	decoder := newArgDecoder(hex.DecodeString)
+	cmd := &cobra.Command{
+		Use:   "json [bech32_address] [key]",
+		Short: "Prints out the json value stored for key of a contract given its address",
+		Long:  "Prints out the json value stored for key of a contract given its address. Fails when the stored value is not valid json",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+
+			addr, err := sdk.AccAddressFromBech32(args[0])
+			if err != nil {
+				return err
+			}
+			queryData, err := decoder.DecodeString(args[1])
+			if err != nil {
+				return err
+			}
+			route := fmt.Sprintf("custom/%s/%s/%s", types.QuerierRoute, keeper.QueryContractStateJSON, addr.String())
+			res, _, err := cliCtx.QueryWithData(route, queryData)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(res))
+			return nil
+		},
+	}
+	decoder.RegisterFlags(cmd.PersistentFlags(), "key argument")
+	return cmd
+}
+
 func GetCmdGetContractStateSmart(cdc *codec.Codec) *cobra.Command {
 	decoder := newArgDecoder(asciiDecodeString)
 
@@ -259,6 +1034,118 @@ func GetCmdGetContractStateSmart(cdc *codec.Codec) *cobra.Command {
 	return cmd
 }
 
+// GetCmdSimulateExecute runs an execution of a contract without committing it and prints the gas used
+func GetCmdSimulateExecute(cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "simulate-execute [contract_addr_bech32] [sender_addr_bech32] [json_encoded_send_args]",
+		Short: "Simulates an execution of a contract and prints the gas used, error and events",
+		Long:  "Simulates an execution of a contract and prints the gas used, error and events. No state is committed and the sender does not sign anything.",
+		Args:  cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+
+			contractAddr, err := sdk.AccAddressFromBech32(args[0])
+			if err != nil {
+				return err
+			}
+			sender, err := sdk.AccAddressFromBech32(args[1])
+			if err != nil {
+				return err
+			}
+			amountStr, err := cmd.Flags().GetString(flagAmount)
+			if err != nil {
+				return err
+			}
+			amount, err := sdk.ParseCoins(amountStr)
+			if err != nil {
+				return err
+			}
+
+			bz, err := json.Marshal(types.MsgExecuteContract{
+				Sender:    sender,
+				Contract:  contractAddr,
+				Msg:       []byte(args[2]),
+				SentFunds: amount,
+			})
+			if err != nil {
+				return err
+			}
+			route := fmt.Sprintf("custom/%s/%s", types.QuerierRoute, keeper.QuerySimulateExecute)
+			res, _, err := cliCtx.QueryWithData(route, bz)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(res))
+			return nil
+		},
+	}
+	cmd.Flags().String(flagAmount, "", "Coins to send to the contract along with command")
+	return cmd
+}
+
+// GetCmdQueryContractMetrics prints the contract invocation counters of the connected node
+func GetCmdQueryContractMetrics(cdc *codec.Codec) *cobra.Command {
+	return &cobra.Command{
+		Use:   "contract-metrics",
+		Short: "Prints the invocation counts and gas used per code and contract as counted by the connected node",
+		Long:  "Prints the invocation counts and gas used per code and contract as counted by the connected node since it started. The node must enable contract_metrics in the wasm config.",
+		Args:  cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+
+			route := fmt.Sprintf("custom/%s/%s", types.QuerierRoute, keeper.QueryContractMetrics)
+			res, _, err := cliCtx.Query(route)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(res))
+			return nil
+		},
+	}
+}
+
+// GetCmdQueryExecutedThisBlock prints the contracts executed in the current block as indexed by the connected node
+func GetCmdQueryExecutedThisBlock(cdc *codec.Codec) *cobra.Command {
+	return &cobra.Command{
+		Use:   "executed-this-block",
+		Short: "Prints the addresses of the contracts executed in the current block",
+		Long:  "Prints the addresses of the contracts executed in the current block as indexed by the connected node. The node must enable executed_contracts_index in the wasm config.",
+		Args:  cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+
+			route := fmt.Sprintf("custom/%s/%s", types.QuerierRoute, keeper.QueryExecutedThisBlock)
+			res, _, err := cliCtx.Query(route)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(res))
+			return nil
+		},
+	}
+}
+
+// GetCmdQueryCodeStorageStats prints the number of stored codes and their total bytecode size
+func GetCmdQueryCodeStorageStats(cdc *codec.Codec) *cobra.Command {
+	return &cobra.Command{
+		Use:   "code-storage-stats",
+		Short: "Prints the number of stored codes and the total size of their wasm bytecode",
+		Long:  "Prints the number of stored codes and the total size of their uncompressed wasm bytecode in bytes",
+		Args:  cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+
+			route := fmt.Sprintf("custom/%s/%s", types.QuerierRoute, keeper.QueryCodeStorageStats)
+			res, _, err := cliCtx.Query(route)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(res))
+			return nil
+		},
+	}
+}
+
 type argumentDecoder struct {
 	// dec is the default decoder
 	dec                func(string) ([]byte, error)
@@ -25,6 +25,15 @@ const (
 	flagAmount  = "amount"
 	flagSource  = "source"
 	flagBuilder = "builder"
+	flagAdmin   = "admin"
+	flagLabel   = "label"
+
+	flagInstantiateByAddress = "instantiate-only-address"
+	flagInstantiateNobody    = "instantiate-nobody"
+	flagAdminOnlyExecute     = "admin-only-execute"
+	flagDeduplicateByHash    = "deduplicate-by-hash"
+	flagAllowCreatorLockout  = "allow-creator-lockout"
+	flagUndeprecate          = "undeprecate"
 )
 
 // GetTxCmd returns the transaction commands for this module
@@ -40,6 +49,11 @@ func GetTxCmd(cdc *codec.Codec) *cobra.Command {
 		StoreCodeCmd(cdc),
 		InstantiateContractCmd(cdc),
 		ExecuteContractCmd(cdc),
+		MigrateContractCmd(cdc),
+		UpdateContractAdminCmd(cdc),
+		ClearContractAdminCmd(cdc),
+		UpdateInstantiateConfigCmd(cdc),
+		DeprecateCodeCmd(cdc),
 	)...)
 	return txCmd
 }
@@ -76,12 +90,20 @@ func StoreCodeCmd(cdc *codec.Codec) *cobra.Command {
 				return fmt.Errorf("invalid input file. Use wasm binary or gzip")
 			}
 
+			perm, err := parseInstantiatePermission()
+			if err != nil {
+				return err
+			}
+
 			// build and sign the transaction, then broadcast to Tendermint
 			msg := types.MsgStoreCode{
 				Sender:       cliCtx.GetFromAddress(),
 				WASMByteCode: wasm,
 				Source:       source,
 				Builder:      builder,
+
+				InstantiatePermission: perm,
+				DeduplicateByHash:     viper.GetBool(flagDeduplicateByHash),
 			}
 			err = msg.ValidateBasic()
 
@@ -94,7 +116,10 @@ func StoreCodeCmd(cdc *codec.Codec) *cobra.Command {
 	}
 
 	cmd.Flags().String(flagSource, "", "A valid URI reference to the contract's source code, optional")
-	cmd.Flags().String(flagBuilder, "", "A valid docker tag for the build system, optional")
+	cmd.Flags().String(flagBuilder, "", "The cosmwasm-opt image used for the build, like cosmwasm-opt:0.7.0 with an optional @sha256 digest, optional")
+	cmd.Flags().String(flagInstantiateByAddress, "", "Only this address can instantiate a contract instance from the code, optional")
+	cmd.Flags().Bool(flagInstantiateNobody, false, "Nobody can instantiate a contract instance from the code, optional")
+	cmd.Flags().Bool(flagDeduplicateByHash, false, "Return the existing code id when the same code was stored before, optional")
 
 	return cmd
 }
@@ -124,18 +149,38 @@ func InstantiateContractCmd(cdc *codec.Codec) *cobra.Command {
 
 			initMsg := args[1]
 
+			label := viper.GetString(flagLabel)
+			if label == "" {
+				return fmt.Errorf("label is required on all contracts")
+			}
+
+			var adminAddr sdk.AccAddress
+			if adminStr := viper.GetString(flagAdmin); adminStr != "" {
+				adminAddr, err = sdk.AccAddressFromBech32(adminStr)
+				if err != nil {
+					return err
+				}
+			}
+
 			// build and sign the transaction, then broadcast to Tendermint
 			msg := types.MsgInstantiateContract{
 				Sender:    cliCtx.GetFromAddress(),
+				Admin:     adminAddr,
 				Code:      codeID,
+				Label:     label,
 				InitFunds: amount,
 				InitMsg:   []byte(initMsg),
+
+				AdminOnlyExecute: viper.GetBool(flagAdminOnlyExecute),
 			}
 			return utils.GenerateOrBroadcastMsgs(cliCtx, txBldr, []sdk.Msg{msg})
 		},
 	}
 
 	cmd.Flags().String(flagAmount, "", "Coins to send to the contract during instantiation")
+	cmd.Flags().String(flagLabel, "", "A human-readable name for this contract in lists")
+	cmd.Flags().String(flagAdmin, "", "Address of an admin that is allowed to migrate the contract, optional")
+	cmd.Flags().Bool(flagAdminOnlyExecute, false, "Only the admin can execute the contract, requires --admin")
 	return cmd
 }
 
@@ -178,3 +223,190 @@ func ExecuteContractCmd(cdc *codec.Codec) *cobra.Command {
 	cmd.Flags().String(flagAmount, "", "Coins to send to the contract along with command")
 	return cmd
 }
+
+// MigrateContractCmd will migrate a contract to a new code version
+func MigrateContractCmd(cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate [contract_addr_bech32] [new_code_id_int64] [json_encoded_migration_args]",
+		Short: "Migrate a wasm contract to a new code version",
+		Args:  cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			inBuf := bufio.NewReader(cmd.InOrStdin())
+			txBldr := auth.NewTxBuilderFromCLI(inBuf).WithTxEncoder(utils.GetTxEncoder(cdc))
+			cliCtx := context.NewCLIContextWithInput(inBuf).WithCodec(cdc)
+
+			contractAddr, err := sdk.AccAddressFromBech32(args[0])
+			if err != nil {
+				return err
+			}
+
+			// get the id of the code to migrate to
+			codeID, err := strconv.ParseUint(args[1], 10, 64)
+			if err != nil {
+				return err
+			}
+
+			migrateMsg := args[2]
+
+			// build and sign the transaction, then broadcast to Tendermint
+			msg := types.MsgMigrateContract{
+				Sender:     cliCtx.GetFromAddress(),
+				Contract:   contractAddr,
+				Code:       codeID,
+				MigrateMsg: []byte(migrateMsg),
+			}
+			return utils.GenerateOrBroadcastMsgs(cliCtx, txBldr, []sdk.Msg{msg})
+		},
+	}
+	return cmd
+}
+
+// UpdateContractAdminCmd sets a new admin for a contract
+func UpdateContractAdminCmd(cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set-contract-admin [contract_addr_bech32] [new_admin_addr_bech32]",
+		Short: "Set new admin for a contract",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			inBuf := bufio.NewReader(cmd.InOrStdin())
+			txBldr := auth.NewTxBuilderFromCLI(inBuf).WithTxEncoder(utils.GetTxEncoder(cdc))
+			cliCtx := context.NewCLIContextWithInput(inBuf).WithCodec(cdc)
+
+			contractAddr, err := sdk.AccAddressFromBech32(args[0])
+			if err != nil {
+				return err
+			}
+			newAdmin, err := sdk.AccAddressFromBech32(args[1])
+			if err != nil {
+				return err
+			}
+
+			msg := types.MsgUpdateAdmin{
+				Sender:   cliCtx.GetFromAddress(),
+				NewAdmin: newAdmin,
+				Contract: contractAddr,
+			}
+			return utils.GenerateOrBroadcastMsgs(cliCtx, txBldr, []sdk.Msg{msg})
+		},
+	}
+	return cmd
+}
+
+// ClearContractAdminCmd clears an admin for a contract which makes it immutable
+func ClearContractAdminCmd(cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "clear-contract-admin [contract_addr_bech32]",
+		Short: "Clears admin for a contract to prevent further migrations",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			inBuf := bufio.NewReader(cmd.InOrStdin())
+			txBldr := auth.NewTxBuilderFromCLI(inBuf).WithTxEncoder(utils.GetTxEncoder(cdc))
+			cliCtx := context.NewCLIContextWithInput(inBuf).WithCodec(cdc)
+
+			contractAddr, err := sdk.AccAddressFromBech32(args[0])
+			if err != nil {
+				return err
+			}
+
+			msg := types.MsgClearAdmin{
+				Sender:   cliCtx.GetFromAddress(),
+				Contract: contractAddr,
+			}
+			return utils.GenerateOrBroadcastMsgs(cliCtx, txBldr, []sdk.Msg{msg})
+		},
+	}
+	return cmd
+}
+
+// UpdateInstantiateConfigCmd sets a new instantiate permission for a code
+func UpdateInstantiateConfigCmd(cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "update-instantiate-config [code_id]",
+		Short: "Set new instantiate permission for a code",
+		Long:  "Set new instantiate permission for a code. Everybody can instantiate the code when no permission flag is given.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			inBuf := bufio.NewReader(cmd.InOrStdin())
+			txBldr := auth.NewTxBuilderFromCLI(inBuf).WithTxEncoder(utils.GetTxEncoder(cdc))
+			cliCtx := context.NewCLIContextWithInput(inBuf).WithCodec(cdc)
+
+			codeID, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return err
+			}
+			perm, err := parseInstantiatePermission()
+			if err != nil {
+				return err
+			}
+			if perm == nil {
+				perm = &types.AllowEverybody
+			}
+
+			msg := types.MsgUpdateInstantiateConfig{
+				Sender:              cliCtx.GetFromAddress(),
+				CodeID:              codeID,
+				NewPermission:       *perm,
+				AllowCreatorLockout: viper.GetBool(flagAllowCreatorLockout),
+			}
+			if err := msg.ValidateBasic(); err != nil {
+				return err
+			}
+			return utils.GenerateOrBroadcastMsgs(cliCtx, txBldr, []sdk.Msg{msg})
+		},
+	}
+	cmd.Flags().String(flagInstantiateByAddress, "", "Only this address can instantiate a contract instance from the code, optional")
+	cmd.Flags().Bool(flagInstantiateNobody, false, "Nobody can instantiate a contract instance from the code, optional")
+	cmd.Flags().Bool(flagAllowCreatorLockout, false, "Confirm a permission that does not allow the code creator to instantiate anymore")
+	return cmd
+}
+
+// DeprecateCodeCmd marks a code as deprecated
+func DeprecateCodeCmd(cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "deprecate-code [code_id]",
+		Short: "Mark a code as deprecated",
+		Long:  "Mark a code as deprecated to discourage new instantiations. Only the code creator can do this.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			inBuf := bufio.NewReader(cmd.InOrStdin())
+			txBldr := auth.NewTxBuilderFromCLI(inBuf).WithTxEncoder(utils.GetTxEncoder(cdc))
+			cliCtx := context.NewCLIContextWithInput(inBuf).WithCodec(cdc)
+
+			codeID, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return err
+			}
+
+			msg := types.MsgDeprecateCode{
+				Sender:     cliCtx.GetFromAddress(),
+				CodeID:     codeID,
+				Deprecated: !viper.GetBool(flagUndeprecate),
+			}
+			if err := msg.ValidateBasic(); err != nil {
+				return err
+			}
+			return utils.GenerateOrBroadcastMsgs(cliCtx, txBldr, []sdk.Msg{msg})
+		},
+	}
+	cmd.Flags().Bool(flagUndeprecate, false, "Clear the deprecated flag of the code instead")
+	return cmd
+}
+
+// parseInstantiatePermission returns the permission set by the instantiate flags or nil when none is set
+func parseInstantiatePermission() (*types.AccessConfig, error) {
+	onlyAddrStr := viper.GetString(flagInstantiateByAddress)
+	switch {
+	case onlyAddrStr != "" && viper.GetBool(flagInstantiateNobody):
+		return nil, fmt.Errorf("flags %s and %s are exclusive", flagInstantiateByAddress, flagInstantiateNobody)
+	case onlyAddrStr != "":
+		allowedAddr, err := sdk.AccAddressFromBech32(onlyAddrStr)
+		if err != nil {
+			return nil, err
+		}
+		x := types.OnlyAddress(allowedAddr)
+		return &x, nil
+	case viper.GetBool(flagInstantiateNobody):
+		return &types.AllowNobody, nil
+	}
+	return nil, nil
+}
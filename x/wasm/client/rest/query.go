@@ -1,133 +1,404 @@
 package rest
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	sdk "github.com/cosmos/cosmos-sdk/types"
-	"github.com/cosmos/cosmos-sdk/types/rest"
-	"github.com/cosmwasm/wasmd/x/wasm/internal/keeper"
-	"github.com/cosmwasm/wasmd/x/wasm/internal/types"
+	"io/ioutil"
 	"net/http"
 	"strconv"
 
 	"github.com/cosmos/cosmos-sdk/client/context"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/rest"
 	"github.com/gorilla/mux"
+
+	"github.com/cosmwasm/wasmd/x/wasm/internal/keeper"
+	"github.com/cosmwasm/wasmd/x/wasm/internal/types"
 )
 
+// heightHeader contains the block height a query response was taken from
+const heightHeader = "X-Cosmos-Block-Height"
+
 func registerQueryRoutes(cliCtx context.CLIContext, r *mux.Router) {
 	r.HandleFunc("/wasm/code/", listCodesHandlerFn(cliCtx)).Methods("GET")
 	r.HandleFunc("/wasm/code/{codeID}", queryCodeHandlerFn(cliCtx)).Methods("GET")
+	r.HandleFunc("/wasm/code/{codeID}/info", queryCodeInfoHandlerFn(cliCtx)).Methods("GET")
+	r.HandleFunc("/wasm/code/{codeID}/contracts", listContractsByCodeHandlerFn(cliCtx)).Methods("GET")
 	r.HandleFunc("/wasm/contract/", listAllContractsHandlerFn(cliCtx)).Methods("GET")
 	r.HandleFunc("/wasm/contract/{contractAddr}", queryContractHandlerFn(cliCtx)).Methods("GET")
+	r.HandleFunc("/wasm/contract/{contractAddr}/history", queryContractHistoryHandlerFn(cliCtx)).Methods("GET")
 	r.HandleFunc("/wasm/contract/{contractAddr}/state", queryContractStateAllHandlerFn(cliCtx)).Methods("GET")
 	r.HandleFunc("/wasm/contract/{contractAddr}/smart", queryContractStateSmartHandlerFn(cliCtx)).Methods("GET")
+	r.HandleFunc("/wasm/contract/{contractAddr}/smart", queryContractStateSmartBodyHandlerFn(cliCtx)).Methods("POST")
 	r.HandleFunc("/wasm/contract/{contractAddr}/raw", queryContractStateRawHandlerFn(cliCtx)).Methods("GET")
+	r.HandleFunc("/wasm/contract/{contractAddr}/state-json", queryContractStateJSONHandlerFn(cliCtx)).Methods("GET")
 }
 
 func listCodesHandlerFn(cliCtx context.CLIContext) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		route := fmt.Sprintf("custom/%s/%s", types.QuerierRoute, keeper.QueryListCode)
-		res, _, err := cliCtx.Query(route)
+		var pagination keeper.ListCodeRequest
+		if v := r.URL.Query().Get("offset"); v != "" {
+			offset, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			pagination.Offset = offset
+		}
+		if v := r.URL.Query().Get("limit"); v != "" {
+			limit, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			pagination.Limit = limit
+		}
+		queryData, err := json.Marshal(pagination)
 		if err != nil {
 			rest.WriteErrorResponse(w, http.StatusInternalServerError, err.Error())
 			return
 		}
-		rest.PostProcessResponse(w, cliCtx, string(res))
+
+		route := fmt.Sprintf("custom/%s/%s", types.QuerierRoute, keeper.QueryListCode)
+		var list keeper.CodeListResponse
+		if cliCtx, res, ok := queryTyped(w, r, cliCtx, route, queryData, &list); ok {
+			writeQueryResponse(w, cliCtx, res)
+		}
 	}
 }
 
 func queryCodeHandlerFn(cliCtx context.CLIContext) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		codeID, err := strconv.ParseUint(mux.Vars(r)["codeId"], 10, 64)
+		codeID, ok := parseCodeID(w, r)
+		if !ok {
+			return
+		}
+
+		var opts keeper.GetCodeRequest
+		if v := r.URL.Query().Get("compressed"); v != "" {
+			compressed, err := strconv.ParseBool(v)
+			if err != nil {
+				rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			opts.Compressed = compressed
+		}
+		queryData, err := json.Marshal(opts)
 		if err != nil {
 			rest.WriteErrorResponse(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 
 		route := fmt.Sprintf("custom/%s/%s/%d", types.QuerierRoute, keeper.QueryGetCode, codeID)
-		res, _, err := cliCtx.Query(route)
-		if err != nil {
-			rest.WriteErrorResponse(w, http.StatusInternalServerError, err.Error())
+		var code keeper.GetCodeResponse
+		cliCtx, res, ok := queryTyped(w, r, cliCtx, route, queryData, &code)
+		if !ok {
+			return
+		}
+		if len(code.Code) == 0 {
+			rest.WriteErrorResponse(w, http.StatusNotFound, "code not found")
 			return
 		}
+		writeQueryResponse(w, cliCtx, res)
+	}
+}
 
-		if len(res) == 0 {
-			rest.WriteErrorResponse(w, http.StatusInternalServerError, "contract not found")
+func queryCodeInfoHandlerFn(cliCtx context.CLIContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		codeID, ok := parseCodeID(w, r)
+		if !ok {
 			return
 		}
-		var code keeper.GetCodeResponse
-		err = json.Unmarshal(res, &code)
-		if err != nil {
-			rest.WriteErrorResponse(w, http.StatusInternalServerError, err.Error())
+
+		route := fmt.Sprintf("custom/%s/%s/%d", types.QuerierRoute, keeper.QueryGetCodeInfo, codeID)
+		var info keeper.ListCodeResponse
+		if cliCtx, res, ok := queryTyped(w, r, cliCtx, route, nil, &info); ok {
+			writeQueryResponse(w, cliCtx, res)
+		}
+	}
+}
+
+func listContractsByCodeHandlerFn(cliCtx context.CLIContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		codeID, ok := parseCodeID(w, r)
+		if !ok {
 			return
 		}
 
-		if len(code.Code) == 0 {
-			rest.WriteErrorResponse(w, http.StatusInternalServerError, "contract not found")
+		queryData, ok := contractsByCodePageData(w, r)
+		if !ok {
 			return
 		}
 
-		rest.PostProcessResponse(w, cliCtx, string(code.Code))
+		route := fmt.Sprintf("custom/%s/%s/%d", types.QuerierRoute, keeper.QueryListContractsByCode, codeID)
+		var list keeper.ContractsByCodeResponse
+		if cliCtx, res, ok := queryTyped(w, r, cliCtx, route, queryData, &list); ok {
+			writeQueryResponse(w, cliCtx, res)
+		}
+	}
+}
+
+// contractsByCodePageData returns the pagination payload of the contracts by code queries from the page and limit
+// url params. It writes the error response when they are invalid.
+func contractsByCodePageData(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	var pagination keeper.ListContractsByCodeRequest
+	if v := r.URL.Query().Get("page"); v != "" {
+		page, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+			return nil, false
+		}
+		pagination.Page = page
+	}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+			return nil, false
+		}
+		pagination.Limit = limit
+	}
+	queryData, err := json.Marshal(pagination)
+	if err != nil {
+		rest.WriteErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return nil, false
 	}
+	return queryData, true
 }
 
 func listAllContractsHandlerFn(cliCtx context.CLIContext) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		route := fmt.Sprintf("custom/%s/%s", types.QuerierRoute, keeper.QueryListContracts)
-		res, _, err := cliCtx.Query(route)
+		var pagination keeper.ListContractsRequest
+		if v := r.URL.Query().Get("page"); v != "" {
+			page, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			pagination.Page = page
+		}
+		if v := r.URL.Query().Get("limit"); v != "" {
+			limit, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			pagination.Limit = limit
+		}
+		if v := r.URL.Query().Get("with_info"); v != "" {
+			withInfo, err := strconv.ParseBool(v)
+			if err != nil {
+				rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			pagination.WithInfo = withInfo
+		}
+		queryData, err := json.Marshal(pagination)
 		if err != nil {
 			rest.WriteErrorResponse(w, http.StatusInternalServerError, err.Error())
-
 			return
 		}
-		rest.PostProcessResponse(w, cliCtx, string(res))
+
+		route := fmt.Sprintf("custom/%s/%s", types.QuerierRoute, keeper.QueryListContracts)
+		var list keeper.ContractListResponse
+		if cliCtx, res, ok := queryTyped(w, r, cliCtx, route, queryData, &list); ok {
+			writeQueryResponse(w, cliCtx, res)
+		}
 	}
 }
 
 func queryContractHandlerFn(cliCtx context.CLIContext) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		addr, err := sdk.AccAddressFromBech32(mux.Vars(r)["codeId"])
-		if err != nil {
-			rest.WriteErrorResponse(w, http.StatusInternalServerError, err.Error())
+		addr, ok := parseContractAddr(w, r)
+		if !ok {
 			return
 		}
 
 		route := fmt.Sprintf("custom/%s/%s/%s", types.QuerierRoute, keeper.QueryGetContract, addr.String())
-		res, _, err := cliCtx.Query(route)
-		if err != nil {
-			rest.WriteErrorResponse(w, http.StatusInternalServerError, err.Error())
+		// the querier returns null for unknown addresses
+		var info *types.ContractInfo
+		cliCtx, res, ok := queryTyped(w, r, cliCtx, route, nil, &info)
+		if !ok {
 			return
 		}
-		rest.PostProcessResponse(w, cliCtx, string(res))
+		if info == nil {
+			rest.WriteErrorResponse(w, http.StatusNotFound, "contract not found")
+			return
+		}
+		writeQueryResponse(w, cliCtx, res)
+	}
+}
+
+func queryContractHistoryHandlerFn(cliCtx context.CLIContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		addr, ok := parseContractAddr(w, r)
+		if !ok {
+			return
+		}
+
+		route := fmt.Sprintf("custom/%s/%s/%s", types.QuerierRoute, keeper.QueryContractHistory, addr.String())
+		var history []types.ContractCodeHistoryEntry
+		if cliCtx, res, ok := queryTyped(w, r, cliCtx, route, nil, &history); ok {
+			writeQueryResponse(w, cliCtx, res)
+		}
 	}
 }
 
 func queryContractStateAllHandlerFn(cliCtx context.CLIContext) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		addr, err := sdk.AccAddressFromBech32(mux.Vars(r)["contractAddr"])
-		if err != nil {
-			rest.WriteErrorResponse(w, http.StatusInternalServerError, err.Error())
+		addr, ok := parseContractAddr(w, r)
+		if !ok {
 			return
 		}
 
 		route := fmt.Sprintf("custom/%s/%s/%s/%s", types.QuerierRoute, keeper.QueryGetContractState, addr.String(), keeper.QueryMethodContractStateAll)
-		res, _, err := cliCtx.Query(route)
-		if err != nil {
-			rest.WriteErrorResponse(w, http.StatusInternalServerError, err.Error())
-			return
+		var models []types.Model
+		if cliCtx, res, ok := queryTyped(w, r, cliCtx, route, nil, &models); ok {
+			writeQueryResponse(w, cliCtx, res)
 		}
-		rest.PostProcessResponse(w, cliCtx, string(res))
 	}
 }
 
+// queryContractStateSmartHandlerFn takes the smart query from the query url parameter
 func queryContractStateSmartHandlerFn(cliCtx context.CLIContext) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		addr, ok := parseContractAddr(w, r)
+		if !ok {
+			return
+		}
+		queryContractStateSmart(w, r, cliCtx, addr, []byte(r.URL.Query().Get("query")))
+	}
+}
+
+// queryContractStateSmartBodyHandlerFn passes the request body on to the contract unmodified
+func queryContractStateSmartBodyHandlerFn(cliCtx context.CLIContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		addr, ok := parseContractAddr(w, r)
+		if !ok {
+			return
+		}
+		queryData, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxSize))
+		if err != nil {
+			rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		queryContractStateSmart(w, r, cliCtx, addr, queryData)
+	}
+}
 
+func queryContractStateSmart(w http.ResponseWriter, r *http.Request, cliCtx context.CLIContext, addr sdk.AccAddress, queryData []byte) {
+	if !json.Valid(queryData) {
+		rest.WriteErrorResponse(w, http.StatusBadRequest, "query must be valid json")
+		return
+	}
+
+	route := fmt.Sprintf("custom/%s/%s/%s/%s", types.QuerierRoute, keeper.QueryGetContractState, addr.String(), keeper.QueryMethodContractStateSmart)
+	var result json.RawMessage
+	if cliCtx, res, ok := queryTyped(w, r, cliCtx, route, queryData, &result); ok {
+		writeQueryResponse(w, cliCtx, res)
 	}
 }
 
+// queryContractStateRawHandlerFn returns the models for the hex encoded key url parameter
 func queryContractStateRawHandlerFn(cliCtx context.CLIContext) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		addr, ok := parseContractAddr(w, r)
+		if !ok {
+			return
+		}
+		key, err := hex.DecodeString(r.URL.Query().Get("key"))
+		if err != nil {
+			rest.WriteErrorResponse(w, http.StatusBadRequest, "key must be hex encoded: "+err.Error())
+			return
+		}
+		if len(key) == 0 {
+			rest.WriteErrorResponse(w, http.StatusBadRequest, "key must not be empty")
+			return
+		}
+
+		route := fmt.Sprintf("custom/%s/%s/%s/%s", types.QuerierRoute, keeper.QueryGetContractState, addr.String(), keeper.QueryMethodContractStateRaw)
+		var models []types.Model
+		cliCtx, res, ok := queryTyped(w, r, cliCtx, route, key, &models)
+		if !ok {
+			return
+		}
+		if len(models) == 0 {
+			rest.WriteErrorResponse(w, http.StatusNotFound, "key not found")
+			return
+		}
+		writeQueryResponse(w, cliCtx, res)
+	}
+}
+
+// queryContractStateJSONHandlerFn returns the stored value of the hex encoded key as json document
+func queryContractStateJSONHandlerFn(cliCtx context.CLIContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		addr, ok := parseContractAddr(w, r)
+		if !ok {
+			return
+		}
+		key, err := hex.DecodeString(r.URL.Query().Get("key"))
+		if err != nil {
+			rest.WriteErrorResponse(w, http.StatusBadRequest, "key must be hex encoded: "+err.Error())
+			return
+		}
+		if len(key) == 0 {
+			rest.WriteErrorResponse(w, http.StatusBadRequest, "key must not be empty")
+			return
+		}
+
+		route := fmt.Sprintf("custom/%s/%s/%s", types.QuerierRoute, keeper.QueryContractStateJSON, addr.String())
+		var value json.RawMessage
+		cliCtx, res, ok := queryTyped(w, r, cliCtx, route, key, &value)
+		if !ok {
+			return
+		}
+		writeQueryResponse(w, cliCtx, res)
+	}
+}
+
+func parseCodeID(w http.ResponseWriter, r *http.Request) (uint64, bool) {
+	codeID, err := strconv.ParseUint(mux.Vars(r)["codeID"], 10, 64)
+	if err != nil {
+		rest.WriteErrorResponse(w, http.StatusBadRequest, "invalid code id: "+err.Error())
+		return 0, false
+	}
+	return codeID, true
+}
+
+func parseContractAddr(w http.ResponseWriter, r *http.Request) (sdk.AccAddress, bool) {
+	addr, err := sdk.AccAddressFromBech32(mux.Vars(r)["contractAddr"])
+	if err != nil {
+		rest.WriteErrorResponse(w, http.StatusBadRequest, "invalid contract address: "+err.Error())
+		return nil, false
+	}
+	return addr, true
+}
 
+// queryTyped runs the query at the requested height and decodes the querier json into target. The raw json is
+// returned together with a context that carries the height of the response. Errors are written to w.
+func queryTyped(w http.ResponseWriter, r *http.Request, cliCtx context.CLIContext, route string, data []byte, target interface{}) (context.CLIContext, json.RawMessage, bool) {
+	cliCtx, ok := rest.ParseQueryHeightOrReturnBadRequest(w, cliCtx, r)
+	if !ok {
+		return cliCtx, nil, false
 	}
+	res, height, err := cliCtx.QueryWithData(route, data)
+	if err != nil {
+		rest.WriteErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return cliCtx, nil, false
+	}
+	if err := json.Unmarshal(res, target); err != nil {
+		rest.WriteErrorResponse(w, http.StatusInternalServerError, "decoding query result: "+err.Error())
+		return cliCtx, nil, false
+	}
+	return cliCtx.WithHeight(height), res, true
+}
+
+// writeQueryResponse writes the querier json as is within the rest response envelope and sets the height header
+func writeQueryResponse(w http.ResponseWriter, cliCtx context.CLIContext, res json.RawMessage) {
+	w.Header().Set(heightHeader, strconv.FormatInt(cliCtx.Height, 10))
+	w.Header().Set("Content-Type", "application/json")
+	// pass raw bytes to not encode the json twice
+	rest.PostProcessResponse(w, cliCtx, []byte(res))
 }
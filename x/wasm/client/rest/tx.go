@@ -26,12 +26,18 @@ const maxSize = 400 * 1024
 type storeCodeReq struct {
 	BaseReq   rest.BaseReq `json:"base_req" yaml:"base_req"`
 	WasmBytes []byte       `json:"wasm_bytes"`
+	// InstantiatePermission restricts who may instantiate the code, optional
+	InstantiatePermission *types.AccessConfig `json:"instantiate_permission,omitempty" yaml:"instantiate_permission"`
 }
 
 type instantiateContractReq struct {
-	BaseReq rest.BaseReq `json:"base_req" yaml:"base_req"`
-	Deposit sdk.Coins    `json:"deposit" yaml:"deposit"`
-	InitMsg []byte       `json:"init_msg" yaml:"init_msg"`
+	BaseReq rest.BaseReq   `json:"base_req" yaml:"base_req"`
+	Deposit sdk.Coins      `json:"deposit" yaml:"deposit"`
+	Admin   sdk.AccAddress `json:"admin,omitempty" yaml:"admin"`
+	Label   string         `json:"label" yaml:"label"`
+	InitMsg []byte         `json:"init_msg" yaml:"init_msg"`
+	// AdminOnlyExecute restricts execution of the contract to the admin, optional
+	AdminOnlyExecute bool `json:"admin_only_execute,omitempty" yaml:"admin_only_execute"`
 }
 
 type executeContractReq struct {
@@ -80,6 +86,8 @@ func storeCodeHandlerFn(cliCtx context.CLIContext) http.HandlerFunc {
 		msg := types.MsgStoreCode{
 			Sender:       fromAddr,
 			WASMByteCode: wasm,
+
+			InstantiatePermission: req.InstantiatePermission,
 		}
 
 		err = msg.ValidateBasic()
@@ -115,8 +123,12 @@ func instantiateContractHandlerFn(cliCtx context.CLIContext) http.HandlerFunc {
 		msg := types.MsgInstantiateContract{
 			Sender:    cliCtx.GetFromAddress(),
 			Code:      codeID,
+			Label:     req.Label,
 			InitFunds: req.Deposit,
 			InitMsg:   req.InitMsg,
+			Admin:     req.Admin,
+
+			AdminOnlyExecute: req.AdminOnlyExecute,
 		}
 
 		err = msg.ValidateBasic()
@@ -2,13 +2,10 @@ package wasm
 
 import (
 	"fmt"
+	"strconv"
 
 	sdk "github.com/cosmos/cosmos-sdk/types"
-)
-
-const (
-	AttributeKeyContract = "contract_address"
-	AttributeKeyCodeID   = "code_id"
+	sdkErrors "github.com/cosmos/cosmos-sdk/types/errors"
 )
 
 // NewHandler returns a handler for "bank" type messages.
@@ -27,11 +24,45 @@ func NewHandler(k Keeper) sdk.Handler {
 		case *MsgInstantiateContract:
 			return handleInstantiate(ctx, k, msg)
 
+		case MsgInstantiateContract2:
+			return handleInstantiate2(ctx, k, &msg)
+		case *MsgInstantiateContract2:
+			return handleInstantiate2(ctx, k, msg)
+
+		case MsgStoreCodeAndInstantiate:
+			return handleStoreCodeAndInstantiate(ctx, k, &msg)
+		case *MsgStoreCodeAndInstantiate:
+			return handleStoreCodeAndInstantiate(ctx, k, msg)
+
 		case MsgExecuteContract:
 			return handleExecute(ctx, k, &msg)
 		case *MsgExecuteContract:
 			return handleExecute(ctx, k, msg)
 
+		case MsgMigrateContract:
+			return handleMigration(ctx, k, &msg)
+		case *MsgMigrateContract:
+			return handleMigration(ctx, k, msg)
+
+		case MsgUpdateAdmin:
+			return handleUpdateContractAdmin(ctx, k, &msg)
+		case *MsgUpdateAdmin:
+			return handleUpdateContractAdmin(ctx, k, msg)
+
+		case MsgClearAdmin:
+			return handleClearContractAdmin(ctx, k, &msg)
+		case *MsgClearAdmin:
+			return handleClearContractAdmin(ctx, k, msg)
+
+		case MsgUpdateInstantiateConfig:
+			return handleUpdateInstantiateConfig(ctx, k, &msg)
+		case *MsgUpdateInstantiateConfig:
+			return handleUpdateInstantiateConfig(ctx, k, msg)
+		case MsgDeprecateCode:
+			return handleDeprecateCode(ctx, k, &msg)
+		case *MsgDeprecateCode:
+			return handleDeprecateCode(ctx, k, msg)
+
 		default:
 			errMsg := fmt.Sprintf("unrecognized wasm message type: %T", msg)
 			return sdk.ErrUnknownRequest(errMsg).Result()
@@ -44,13 +75,17 @@ func handleStoreCode(ctx sdk.Context, k Keeper, msg *MsgStoreCode) sdk.Result {
 	if sdkerr != nil {
 		return sdk.ResultFromError(sdkerr)
 	}
+	if uint64(len(msg.WASMByteCode)) > k.GetParams(ctx).MaxWasmCodeSize {
+		return sdk.ResultFromError(sdkErrors.Wrapf(ErrCodeTooLarge, "max %d bytes", k.GetParams(ctx).MaxWasmCodeSize))
+	}
+	consumeUploadGas(ctx, k, msg.WASMByteCode)
 
-	codeID, err := k.Create(ctx, msg.Sender, msg.WASMByteCode, msg.Source, msg.Builder)
+	codeID, created, err := storeCode(ctx, k, *msg)
 	if err != nil {
 		return sdk.ResultFromError(err)
 	}
 
-	ctx.EventManager().EmitEvent(
+	ctx.EventManager().EmitEvents(sdk.Events{
 		sdk.NewEvent(
 			sdk.EventTypeMessage,
 			sdk.NewAttribute(sdk.AttributeKeyModule, ModuleName),
@@ -58,21 +93,44 @@ func handleStoreCode(ctx sdk.Context, k Keeper, msg *MsgStoreCode) sdk.Result {
 			sdk.NewAttribute(sdk.AttributeKeySender, msg.Sender.String()),
 			sdk.NewAttribute(AttributeKeyCodeID, fmt.Sprintf("%d", codeID)),
 		),
-	)
+		sdk.NewEvent(
+			EventTypeStoreCode,
+			sdk.NewAttribute(AttributeKeyCodeID, fmt.Sprintf("%d", codeID)),
+			sdk.NewAttribute(AttributeKeyCreator, msg.Sender.String()),
+			sdk.NewAttribute(AttributeKeyCodeReused, strconv.FormatBool(!created)),
+		),
+	})
 
+	// the code id is returned as big endian uint64, the same encoding as in the store keys
 	return sdk.Result{
-		Data:   []byte(fmt.Sprintf("%d", codeID)),
+		Data:   sdk.Uint64ToBigEndian(codeID),
 		Events: ctx.EventManager().Events(),
 	}
 }
 
+// storeCode stores the code of the message. Created is false when DeduplicateByHash is set and an existing code
+// with the same hash was returned.
+func storeCode(ctx sdk.Context, k Keeper, msg MsgStoreCode) (codeID uint64, created bool, err error) {
+	if msg.DeduplicateByHash {
+		return k.CreateOrReuse(ctx, msg.Sender, msg.WASMByteCode, msg.Source, msg.Builder, msg.InstantiatePermission)
+	}
+	codeID, err = k.Create(ctx, msg.Sender, msg.WASMByteCode, msg.Source, msg.Builder, msg.InstantiatePermission)
+	return codeID, true, err
+}
+
+// consumeUploadGas charges UploadGasPerByte for every byte of the wasm code as submitted
+func consumeUploadGas(ctx sdk.Context, k Keeper, wasmCode []byte) {
+	gas := k.GetParams(ctx).UploadGasPerByte * uint64(len(wasmCode))
+	ctx.GasMeter().ConsumeGas(gas, "wasm upload")
+}
+
 func handleInstantiate(ctx sdk.Context, k Keeper, msg *MsgInstantiateContract) sdk.Result {
-	contractAddr, err := k.Instantiate(ctx, msg.Code, msg.Sender, msg.InitMsg, msg.InitFunds)
+	contractAddr, err := k.Instantiate(ctx, msg.Code, msg.Sender, msg.Admin, msg.InitMsg, msg.Label, msg.AdminOnlyExecute, msg.InitFunds)
 	if err != nil {
 		return sdk.ResultFromError(err)
 	}
 
-	ctx.EventManager().EmitEvent(
+	ctx.EventManager().EmitEvents(sdk.Events{
 		sdk.NewEvent(
 			sdk.EventTypeMessage,
 			sdk.NewAttribute(sdk.AttributeKeyModule, ModuleName),
@@ -81,7 +139,90 @@ func handleInstantiate(ctx sdk.Context, k Keeper, msg *MsgInstantiateContract) s
 			sdk.NewAttribute(AttributeKeyCodeID, fmt.Sprintf("%d", msg.Code)),
 			sdk.NewAttribute(AttributeKeyContract, contractAddr.String()),
 		),
-	)
+		sdk.NewEvent(
+			EventTypeInstantiate,
+			sdk.NewAttribute(AttributeKeyContract, contractAddr.String()),
+			sdk.NewAttribute(AttributeKeyCodeID, fmt.Sprintf("%d", msg.Code)),
+			sdk.NewAttribute(AttributeKeyFunds, msg.InitFunds.String()),
+		),
+	})
+
+	return sdk.Result{
+		Data:   contractAddr,
+		Events: ctx.EventManager().Events(),
+	}
+}
+
+func handleInstantiate2(ctx sdk.Context, k Keeper, msg *MsgInstantiateContract2) sdk.Result {
+	contractAddr, err := k.Instantiate2(ctx, msg.Code, msg.Sender, msg.Admin, msg.InitMsg, msg.Label, msg.AdminOnlyExecute, msg.InitFunds, msg.Salt)
+	if err != nil {
+		return sdk.ResultFromError(err)
+	}
+
+	ctx.EventManager().EmitEvents(sdk.Events{
+		sdk.NewEvent(
+			sdk.EventTypeMessage,
+			sdk.NewAttribute(sdk.AttributeKeyModule, ModuleName),
+			sdk.NewAttribute(sdk.AttributeKeyAction, "instantiate2"),
+			sdk.NewAttribute(sdk.AttributeKeySender, msg.Sender.String()),
+			sdk.NewAttribute(AttributeKeyCodeID, fmt.Sprintf("%d", msg.Code)),
+			sdk.NewAttribute(AttributeKeyContract, contractAddr.String()),
+		),
+		sdk.NewEvent(
+			EventTypeInstantiate,
+			sdk.NewAttribute(AttributeKeyContract, contractAddr.String()),
+			sdk.NewAttribute(AttributeKeyCodeID, fmt.Sprintf("%d", msg.Code)),
+			sdk.NewAttribute(AttributeKeyFunds, msg.InitFunds.String()),
+		),
+	})
+
+	return sdk.Result{
+		Data:   contractAddr,
+		Events: ctx.EventManager().Events(),
+	}
+}
+
+func handleStoreCodeAndInstantiate(ctx sdk.Context, k Keeper, msg *MsgStoreCodeAndInstantiate) sdk.Result {
+	sdkerr := msg.ValidateBasic()
+	if sdkerr != nil {
+		return sdk.ResultFromError(sdkerr)
+	}
+	if uint64(len(msg.WASMByteCode)) > k.GetParams(ctx).MaxWasmCodeSize {
+		return sdk.ResultFromError(sdkErrors.Wrapf(ErrCodeTooLarge, "max %d bytes", k.GetParams(ctx).MaxWasmCodeSize))
+	}
+	consumeUploadGas(ctx, k, msg.WASMByteCode)
+
+	codeID, created, err := storeCode(ctx, k, msg.StoreCodeMsg())
+	if err != nil {
+		return sdk.ResultFromError(err)
+	}
+	contractAddr, err := k.Instantiate(ctx, codeID, msg.Sender, msg.Admin, msg.InitMsg, msg.Label, msg.AdminOnlyExecute, msg.InitFunds)
+	if err != nil {
+		return sdk.ResultFromError(err)
+	}
+
+	ctx.EventManager().EmitEvents(sdk.Events{
+		sdk.NewEvent(
+			sdk.EventTypeMessage,
+			sdk.NewAttribute(sdk.AttributeKeyModule, ModuleName),
+			sdk.NewAttribute(sdk.AttributeKeyAction, "store-code-and-instantiate"),
+			sdk.NewAttribute(sdk.AttributeKeySender, msg.Sender.String()),
+			sdk.NewAttribute(AttributeKeyCodeID, fmt.Sprintf("%d", codeID)),
+			sdk.NewAttribute(AttributeKeyContract, contractAddr.String()),
+		),
+		sdk.NewEvent(
+			EventTypeStoreCode,
+			sdk.NewAttribute(AttributeKeyCodeID, fmt.Sprintf("%d", codeID)),
+			sdk.NewAttribute(AttributeKeyCreator, msg.Sender.String()),
+			sdk.NewAttribute(AttributeKeyCodeReused, strconv.FormatBool(!created)),
+		),
+		sdk.NewEvent(
+			EventTypeInstantiate,
+			sdk.NewAttribute(AttributeKeyContract, contractAddr.String()),
+			sdk.NewAttribute(AttributeKeyCodeID, fmt.Sprintf("%d", codeID)),
+			sdk.NewAttribute(AttributeKeyFunds, msg.InitFunds.String()),
+		),
+	})
 
 	return sdk.Result{
 		Data:   contractAddr,
@@ -95,7 +236,7 @@ func handleExecute(ctx sdk.Context, k Keeper, msg *MsgExecuteContract) sdk.Resul
 		return sdk.ResultFromError(err)
 	}
 
-	ctx.EventManager().EmitEvent(
+	ctx.EventManager().EmitEvents(sdk.Events{
 		sdk.NewEvent(
 			sdk.EventTypeMessage,
 			sdk.NewAttribute(sdk.AttributeKeyModule, ModuleName),
@@ -103,8 +244,124 @@ func handleExecute(ctx sdk.Context, k Keeper, msg *MsgExecuteContract) sdk.Resul
 			sdk.NewAttribute(sdk.AttributeKeySender, msg.Sender.String()),
 			sdk.NewAttribute(AttributeKeyContract, msg.Contract.String()),
 		),
-	)
+		sdk.NewEvent(
+			EventTypeExecute,
+			sdk.NewAttribute(AttributeKeyContract, msg.Contract.String()),
+			sdk.NewAttribute(AttributeKeySender, msg.Sender.String()),
+			sdk.NewAttribute(AttributeKeyFunds, msg.SentFunds.String()),
+		),
+	})
 
 	res.Events = append(res.Events, ctx.EventManager().Events()...)
 	return res
 }
+
+func handleMigration(ctx sdk.Context, k Keeper, msg *MsgMigrateContract) sdk.Result {
+	err := k.Migrate(ctx, msg.Contract, msg.Sender, msg.Code, msg.MigrateMsg)
+	if err != nil {
+		return sdk.ResultFromError(err)
+	}
+
+	ctx.EventManager().EmitEvents(sdk.Events{
+		sdk.NewEvent(
+			sdk.EventTypeMessage,
+			sdk.NewAttribute(sdk.AttributeKeyModule, ModuleName),
+			sdk.NewAttribute(sdk.AttributeKeyAction, "migrate"),
+			sdk.NewAttribute(sdk.AttributeKeySender, msg.Sender.String()),
+			sdk.NewAttribute(AttributeKeyContract, msg.Contract.String()),
+		),
+		sdk.NewEvent(
+			EventTypeMigrate,
+			sdk.NewAttribute(AttributeKeyContract, msg.Contract.String()),
+			sdk.NewAttribute(AttributeKeyCodeID, fmt.Sprintf("%d", msg.Code)),
+		),
+	})
+
+	return sdk.Result{
+		Events: ctx.EventManager().Events(),
+	}
+}
+
+func handleUpdateContractAdmin(ctx sdk.Context, k Keeper, msg *MsgUpdateAdmin) sdk.Result {
+	err := k.UpdateContractAdmin(ctx, msg.Contract, msg.Sender, msg.NewAdmin)
+	if err != nil {
+		return sdk.ResultFromError(err)
+	}
+
+	ctx.EventManager().EmitEvent(
+		sdk.NewEvent(
+			sdk.EventTypeMessage,
+			sdk.NewAttribute(sdk.AttributeKeyModule, ModuleName),
+			sdk.NewAttribute(sdk.AttributeKeyAction, "update-contract-admin"),
+			sdk.NewAttribute(sdk.AttributeKeySender, msg.Sender.String()),
+			sdk.NewAttribute(AttributeKeyContract, msg.Contract.String()),
+		),
+	)
+
+	return sdk.Result{
+		Events: ctx.EventManager().Events(),
+	}
+}
+
+func handleClearContractAdmin(ctx sdk.Context, k Keeper, msg *MsgClearAdmin) sdk.Result {
+	err := k.ClearContractAdmin(ctx, msg.Contract, msg.Sender)
+	if err != nil {
+		return sdk.ResultFromError(err)
+	}
+
+	ctx.EventManager().EmitEvent(
+		sdk.NewEvent(
+			sdk.EventTypeMessage,
+			sdk.NewAttribute(sdk.AttributeKeyModule, ModuleName),
+			sdk.NewAttribute(sdk.AttributeKeyAction, "clear-contract-admin"),
+			sdk.NewAttribute(sdk.AttributeKeySender, msg.Sender.String()),
+			sdk.NewAttribute(AttributeKeyContract, msg.Contract.String()),
+		),
+	)
+
+	return sdk.Result{
+		Events: ctx.EventManager().Events(),
+	}
+}
+
+func handleUpdateInstantiateConfig(ctx sdk.Context, k Keeper, msg *MsgUpdateInstantiateConfig) sdk.Result {
+	err := k.UpdateInstantiateConfig(ctx, msg.CodeID, msg.Sender, msg.NewPermission, msg.AllowCreatorLockout)
+	if err != nil {
+		return sdk.ResultFromError(err)
+	}
+
+	ctx.EventManager().EmitEvent(
+		sdk.NewEvent(
+			sdk.EventTypeMessage,
+			sdk.NewAttribute(sdk.AttributeKeyModule, ModuleName),
+			sdk.NewAttribute(sdk.AttributeKeyAction, "update-instantiate-config"),
+			sdk.NewAttribute(sdk.AttributeKeySender, msg.Sender.String()),
+			sdk.NewAttribute(AttributeKeyCodeID, fmt.Sprintf("%d", msg.CodeID)),
+		),
+	)
+
+	return sdk.Result{
+		Events: ctx.EventManager().Events(),
+	}
+}
+
+func handleDeprecateCode(ctx sdk.Context, k Keeper, msg *MsgDeprecateCode) sdk.Result {
+	err := k.DeprecateCode(ctx, msg.CodeID, msg.Sender, msg.Deprecated)
+	if err != nil {
+		return sdk.ResultFromError(err)
+	}
+
+	ctx.EventManager().EmitEvent(
+		sdk.NewEvent(
+			sdk.EventTypeMessage,
+			sdk.NewAttribute(sdk.AttributeKeyModule, ModuleName),
+			sdk.NewAttribute(sdk.AttributeKeyAction, "deprecate-code"),
+			sdk.NewAttribute(sdk.AttributeKeySender, msg.Sender.String()),
+			sdk.NewAttribute(AttributeKeyCodeID, fmt.Sprintf("%d", msg.CodeID)),
+		),
+	)
+
+	return sdk.Result{
+		Events: ctx.EventManager().Events(),
+	}
+}
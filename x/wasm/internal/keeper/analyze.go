@@ -0,0 +1,90 @@
+package keeper
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+)
+
+// apiVersionExportPrefix is the prefix of the marker function that contracts export to declare the
+// contract API version they were built against, e.g. cosmwasm_api_0_6
+const apiVersionExportPrefix = "cosmwasm_api_"
+
+const wasmExportSectionID = 7
+
+var (
+	wasmHeader = []byte("\x00asm\x01\x00\x00\x00")
+
+	errMalformedWasm = errors.New("malformed wasm module")
+)
+
+// contractAPIVersion returns the contract API version declared by the exports of the uncompressed wasm
+// code, like "0.6". It is empty when the code declares no version or can not be parsed.
+func contractAPIVersion(wasmCode []byte) string {
+	exports, err := wasmExports(wasmCode)
+	if err != nil {
+		return ""
+	}
+	for _, name := range exports {
+		if strings.HasPrefix(name, apiVersionExportPrefix) {
+			return strings.Replace(strings.TrimPrefix(name, apiVersionExportPrefix), "_", ".", -1)
+		}
+	}
+	return ""
+}
+
+// wasmExports returns the names of all exports of a wasm module in the binary format
+func wasmExports(wasmCode []byte) ([]string, error) {
+	if !bytes.HasPrefix(wasmCode, wasmHeader) {
+		return nil, errMalformedWasm
+	}
+	r := bytes.NewReader(wasmCode[len(wasmHeader):])
+	for r.Len() != 0 {
+		id, err := r.ReadByte()
+		if err != nil {
+			return nil, errMalformedWasm
+		}
+		size, err := binary.ReadUvarint(r)
+		if err != nil || size > uint64(r.Len()) {
+			return nil, errMalformedWasm
+		}
+		section := make([]byte, size)
+		if _, err := io.ReadFull(r, section); err != nil {
+			return nil, errMalformedWasm
+		}
+		if id == wasmExportSectionID {
+			return parseExportSection(section)
+		}
+	}
+	return nil, nil
+}
+
+func parseExportSection(section []byte) ([]string, error) {
+	r := bytes.NewReader(section)
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, errMalformedWasm
+	}
+	var names []string
+	for i := uint64(0); i < count; i++ {
+		nameLen, err := binary.ReadUvarint(r)
+		if err != nil || nameLen > uint64(r.Len()) {
+			return nil, errMalformedWasm
+		}
+		name := make([]byte, nameLen)
+		if _, err := io.ReadFull(r, name); err != nil {
+			return nil, errMalformedWasm
+		}
+		// export kind followed by the index of the exported item
+		if _, err := r.ReadByte(); err != nil {
+			return nil, errMalformedWasm
+		}
+		if _, err := binary.ReadUvarint(r); err != nil {
+			return nil, errMalformedWasm
+		}
+		names = append(names, string(name))
+	}
+	return names, nil
+}
@@ -0,0 +1,56 @@
+package keeper
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContractAPIVersion(t *testing.T) {
+	wasmRaw, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+
+	wasmGzipped, err := ioutil.ReadFile("./testdata/contract.wasm.gzip")
+	require.NoError(t, err)
+
+	specs := map[string]struct {
+		src        []byte
+		expVersion string
+	}{
+		"declared version": {
+			src:        wasmRaw,
+			expVersion: "0.6",
+		},
+		"module without exports": {
+			src: wasmHeader,
+		},
+		"truncated export section": {
+			src: append(append([]byte{}, wasmHeader...), wasmExportSectionID, 100),
+		},
+		"compressed code": {
+			src: wasmGzipped,
+		},
+		"nil slice": {},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			assert.Equal(t, spec.expVersion, contractAPIVersion(spec.src))
+		})
+	}
+}
+
+func TestWasmExports(t *testing.T) {
+	wasmRaw, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+
+	exports, err := wasmExports(wasmRaw)
+	require.NoError(t, err)
+	assert.Contains(t, exports, "init")
+	assert.Contains(t, exports, "handle")
+	assert.Contains(t, exports, "query")
+
+	_, err = wasmExports([]byte("not wasm"))
+	assert.Equal(t, errMalformedWasm, err)
+}
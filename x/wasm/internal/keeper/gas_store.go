@@ -0,0 +1,54 @@
+package keeper
+
+import (
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/cosmwasm/wasmd/x/wasm/internal/types"
+)
+
+const (
+	gasDescContractStoreRead   = "wasm contract store read"
+	gasDescContractStoreWrite  = "wasm contract store write"
+	gasDescContractStoreDelete = "wasm contract store delete"
+)
+
+// contractGasStore is the storage handed to the wasm vm. It charges the contract store gas params per key and
+// value byte on the gas meter of the context, so that large values cost more than small ones.
+type contractGasStore struct {
+	sdk.KVStore
+	gasMeter         sdk.GasMeter
+	readCostPerByte  uint64
+	writeCostPerByte uint64
+}
+
+// newContractGasStore wraps the store when any of the contract store gas params is set
+func newContractGasStore(ctx sdk.Context, store sdk.KVStore, params types.Params) sdk.KVStore {
+	if params.ContractStoreReadGasPerByte == 0 && params.ContractStoreWriteGasPerByte == 0 {
+		return store
+	}
+	return &contractGasStore{
+		KVStore:          store,
+		gasMeter:         ctx.GasMeter(),
+		readCostPerByte:  params.ContractStoreReadGasPerByte,
+		writeCostPerByte: params.ContractStoreWriteGasPerByte,
+	}
+}
+
+// Get charges the read cost for the key and the returned value
+func (s *contractGasStore) Get(key []byte) []byte {
+	value := s.KVStore.Get(key)
+	s.gasMeter.ConsumeGas(s.readCostPerByte*uint64(len(key)+len(value)), gasDescContractStoreRead)
+	return value
+}
+
+// Set charges the write cost for key and value before the value is stored
+func (s *contractGasStore) Set(key, value []byte) {
+	s.gasMeter.ConsumeGas(s.writeCostPerByte*uint64(len(key)+len(value)), gasDescContractStoreWrite)
+	s.KVStore.Set(key, value)
+}
+
+// Delete charges the write cost for the key before it is removed
+func (s *contractGasStore) Delete(key []byte) {
+	s.gasMeter.ConsumeGas(s.writeCostPerByte*uint64(len(key)), gasDescContractStoreDelete)
+	s.KVStore.Delete(key)
+}
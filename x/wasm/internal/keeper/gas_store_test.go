@@ -0,0 +1,99 @@
+package keeper
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/store/prefix"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cosmwasm/wasmd/x/wasm/internal/types"
+)
+
+func TestContractGasStore(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, _, keeper := CreateTestInput(t, false, tempDir)
+
+	params := types.DefaultParams()
+	params.ContractStoreReadGasPerByte = 3
+	params.ContractStoreWriteGasPerByte = 30
+
+	_, _, contractAddr := keyPubAddr()
+	key := []byte("config")
+	// gasFor returns the gas consumed by the store operation on the contract store
+	gasFor := func(op func(store sdk.KVStore)) uint64 {
+		ctx := ctx.WithGasMeter(sdk.NewInfiniteGasMeter())
+		parent := prefix.NewStore(ctx.KVStore(keeper.storeKey), types.GetContractStorePrefixKey(contractAddr))
+		op(newContractGasStore(ctx, parent, params))
+		return ctx.GasMeter().GasConsumed()
+	}
+
+	smallValue := bytes.Repeat([]byte{1}, 100)
+	bigValue := bytes.Repeat([]byte{1}, 1024*1024)
+
+	smallSet := gasFor(func(store sdk.KVStore) { store.Set(key, smallValue) })
+	smallGet := gasFor(func(store sdk.KVStore) { assert.Equal(t, smallValue, store.Get(key)) })
+	bigSet := gasFor(func(store sdk.KVStore) { store.Set(key, bigValue) })
+	bigGet := gasFor(func(store sdk.KVStore) { assert.Equal(t, bigValue, store.Get(key)) })
+	deleteGas := gasFor(func(store sdk.KVStore) { store.Delete(key) })
+
+	// the extra costs come on top of the sdk gas kv store costs
+	assert.True(t, bigSet-smallSet >= params.ContractStoreWriteGasPerByte*uint64(len(bigValue)-len(smallValue)), "big %d, small %d", bigSet, smallSet)
+	assert.True(t, bigGet-smallGet >= params.ContractStoreReadGasPerByte*uint64(len(bigValue)-len(smallValue)), "big %d, small %d", bigGet, smallGet)
+	assert.True(t, deleteGas >= params.ContractStoreWriteGasPerByte*uint64(len(key)))
+	missingGet := gasFor(func(store sdk.KVStore) { assert.Nil(t, store.Get(key)) })
+	assert.True(t, missingGet >= params.ContractStoreReadGasPerByte*uint64(len(key)))
+}
+
+func TestContractGasStoreDisabled(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, _, keeper := CreateTestInput(t, false, tempDir)
+
+	parent := prefix.NewStore(ctx.KVStore(keeper.storeKey), types.ContractStorePrefix)
+	store := newContractGasStore(ctx, parent, types.DefaultParams())
+	assert.Equal(t, parent, store)
+}
+
+func TestInstantiateChargesContractStoreGas(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	codeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	_, _, fred := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: fred, Beneficiary: bob})
+	require.NoError(t, err)
+
+	instantiateGas := func(label string) uint64 {
+		before := ctx.GasMeter().GasConsumed()
+		_, err := keeper.Instantiate(ctx, codeID, creator, nil, initMsgBz, label, false, nil)
+		require.NoError(t, err)
+		return ctx.GasMeter().GasConsumed() - before
+	}
+	withoutCharge := instantiateGas("first")
+
+	params := keeper.GetParams(ctx)
+	params.ContractStoreWriteGasPerByte = 1000
+	keeper.SetParams(ctx, params)
+
+	withCharge := instantiateGas("other")
+	assert.True(t, withCharge > withoutCharge, "with %d, without %d", withCharge, withoutCharge)
+}
@@ -1,7 +1,7 @@
 package keeper
 
 import (
-	"bytes"
+	"fmt"
 
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/cosmwasm/wasmd/x/wasm/internal/types"
@@ -10,62 +10,87 @@ import (
 )
 
 // InitGenesis sets supply information for genesis.
+// Codes and contracts are stored with the IDs and addresses from the genesis. The ID counters are
+// restored from the sequences and derived from the imported data when the genesis has none.
 //
 // CONTRACT: all types of accounts must have been already initialized/created
 func InitGenesis(ctx sdk.Context, keeper Keeper, data types.GenesisState) {
+	keeper.SetParams(ctx, data.Params)
+
+	var maxCodeID uint64
 	for _, code := range data.Codes {
-		newId, err := keeper.Create(ctx, code.CodeInfo.Creator, code.CodesBytes, code.CodeInfo.Source, code.CodeInfo.Builder)
-		if err != nil {
+		if err := keeper.importCode(ctx, code.CodeID, code.CodeInfo, code.CodesBytes); err != nil {
 			panic(err)
 		}
-		newInfo := keeper.GetCodeInfo(ctx, newId)
-		if !bytes.Equal(code.CodeInfo.CodeHash, newInfo.CodeHash) {
-			panic("code hashes not same")
+		if code.Pinned {
+			if err := keeper.PinCode(ctx, code.CodeID); err != nil {
+				panic(err)
+			}
+		}
+		if code.CodeID > maxCodeID {
+			maxCodeID = code.CodeID
 		}
 	}
 
 	for _, contract := range data.Contracts {
-		keeper.setContractInfo(ctx, contract.ContractAddress, contract.ContractInfo)
-		keeper.setContractState(ctx, contract.ContractAddress, contract.ContractState)
+		if err := keeper.importContract(ctx, contract.ContractAddress, contract.ContractInfo, contract.ContractState, contract.ContractCodeHistory); err != nil {
+			panic(err)
+		}
 	}
 
+	// defaults for a genesis without sequences
+	keeper.importAutoIncrementID(ctx, types.KeyLastCodeID, maxCodeID+1)
+	keeper.importAutoIncrementID(ctx, types.KeyLastInstanceID, uint64(len(data.Contracts))+1)
+	for _, seq := range data.Sequences {
+		keeper.importAutoIncrementID(ctx, seq.IDKey, seq.Value)
+	}
+	if next := keeper.GetNextCodeID(ctx); next <= maxCodeID {
+		panic(fmt.Sprintf("code id sequence %d must be greater than the max code id %d", next, maxCodeID))
+	}
 }
 
 // ExportGenesis returns a GenesisState for a given context and keeper.
+// Codes are exported by ascending code ID and contracts by address, so that every node produces the same file.
 func ExportGenesis(ctx sdk.Context, keeper Keeper) types.GenesisState {
 	var genState types.GenesisState
 
-	maxCodeID := keeper.GetNextCodeID(ctx)
-	for i := uint64(1); i < maxCodeID; i++ {
-		bytecode, err := keeper.GetByteCode(ctx, i)
+	genState.Params = keeper.GetParams(ctx)
+
+	keeper.IterateCodeInfos(ctx, func(codeID uint64, info types.CodeInfo) bool {
+		bytecode, err := keeper.GetByteCode(ctx, codeID)
 		if err != nil {
 			panic(err)
 		}
 		genState.Codes = append(genState.Codes, types.Code{
-			CodeInfo:   *keeper.GetCodeInfo(ctx, i),
+			CodeID:     codeID,
+			CodeInfo:   info,
 			CodesBytes: bytecode,
+			Pinned:     keeper.IsPinnedCode(ctx, codeID),
 		})
-	}
+		return false
+	})
 
 	keeper.ListContractInfo(ctx, func(addr sdk.AccAddress, contract types.ContractInfo) bool {
-		contractStateIterator := keeper.GetContractState(ctx, addr)
 		var state []types.Model
-		for ; contractStateIterator.Valid(); contractStateIterator.Next() {
-			m := types.Model{
-				Key:   string(contractStateIterator.Key()),
-				Value: string(contractStateIterator.Value()),
-			}
-			state = append(state, m)
-		}
+		keeper.IterateContractState(ctx, addr, func(key, value []byte) bool {
+			state = append(state, types.Model{Key: key, Value: value})
+			return false
+		})
 
 		genState.Contracts = append(genState.Contracts, types.Contract{
-			ContractAddress: addr,
-			ContractInfo:    contract,
-			ContractState:   state,
+			ContractAddress:     addr,
+			ContractInfo:        contract,
+			ContractState:       state,
+			ContractCodeHistory: keeper.GetContractHistory(ctx, addr),
 		})
 
 		return false
 	})
 
+	genState.Sequences = []types.Sequence{
+		{IDKey: types.KeyLastCodeID, Value: keeper.GetNextCodeID(ctx)},
+		{IDKey: types.KeyLastInstanceID, Value: keeper.GetNextInstanceID(ctx)},
+	}
+
 	return genState
 }
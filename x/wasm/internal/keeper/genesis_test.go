@@ -0,0 +1,134 @@
+package keeper
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cosmwasm/wasmd/x/wasm/internal/types"
+)
+
+func TestGenesisExportImportRoundTrip(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	srcCtx, accKeeper, srcKeeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(srcCtx, accKeeper, deposit)
+	admin := createFakeFundedAccount(srcCtx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	codeID, err := srcKeeper.Create(srcCtx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+	newCodeID, err := srcKeeper.Create(srcCtx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: creator, Beneficiary: bob})
+	require.NoError(t, err)
+	srcCtx = srcCtx.WithBlockHeight(7)
+	contractAddr, err := srcKeeper.Instantiate(srcCtx, codeID, creator, admin, initMsgBz, "demo contract", false, deposit)
+	require.NoError(t, err)
+	srcKeeper.setContractState(srcCtx, contractAddr, []types.Model{{Key: []byte("foo"), Value: []byte("bar")}})
+	migratedAddr, err := srcKeeper.Instantiate(srcCtx, codeID, creator, admin, initMsgBz, "migrated contract", false, nil)
+	require.NoError(t, err)
+	require.NoError(t, srcKeeper.Migrate(srcCtx.WithBlockHeight(8), migratedAddr, admin, newCodeID, []byte(`{}`)))
+
+	// import into two fresh chains, the second one from the export of the first one
+	tempDir1, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir1)
+	ctx1, _, keeper1 := CreateTestInput(t, false, tempDir1)
+	InitGenesis(ctx1, keeper1, ExportGenesis(srcCtx, srcKeeper))
+
+	tempDir2, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir2)
+	ctx2, _, keeper2 := CreateTestInput(t, false, tempDir2)
+	InitGenesis(ctx2, keeper2, ExportGenesis(ctx1, keeper1))
+
+	// the contract info is restored verbatim
+	srcInfo := srcKeeper.GetContractInfo(srcCtx, contractAddr)
+	require.NotNil(t, srcInfo)
+	importedInfo := keeper1.GetContractInfo(ctx1, contractAddr)
+	require.NotNil(t, importedInfo)
+	assert.Equal(t, *srcInfo, *importedInfo)
+	assert.Equal(t, "demo contract", importedInfo.Label)
+	assert.Equal(t, admin, importedInfo.Admin)
+	assert.Equal(t, codeID, importedInfo.CodeID)
+	require.NotNil(t, importedInfo.Created)
+	assert.Equal(t, int64(7), importedInfo.Created.BlockHeight)
+
+	// and so is the contract state
+	srcState, err := collectModels(srcKeeper.GetContractState(srcCtx, contractAddr), types.DefaultMaxQueryResultEntries)
+	require.NoError(t, err)
+	importedState, err := collectModels(keeper1.GetContractState(ctx1, contractAddr), types.DefaultMaxQueryResultEntries)
+	require.NoError(t, err)
+	assert.Equal(t, srcState, importedState)
+
+	// the code history of the migrated contract is kept with the original code id
+	srcHistory := srcKeeper.GetContractHistory(srcCtx, migratedAddr)
+	require.Len(t, srcHistory, 2)
+	assert.Equal(t, srcHistory, keeper1.GetContractHistory(ctx1, migratedAddr))
+	assert.Equal(t, codeID, keeper1.GetContractHistory(ctx1, migratedAddr)[0].CodeID)
+
+	// the imports produce stores that are byte identical to the source
+	assert.Equal(t, dumpStore(srcCtx, srcKeeper), dumpStore(ctx1, keeper1))
+	assert.Equal(t, dumpStore(srcCtx, srcKeeper), dumpStore(ctx2, keeper2))
+}
+
+func TestInitGenesisPinnedCode(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	srcCtx, accKeeper, srcKeeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(srcCtx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	pinnedID, err := srcKeeper.StoreCodeAndPin(srcCtx, types.MsgStoreCode{Sender: creator, WASMByteCode: wasmCode})
+	require.NoError(t, err)
+	unpinnedID, err := srcKeeper.Create(srcCtx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+
+	genesis := ExportGenesis(srcCtx, srcKeeper)
+	require.Len(t, genesis.Codes, 2)
+	assert.True(t, genesis.Codes[0].Pinned)
+	assert.False(t, genesis.Codes[1].Pinned)
+
+	tempDir1, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir1)
+	ctx, accKeeper1, keeper := CreateTestInput(t, false, tempDir1)
+	InitGenesis(ctx, keeper, genesis)
+
+	assert.True(t, keeper.IsPinnedCode(ctx, pinnedID))
+	assert.False(t, keeper.IsPinnedCode(ctx, unpinnedID))
+	// the code is compiled on import and can be instantiated right away
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: creator, Beneficiary: bob})
+	require.NoError(t, err)
+	anyAddr := createFakeFundedAccount(ctx, accKeeper1, deposit)
+	_, err = keeper.Instantiate(ctx, pinnedID, anyAddr, nil, initMsgBz, "demo contract", false, nil)
+	require.NoError(t, err)
+}
+
+// dumpStore returns all raw entries of the wasm store in key order
+func dumpStore(ctx sdk.Context, keeper Keeper) []types.Model {
+	iter := ctx.KVStore(keeper.storeKey).Iterator(nil, nil)
+	defer iter.Close()
+	var models []types.Model
+	for ; iter.Valid(); iter.Next() {
+		models = append(models, types.Model{Key: iter.Key(), Value: iter.Value()})
+	}
+	return models
+}
@@ -3,6 +3,7 @@ package keeper
 import (
 	"bytes"
 	"compress/gzip"
+	"errors"
 	"io"
 	"io/ioutil"
 )
@@ -12,15 +13,35 @@ import (
 // and https://github.com/golang/go/blob/master/src/net/http/sniff.go#L186
 var gzipIdent = []byte("\x1F\x8B\x08")
 
-// limit max bytes read to prevent gzip bombs
-const maxSize = 400 * 1024
+// errLimit is returned when the uncompressed content exceeds the given limit
+var errLimit = errors.New("exceeds limit")
 
-// uncompress returns gzip uncompressed content or given src when not gzip.
-func uncompress(src []byte) ([]byte, error) {
-	if len(src) < 3 {
-		return src, nil
+// isGzip returns true when the content starts with the gzip magic bytes
+func isGzip(src []byte) bool {
+	return len(src) >= 3 && bytes.Equal(gzipIdent, src[0:3])
+}
+
+// compress returns the gzip compressed content of src
+func compress(src []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := zw.Write(src); err != nil {
+		return nil, err
 	}
-	if !bytes.Equal(gzipIdent, src[0:3]) {
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// uncompress returns gzip uncompressed content or given src when not gzip.
+// The uncompressed content must not exceed limit bytes to prevent gzip bombs. Reading stops as soon as
+// the limit is passed, so the content is never fully inflated.
+func uncompress(src []byte, limit uint64) ([]byte, error) {
+	if !isGzip(src) {
 		return src, nil
 	}
 	zr, err := gzip.NewReader(bytes.NewReader(src))
@@ -29,5 +50,12 @@ func uncompress(src []byte) ([]byte, error) {
 	}
 	zr.Multistream(false)
 
-	return ioutil.ReadAll(io.LimitReader(zr, maxSize))
+	bz, err := ioutil.ReadAll(io.LimitReader(zr, int64(limit+1)))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(bz)) > limit {
+		return nil, errLimit
+	}
+	return bz, nil
 }
@@ -11,6 +11,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/cosmwasm/wasmd/x/wasm/internal/types"
 )
 
 func TestUncompress(t *testing.T) {
@@ -20,6 +22,8 @@ func TestUncompress(t *testing.T) {
 	wasmGzipped, err := ioutil.ReadFile("./testdata/contract.wasm.gzip")
 	require.NoError(t, err)
 
+	const maxSize = types.DefaultMaxWasmCodeSize
+
 	specs := map[string]struct {
 		src       []byte
 		expError  error
@@ -57,18 +61,22 @@ func TestUncompress(t *testing.T) {
 			src:      wasmGzipped[:len(wasmGzipped)-5],
 			expError: io.ErrUnexpectedEOF,
 		},
+		"handle gzip output at limit": {
+			src:       asGzip(strings.Repeat("a", maxSize)),
+			expResult: []byte(strings.Repeat("a", maxSize)),
+		},
 		"handle big gzip output": {
 			src:      asGzip(strings.Repeat("a", maxSize+1)),
-			expError: io.ErrUnexpectedEOF,
+			expError: errLimit,
 		},
 		"handle other big gzip output": {
 			src:      asGzip(strings.Repeat("a", 2*maxSize)),
-			expError: io.ErrUnexpectedEOF,
+			expError: errLimit,
 		},
 	}
 	for msg, spec := range specs {
 		t.Run(msg, func(t *testing.T) {
-			r, err := uncompress(spec.src)
+			r, err := uncompress(spec.src, maxSize)
 			require.True(t, errors.Is(spec.expError, err), "exp %+v got %+v", spec.expError, err)
 			if spec.expError != nil {
 				return
@@ -81,7 +89,11 @@ func TestUncompress(t *testing.T) {
 
 func asGzip(src string) []byte {
 	var buf bytes.Buffer
-	if _, err := io.Copy(gzip.NewWriter(&buf), strings.NewReader(src)); err != nil {
+	zipper := gzip.NewWriter(&buf)
+	if _, err := io.Copy(zipper, strings.NewReader(src)); err != nil {
+		panic(err)
+	}
+	if err := zipper.Close(); err != nil {
 		panic(err)
 	}
 	return buf.Bytes()
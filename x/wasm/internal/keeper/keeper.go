@@ -1,6 +1,7 @@
 package keeper
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"path/filepath"
@@ -14,6 +15,8 @@ import (
 	"github.com/cosmos/cosmos-sdk/x/auth"
 	"github.com/cosmos/cosmos-sdk/x/auth/exported"
 	"github.com/cosmos/cosmos-sdk/x/bank"
+	"github.com/cosmos/cosmos-sdk/x/params"
+	"github.com/cosmos/cosmos-sdk/x/staking"
 	"github.com/tendermint/tendermint/crypto"
 
 	"github.com/cosmwasm/wasmd/x/wasm/internal/types"
@@ -28,10 +31,15 @@ const GasMultiplier = 100
 // MaxGas for a contract is 900 million (enforced in rust)
 const MaxGas = 900_000_000
 
+// MaxDispatchDepth is how deep contracts can call other contracts through the messages they return.
+// The total number of calls is bounded by the gas limit of the transaction.
+const MaxDispatchDepth = 10
+
 // Keeper will have a reference to Wasmer with it's own data directory.
 type Keeper struct {
 	storeKey      sdk.StoreKey
 	cdc           *codec.Codec
+	paramSpace    params.Subspace
 	accountKeeper auth.AccountKeeper
 	bankKeeper    bank.Keeper
 
@@ -40,52 +48,178 @@ type Keeper struct {
 	wasmer wasm.Wasmer
 	// queryGasLimit is the max wasm gas that can be spent on executing a query with a contract
 	queryGasLimit uint64
+	// queryDebug disables the redaction of querier errors
+	queryDebug bool
+	// hooks are optional and called after contract lifecycle events
+	hooks types.WasmHooks
+	// metrics are nil unless enabled in the WasmConfig
+	metrics *contractMetrics
+	// executed is nil unless enabled in the WasmConfig
+	executed *executedContracts
 }
 
 // NewKeeper creates a new contract Keeper instance
-func NewKeeper(cdc *codec.Codec, storeKey sdk.StoreKey, accountKeeper auth.AccountKeeper, bankKeeper bank.Keeper,
+func NewKeeper(cdc *codec.Codec, storeKey sdk.StoreKey, paramSpace params.Subspace, accountKeeper auth.AccountKeeper, bankKeeper bank.Keeper,
 	router sdk.Router, homeDir string, wasmConfig types.WasmConfig) Keeper {
 	wasmer, err := wasm.NewWasmer(filepath.Join(homeDir, "wasm"), wasmConfig.CacheSize)
 	if err != nil {
 		panic(err)
 	}
 
+	var metrics *contractMetrics
+	if wasmConfig.ContractMetrics {
+		metrics = newContractMetrics()
+	}
+	var executed *executedContracts
+	if wasmConfig.ExecutedContractsIndex {
+		executed = newExecutedContracts()
+	}
 	return Keeper{
 		storeKey:      storeKey,
 		cdc:           cdc,
+		paramSpace:    paramSpace.WithKeyTable(types.ParamKeyTable()),
 		wasmer:        *wasmer,
 		accountKeeper: accountKeeper,
 		bankKeeper:    bankKeeper,
 		router:        router,
 		queryGasLimit: wasmConfig.SmartQueryGasLimit,
+		queryDebug:    wasmConfig.QueryDebug,
+		metrics:       metrics,
+		executed:      executed,
 	}
 }
 
+// BeginBlock resets the index of the contracts executed in the block, when it is enabled
+func (k Keeper) BeginBlock(ctx sdk.Context) {
+	k.executed.reset(ctx.BlockHeight())
+}
+
+// SetHooks sets the wasm hooks. It can only be called once, use MultiWasmHooks to register more than one.
+func (k *Keeper) SetHooks(h types.WasmHooks) *Keeper {
+	if k.hooks != nil {
+		panic("cannot set wasm hooks twice")
+	}
+	k.hooks = h
+	return k
+}
+
+// GetParams returns the total set of wasm parameters.
+func (k Keeper) GetParams(ctx sdk.Context) types.Params {
+	var params types.Params
+	k.paramSpace.GetParamSet(ctx, &params)
+	return params
+}
+
+// SetParams sets the total set of wasm parameters.
+func (k Keeper) SetParams(ctx sdk.Context, ps types.Params) {
+	k.paramSpace.SetParamSet(ctx, &ps)
+}
+
 // Create uploads and compiles a WASM contract, returning a short identifier for the contract
-func (k Keeper) Create(ctx sdk.Context, creator sdk.AccAddress, wasmCode []byte, source string, builder string) (codeID uint64, err error) {
-	wasmCode, err = uncompress(wasmCode)
+// The instantiatePermission is optional and defaults to everybody.
+func (k Keeper) Create(ctx sdk.Context, creator sdk.AccAddress, wasmCode []byte, source string, builder string, instantiatePermission *types.AccessConfig) (codeID uint64, err error) {
+	codeID, _, err = k.create(ctx, creator, wasmCode, source, builder, instantiatePermission, false)
+	return codeID, err
+}
+
+// StoreCodeAndPin stores the code of the message like Create and adds it to the pinned code index in the same
+// call, so that no block sees the code unpinned. The code is compiled by the VM on store already; see PinCode
+// for why pinning does not keep the module in memory with the VM in use.
+func (k Keeper) StoreCodeAndPin(ctx sdk.Context, msg types.MsgStoreCode) (uint64, error) {
+	codeID, err := k.Create(ctx, msg.Sender, msg.WASMByteCode, msg.Source, msg.Builder, msg.InstantiatePermission)
+	if err != nil {
+		return 0, err
+	}
+	if err := k.PinCode(ctx, codeID); err != nil {
+		return 0, err
+	}
+	return codeID, nil
+}
+
+// CreateOrReuse works like Create but returns the lowest code ID with the same code hash when the code was
+// stored before. The existing code keeps its creator, source, builder and instantiate permission.
+// Created is false when an existing code was reused.
+func (k Keeper) CreateOrReuse(ctx sdk.Context, creator sdk.AccAddress, wasmCode []byte, source string, builder string, instantiatePermission *types.AccessConfig) (codeID uint64, created bool, err error) {
+	return k.create(ctx, creator, wasmCode, source, builder, instantiatePermission, true)
+}
+
+func (k Keeper) create(ctx sdk.Context, creator sdk.AccAddress, wasmCode []byte, source string, builder string, instantiatePermission *types.AccessConfig, reuse bool) (codeID uint64, created bool, err error) {
+	params := k.GetParams(ctx)
+	if !params.AllowGzipUpload && isGzip(wasmCode) {
+		return 0, false, sdkErrors.Wrap(types.ErrInvalidWasmCode, "gzip compressed code is not allowed")
+	}
+	wasmCode, err = uncompress(wasmCode, params.MaxWasmCodeSize)
 	if err != nil {
-		return 0, sdkErrors.Wrap(types.ErrCreateFailed, err.Error())
+		return 0, false, uncompressError(err)
 	}
 	codeHash, err := k.wasmer.Create(wasmCode)
 	if err != nil {
 		// return 0, sdkErrors.Wrap(err, "cosmwasm create")
-		return 0, sdkErrors.Wrap(types.ErrCreateFailed, err.Error())
+		return 0, false, sdkErrors.Wrap(types.ErrCreateFailed, err.Error())
+	}
+	if reuse {
+		var existingID uint64
+		k.IterateCodesByHash(ctx, codeHash, func(id uint64, _ types.CodeInfo) bool {
+			existingID = id
+			return true
+		})
+		if existingID != 0 {
+			return existingID, false, nil
+		}
 	}
 
 	store := ctx.KVStore(k.storeKey)
 	codeID = k.autoIncrementID(ctx, types.KeyLastCodeID)
-	contractInfo := types.NewCodeInfo(codeHash, creator, source, builder)
+	permission := types.AllowEverybody
+	if instantiatePermission != nil {
+		permission = *instantiatePermission
+	}
+	contractInfo := types.NewCodeInfo(codeHash, creator, source, builder, permission, contractAPIVersion(wasmCode))
 	// 0x01 | codeID (uint64) -> ContractInfo
 	store.Set(types.GetCodeKey(codeID), k.cdc.MustMarshalBinaryBare(contractInfo))
+	k.addToCodeCreatorSecondaryIndex(ctx, creator, codeID)
+	k.addToCodeHashSecondaryIndex(ctx, codeHash, codeID)
+	k.addToCodeStorageStats(ctx, wasmCode)
+	if k.hooks != nil && !isSimulation(ctx) {
+		k.hooks.AfterStoreCode(ctx, codeID, creator)
+	}
 
-	return codeID, nil
+	return codeID, true, nil
 }
 
-// Instantiate creates an instance of a WASM contract
-func (k Keeper) Instantiate(ctx sdk.Context, codeID uint64, creator sdk.AccAddress, initMsg []byte, deposit sdk.Coins) (sdk.AccAddress, error) {
+// Instantiate creates an instance of a WASM contract. The admin is optional and the only account allowed to migrate
+// the contract later.
+func (k Keeper) Instantiate(ctx sdk.Context, codeID uint64, creator, admin sdk.AccAddress, initMsg []byte, label string, adminOnlyExecute bool, deposit sdk.Coins) (sdk.AccAddress, error) {
 	// create contract address
 	contractAddress := k.generateContractAddress(ctx, codeID)
+	return k.instantiate(ctx, contractAddress, codeID, creator, admin, initMsg, label, adminOnlyExecute, deposit)
+}
+
+// Instantiate2 creates an instance of a WASM contract like Instantiate but at an address that is derived
+// from the code id, creator and salt. See PredictableContractAddress for how the address is built.
+// It fails when an account exists at the derived address already.
+func (k Keeper) Instantiate2(ctx sdk.Context, codeID uint64, creator, admin sdk.AccAddress, initMsg []byte, label string, adminOnlyExecute bool, deposit sdk.Coins, salt []byte) (sdk.AccAddress, error) {
+	contractAddress := PredictableContractAddress(codeID, creator, salt)
+	contractAddress, err := k.instantiate(ctx, contractAddress, codeID, creator, admin, initMsg, label, adminOnlyExecute, deposit)
+	if err != nil {
+		return nil, err
+	}
+	// the instance sequence is still increased so that it keeps counting all contracts
+	k.autoIncrementID(ctx, types.KeyLastInstanceID)
+	return contractAddress, nil
+}
+
+func (k Keeper) instantiate(ctx sdk.Context, contractAddress sdk.AccAddress, codeID uint64, creator, admin sdk.AccAddress, initMsg []byte, label string, adminOnlyExecute bool, deposit sdk.Coins) (sdk.AccAddress, error) {
+	if adminOnlyExecute && admin.Empty() {
+		return nil, sdkErrors.Wrap(types.ErrInstantiateFailed, "admin only execute requires an admin")
+	}
+	// enforced here rather than in the handler so that instantiations dispatched by contracts are covered, too
+	if uint64(len(initMsg)) > k.GetParams(ctx).MaxInitMsgSize {
+		return nil, sdkErrors.Wrap(types.ErrInstantiateFailed, "init msg too large")
+	}
+	if err := checkLabelAndFunds(k.GetParams(ctx), label, deposit); err != nil {
+		return nil, sdkErrors.Wrap(types.ErrInstantiateFailed, err.Error())
+	}
 	existingAcct := k.accountKeeper.GetAccount(ctx, contractAddress)
 	if existingAcct != nil {
 		return nil, sdkErrors.Wrap(types.ErrAccountExists, existingAcct.GetAddress().String())
@@ -106,23 +240,41 @@ func (k Keeper) Instantiate(ctx sdk.Context, codeID uint64, creator sdk.AccAddre
 	}
 	var codeInfo types.CodeInfo
 	k.cdc.MustUnmarshalBinaryBare(bz, &codeInfo)
+	if !codeInfo.InstantiateConfig.Allowed(creator) {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrUnauthorized, "can not instantiate")
+	}
+	if codeInfo.Deprecated {
+		if k.GetParams(ctx).RejectDeprecatedInstantiate {
+			return nil, sdkErrors.Wrap(types.ErrInstantiateFailed, "code is deprecated")
+		}
+		ctx.EventManager().EmitEvent(sdk.NewEvent(
+			types.EventTypeInstantiateDeprecated,
+			sdk.NewAttribute(types.AttributeKeyCodeID, fmt.Sprintf("%d", codeID)),
+		))
+	}
+
+	// contracts called back from the messages of the new contract see it on the call stack
+	ctx = pushCallStack(ctx, contractAddress)
 
 	// prepare params for contract instantiate call
 	params := types.NewParams(ctx, creator, deposit, contractAccount)
 
-	// create prefixed data store
-	// 0x03 | contractAddress (sdk.AccAddress)
-	prefixStoreKey := types.GetContractStorePrefixKey(contractAddress)
-	prefixStore := prefix.NewStore(ctx.KVStore(k.storeKey), prefixStoreKey)
-
 	// instantiate wasm contract
-	gas := gasForContract(ctx)
-	res, err := k.wasmer.Instantiate(codeInfo.CodeHash, params, initMsg, prefixStore, cosmwasmAPI, gas)
+	var res *wasmTypes.Result
+	err := k.withContractGasLimit(ctx, func(ctx sdk.Context) error {
+		var vmErr error
+		res, vmErr = k.wasmer.Instantiate(codeInfo.CodeHash, params, initMsg, k.contractStore(ctx, contractAddress), cosmwasmAPI, gasForContract(ctx))
+		if vmErr != nil {
+			return sdkErrors.Wrap(types.ErrInstantiateFailed, vmErr.Error())
+			// return sdkErrors.Wrap(err, "cosmwasm instantiate")
+		}
+		consumeGas(ctx, res.GasUsed)
+		return nil
+	})
 	if err != nil {
-		return contractAddress, sdkErrors.Wrap(types.ErrInstantiateFailed, err.Error())
-		// return contractAddress, sdkErrors.Wrap(err, "cosmwasm instantiate")
+		return contractAddress, err
 	}
-	consumeGas(ctx, res.GasUsed)
+	k.metrics.record(ctx, codeID, contractAddress, res.GasUsed/GasMultiplier)
 
 	err = k.dispatchMessages(ctx, contractAccount, res.Messages)
 	if err != nil {
@@ -130,47 +282,279 @@ func (k Keeper) Instantiate(ctx sdk.Context, codeID uint64, creator sdk.AccAddre
 	}
 
 	// persist instance
-	instance := types.NewContractInfo(codeID, creator, string(initMsg))
+	instance := types.NewContractInfo(codeID, creator, admin, string(initMsg), label, types.NewAbsoluteTxPosition(ctx))
+	instance.AdminOnlyExecute = adminOnlyExecute
 	// 0x02 | contractAddress (sdk.AccAddress) -> Instance
 	store.Set(types.GetContractAddressKey(contractAddress), k.cdc.MustMarshalBinaryBare(instance))
+	k.addToContractCodeSecondaryIndex(ctx, contractAddress, codeID)
+	k.addToContractLabelSecondaryIndex(ctx, contractAddress, creator, label)
+	k.addToContractCreatedSecondaryIndex(ctx, contractAddress, instance.Created)
+	k.appendToContractHistory(ctx, contractAddress, types.ContractCodeHistoryEntry{
+		Operation: types.InitContractCodeHistoryType,
+		CodeID:    codeID,
+		Updated:   ctx.BlockHeight(),
+		Msg:       initMsg,
+	})
+	if k.hooks != nil && !isSimulation(ctx) {
+		k.hooks.AfterInstantiate(ctx, codeID, contractAddress, creator)
+	}
 
 	return contractAddress, nil
 }
 
 // Execute executes the contract instance
 func (k Keeper) Execute(ctx sdk.Context, contractAddress sdk.AccAddress, caller sdk.AccAddress, msg []byte, coins sdk.Coins) (sdk.Result, error) {
-	codeInfo, prefixStore, err := k.contractInstance(ctx, contractAddress)
+	if uint64(len(msg)) > k.GetParams(ctx).MaxExecuteMsgSize {
+		return sdk.Result{}, sdkErrors.Wrap(types.ErrExecuteFailed, "msg too large")
+	}
+	if err := checkFunds(k.GetParams(ctx), coins); err != nil {
+		return sdk.Result{}, sdkErrors.Wrap(types.ErrExecuteFailed, err.Error())
+	}
+	contractInfo, codeInfo, _, err := k.contractInstance(ctx, contractAddress)
 	if err != nil {
 		return sdk.Result{}, err
 	}
-	// add more funds
-	sdkerr := k.bankKeeper.SendCoins(ctx, caller, contractAddress, coins)
-	if sdkerr != nil {
-		return sdk.Result{}, sdkerr
+	// rejected before any funds are moved or wasm is run
+	if contractInfo.AdminOnlyExecute && !contractInfo.Admin.Equals(caller) {
+		return sdk.Result{}, sdkErrors.Wrap(sdkErrors.ErrUnauthorized, "caller is not the contract admin")
+	}
+	if ctx, err = k.enterContract(ctx, contractAddress); err != nil {
+		return sdk.Result{}, err
+	}
+	// add more funds; calls without funds skip the bank keeper entirely
+	if !coins.IsZero() {
+		sdkerr := k.bankKeeper.SendCoins(ctx, caller, contractAddress, coins)
+		if sdkerr != nil {
+			return sdk.Result{}, sdkerr
+		}
 	}
 	contractAccount := k.accountKeeper.GetAccount(ctx, contractAddress)
 	params := types.NewParams(ctx, caller, coins, contractAccount)
 
-	gas := gasForContract(ctx)
-	res, execErr := k.wasmer.Execute(codeInfo.CodeHash, params, msg, prefixStore, cosmwasmAPI, gas)
-	if execErr != nil {
-		return sdk.Result{}, sdkErrors.Wrap(types.ErrExecuteFailed, execErr.Error())
+	var res *wasmTypes.Result
+	err = k.withContractGasLimit(ctx, func(ctx sdk.Context) error {
+		var execErr error
+		res, execErr = k.wasmer.Execute(codeInfo.CodeHash, params, msg, k.contractStore(ctx, contractAddress), cosmwasmAPI, gasForContract(ctx))
+		if execErr != nil {
+			return sdkErrors.Wrap(types.ErrExecuteFailed, execErr.Error())
+		}
+		consumeGas(ctx, res.GasUsed)
+		return nil
+	})
+	if err != nil {
+		return sdk.Result{}, err
 	}
-	consumeGas(ctx, res.GasUsed)
+	k.metrics.record(ctx, contractInfo.CodeID, contractAddress, res.GasUsed/GasMultiplier)
+	k.executed.record(ctx, contractAddress)
 
 	err = k.dispatchMessages(ctx, contractAccount, res.Messages)
 	if err != nil {
 		return sdk.Result{}, err
 	}
+	if k.hooks != nil && !isSimulation(ctx) {
+		k.hooks.AfterExecute(ctx, contractAddress, caller)
+	}
 
 	return types.CosmosResult(*res), nil
 }
 
-// QuerySmart queries the smart contract itself.
-func (k Keeper) QuerySmart(ctx sdk.Context, contractAddr sdk.AccAddress, req []byte) ([]byte, error) {
+// checkLabelAndFunds enforces the label and funds limits of the params, which can be tighter than
+// the bounds checked in ValidateBasic
+func checkLabelAndFunds(params types.Params, label string, funds sdk.Coins) error {
+	if uint64(len(label)) > params.MaxLabelSize {
+		return fmt.Errorf("label too long")
+	}
+	return checkFunds(params, funds)
+}
+
+// checkFunds rejects funds with too many denoms or a denom that is not in the accepted fund denoms of the params
+func checkFunds(params types.Params, funds sdk.Coins) error {
+	if uint64(len(funds)) > params.MaxFundsCoins {
+		return fmt.Errorf("too many funds denoms")
+	}
+	for _, c := range funds {
+		if !params.FundDenomAccepted(c.Denom) {
+			return fmt.Errorf("denom not accepted as contract funds: %s", c.Denom)
+		}
+	}
+	return nil
+}
+
+// Migrate switches the contract to the given code. Only the contract admin is allowed to do this.
+// The go-cosmwasm version in use does not expose a migrate export, so the new code takes over the
+// existing contract state as-is.
+func (k Keeper) Migrate(ctx sdk.Context, contractAddress sdk.AccAddress, caller sdk.AccAddress, newCodeID uint64, msg []byte) error {
+	contractInfo := k.GetContractInfo(ctx, contractAddress)
+	if contractInfo == nil {
+		return sdkErrors.Wrap(types.ErrNotFound, "contract")
+	}
+	if contractInfo.Admin.Empty() {
+		return sdkErrors.Wrap(types.ErrMigrationFailed, "contract has no admin")
+	}
+	if !contractInfo.Admin.Equals(caller) {
+		return sdkErrors.Wrap(sdkErrors.ErrUnauthorized, "caller is not the contract admin")
+	}
+	return k.migrate(ctx, contractAddress, *contractInfo, newCodeID, msg)
+}
+
+// MigrateByGovernance switches the contract to the given code without any admin check. This is meant for
+// migrations approved by a governance proposal and works for contracts without an admin, too.
+func (k Keeper) MigrateByGovernance(ctx sdk.Context, contractAddress sdk.AccAddress, newCodeID uint64, msg []byte) error {
+	contractInfo := k.GetContractInfo(ctx, contractAddress)
+	if contractInfo == nil {
+		return sdkErrors.Wrap(types.ErrNotFound, "contract")
+	}
+	return k.migrate(ctx, contractAddress, *contractInfo, newCodeID, msg)
+}
+
+// MigrateAllContractsByGovernance switches the contracts of the old code to the new code without any admin
+// check. At most MaxMigrateAllContracts contracts are migrated, the ones left over stay on the old code for a
+// follow-up call. Returns the addresses of the migrated contracts.
+func (k Keeper) MigrateAllContractsByGovernance(ctx sdk.Context, oldCodeID, newCodeID uint64, msg []byte) ([]sdk.AccAddress, error) {
+	if k.GetCodeInfo(ctx, oldCodeID) == nil {
+		return nil, sdkErrors.Wrap(types.ErrNotFound, "old code")
+	}
+	if k.GetCodeInfo(ctx, newCodeID) == nil {
+		return nil, sdkErrors.Wrap(types.ErrNotFound, "new code")
+	}
+	max := k.GetParams(ctx).MaxMigrateAllContracts
+	// collect first as migrating modifies the index that is iterated
+	var addrs []sdk.AccAddress
+	var infos []types.ContractInfo
+	k.IterateContractsByCode(ctx, oldCodeID, func(addr sdk.AccAddress, info types.ContractInfo) bool {
+		addrs = append(addrs, addr)
+		infos = append(infos, info)
+		return uint64(len(addrs)) >= max
+	})
+	for i := range addrs {
+		if err := k.migrate(ctx, addrs[i], infos[i], newCodeID, msg); err != nil {
+			return nil, sdkErrors.Wrapf(err, "contract: %s", addrs[i])
+		}
+	}
+	return addrs, nil
+}
+
+func (k Keeper) migrate(ctx sdk.Context, contractAddress sdk.AccAddress, contractInfo types.ContractInfo, newCodeID uint64, msg []byte) error {
+	if k.GetCodeInfo(ctx, newCodeID) == nil {
+		return sdkErrors.Wrap(types.ErrNotFound, "code")
+	}
+
+	oldCodeID := contractInfo.CodeID
+	k.removeFromContractCodeSecondaryIndex(ctx, contractAddress, oldCodeID)
+	contractInfo.CodeID = newCodeID
+	k.setContractInfo(ctx, contractAddress, contractInfo)
+	k.addToContractCodeSecondaryIndex(ctx, contractAddress, newCodeID)
+	k.appendToContractHistory(ctx, contractAddress, types.ContractCodeHistoryEntry{
+		Operation: types.MigrateContractCodeHistoryType,
+		CodeID:    newCodeID,
+		Updated:   ctx.BlockHeight(),
+		Msg:       msg,
+	})
+	if k.hooks != nil && !isSimulation(ctx) {
+		k.hooks.AfterMigrate(ctx, contractAddress, oldCodeID, newCodeID)
+	}
+	return nil
+}
+
+// UpdateContractAdmin sets the admin value on the ContractInfo. It must be a valid address (use ClearContractAdmin to remove it)
+func (k Keeper) UpdateContractAdmin(ctx sdk.Context, contractAddress sdk.AccAddress, caller sdk.AccAddress, newAdmin sdk.AccAddress) error {
+	return k.setContractAdmin(ctx, contractAddress, caller, newAdmin)
+}
+
+// ClearContractAdmin sets the admin value on the ContractInfo to nil, to disable further migrations/ updates.
+func (k Keeper) ClearContractAdmin(ctx sdk.Context, contractAddress sdk.AccAddress, caller sdk.AccAddress) error {
+	return k.setContractAdmin(ctx, contractAddress, caller, nil)
+}
+
+func (k Keeper) setContractAdmin(ctx sdk.Context, contractAddress sdk.AccAddress, caller sdk.AccAddress, newAdmin sdk.AccAddress) error {
+	contractInfo := k.GetContractInfo(ctx, contractAddress)
+	if contractInfo == nil {
+		return sdkErrors.Wrap(types.ErrNotFound, "contract")
+	}
+	if contractInfo.Admin.Empty() || !contractInfo.Admin.Equals(caller) {
+		return sdkErrors.Wrap(sdkErrors.ErrUnauthorized, "caller is not the contract admin")
+	}
+	contractInfo.Admin = newAdmin
+	k.setContractInfo(ctx, contractAddress, *contractInfo)
+	return nil
+}
+
+// UpdateInstantiateConfig sets a new instantiate permission on the code. Only the code creator can change it.
+// A permission that does not allow the creator to instantiate the code anymore is rejected unless
+// allowCreatorLockout is set.
+func (k Keeper) UpdateInstantiateConfig(ctx sdk.Context, codeID uint64, caller sdk.AccAddress, newConfig types.AccessConfig, allowCreatorLockout bool) error {
+	codeInfo := k.GetCodeInfo(ctx, codeID)
+	if codeInfo == nil {
+		return sdkErrors.Wrap(types.ErrNotFound, "code")
+	}
+	if !codeInfo.Creator.Equals(caller) {
+		return sdkErrors.Wrap(sdkErrors.ErrUnauthorized, "caller is not the code creator")
+	}
+	if !allowCreatorLockout && !newConfig.Allowed(codeInfo.Creator) {
+		return sdkErrors.Wrap(types.ErrInvalidMsg, "new permission locks out the code creator, it must be confirmed")
+	}
+	k.setInstantiateConfig(ctx, codeID, *codeInfo, newConfig)
+	return nil
+}
+
+// UpdateInstantiateConfigByGovernance sets a new instantiate permission on the code without a creator check
+func (k Keeper) UpdateInstantiateConfigByGovernance(ctx sdk.Context, codeID uint64, newConfig types.AccessConfig) error {
+	codeInfo := k.GetCodeInfo(ctx, codeID)
+	if codeInfo == nil {
+		return sdkErrors.Wrap(types.ErrNotFound, "code")
+	}
+	k.setInstantiateConfig(ctx, codeID, *codeInfo, newConfig)
+	return nil
+}
+
+// DeprecateCode sets or clears the deprecated flag of the code. Only the code creator can change it.
+func (k Keeper) DeprecateCode(ctx sdk.Context, codeID uint64, caller sdk.AccAddress, deprecated bool) error {
+	codeInfo := k.GetCodeInfo(ctx, codeID)
+	if codeInfo == nil {
+		return sdkErrors.Wrap(types.ErrNotFound, "code")
+	}
+	if !codeInfo.Creator.Equals(caller) {
+		return sdkErrors.Wrap(sdkErrors.ErrUnauthorized, "caller is not the code creator")
+	}
+	k.setCodeDeprecated(ctx, codeID, *codeInfo, deprecated)
+	return nil
+}
+
+// DeprecateCodeByGovernance sets or clears the deprecated flag of the code without a creator check
+func (k Keeper) DeprecateCodeByGovernance(ctx sdk.Context, codeID uint64, deprecated bool) error {
+	codeInfo := k.GetCodeInfo(ctx, codeID)
+	if codeInfo == nil {
+		return sdkErrors.Wrap(types.ErrNotFound, "code")
+	}
+	k.setCodeDeprecated(ctx, codeID, *codeInfo, deprecated)
+	return nil
+}
+
+func (k Keeper) setCodeDeprecated(ctx sdk.Context, codeID uint64, codeInfo types.CodeInfo, deprecated bool) {
+	codeInfo.Deprecated = deprecated
+	ctx.KVStore(k.storeKey).Set(types.GetCodeKey(codeID), k.cdc.MustMarshalBinaryBare(codeInfo))
+}
+
+func (k Keeper) setInstantiateConfig(ctx sdk.Context, codeID uint64, codeInfo types.CodeInfo, newConfig types.AccessConfig) {
+	codeInfo.InstantiateConfig = newConfig
+	ctx.KVStore(k.storeKey).Set(types.GetCodeKey(codeID), k.cdc.MustMarshalBinaryBare(codeInfo))
+}
+
+// QuerySmart queries the smart contract itself. The query runs with its own gas meter bounded by the
+// configured query gas limit and fails with ErrGasLimit when it runs out of gas.
+func (k Keeper) QuerySmart(ctx sdk.Context, contractAddr sdk.AccAddress, req []byte) (res []byte, err error) {
 	ctx = ctx.WithGasMeter(sdk.NewGasMeter(k.queryGasLimit))
+	defer func() {
+		if r := recover(); r != nil {
+			oog, ok := r.(sdk.ErrorOutOfGas)
+			if !ok {
+				panic(r)
+			}
+			res, err = nil, sdkErrors.Wrap(types.ErrGasLimit, oog.Descriptor)
+		}
+	}()
 
-	codeInfo, prefixStore, err := k.contractInstance(ctx, contractAddr)
+	_, codeInfo, prefixStore, err := k.contractInstance(ctx, contractAddr)
 	if err != nil {
 		return nil, err
 	}
@@ -182,6 +566,22 @@ func (k Keeper) QuerySmart(ctx sdk.Context, contractAddr sdk.AccAddress, req []b
 	return queryResult, nil
 }
 
+// QueryRawBatch returns the contract's state for all given keys in the same order. Missing keys are
+// returned with a nil value.
+func (k Keeper) QueryRawBatch(ctx sdk.Context, contractAddress sdk.AccAddress, keys [][]byte) []types.Model {
+	prefixStoreKey := types.GetContractStorePrefixKey(contractAddress)
+	prefixStore := prefix.NewStore(ctx.KVStore(k.storeKey), prefixStoreKey)
+
+	result := make([]types.Model, len(keys))
+	for i, key := range keys {
+		result[i] = types.Model{
+			Key:   key,
+			Value: prefixStore.Get(key),
+		}
+	}
+	return result
+}
+
 // QueryRaw returns the contract's state for give key. For a `nil` key a empty slice` result is returned.
 func (k Keeper) QueryRaw(ctx sdk.Context, contractAddress sdk.AccAddress, key []byte) []types.Model {
 	result := make([]types.Model, 0)
@@ -193,32 +593,39 @@ func (k Keeper) QueryRaw(ctx sdk.Context, contractAddress sdk.AccAddress, key []
 
 	if val := prefixStore.Get(key); val != nil {
 		return append(result, types.Model{
-			Key:   string(key),
-			Value: string(val),
+			Key:   key,
+			Value: val,
 		})
 	}
 	return result
 }
 
-func (k Keeper) contractInstance(ctx sdk.Context, contractAddress sdk.AccAddress) (types.CodeInfo, prefix.Store, error) {
+// contractInstance loads the contract and code info together with the gas metered storage of the contract
+func (k Keeper) contractInstance(ctx sdk.Context, contractAddress sdk.AccAddress) (types.ContractInfo, types.CodeInfo, sdk.KVStore, error) {
 	store := ctx.KVStore(k.storeKey)
 
 	contractBz := store.Get(types.GetContractAddressKey(contractAddress))
 	if contractBz == nil {
-		return types.CodeInfo{}, prefix.Store{}, sdkErrors.Wrap(types.ErrNotFound, "contract")
+		return types.ContractInfo{}, types.CodeInfo{}, nil, sdkErrors.Wrap(types.ErrNotFound, "contract")
 	}
 	var contract types.ContractInfo
 	k.cdc.MustUnmarshalBinaryBare(contractBz, &contract)
 
 	contractInfoBz := store.Get(types.GetCodeKey(contract.CodeID))
 	if contractInfoBz == nil {
-		return types.CodeInfo{}, prefix.Store{}, sdkErrors.Wrap(types.ErrNotFound, "contract info")
+		return types.ContractInfo{}, types.CodeInfo{}, nil, sdkErrors.Wrap(types.ErrNotFound, "contract info")
 	}
 	var codeInfo types.CodeInfo
 	k.cdc.MustUnmarshalBinaryBare(contractInfoBz, &codeInfo)
+	return contract, codeInfo, k.contractStore(ctx, contractAddress), nil
+}
+
+// contractStore returns the gas metered storage of the contract on the gas meter of the context
+func (k Keeper) contractStore(ctx sdk.Context, contractAddress sdk.AccAddress) sdk.KVStore {
+	// 0x03 | contractAddress (sdk.AccAddress)
 	prefixStoreKey := types.GetContractStorePrefixKey(contractAddress)
 	prefixStore := prefix.NewStore(ctx.KVStore(k.storeKey), prefixStoreKey)
-	return codeInfo, prefixStore, nil
+	return newContractGasStore(ctx, prefixStore, k.GetParams(ctx))
 }
 
 func (k Keeper) GetContractInfo(ctx sdk.Context, contractAddress sdk.AccAddress) *types.ContractInfo {
@@ -237,9 +644,27 @@ func (k Keeper) setContractInfo(ctx sdk.Context, contractAddress sdk.AccAddress,
 	store.Set(types.GetContractAddressKey(contractAddress), k.cdc.MustMarshalBinaryBare(contract))
 }
 
+// GetContractHistory returns the code history of a contract in the order the entries were added
+func (k Keeper) GetContractHistory(ctx sdk.Context, contractAddress sdk.AccAddress) []types.ContractCodeHistoryEntry {
+	store := ctx.KVStore(k.storeKey)
+	var entries []types.ContractCodeHistoryEntry
+	bz := store.Get(types.GetContractHistoryStoreKey(contractAddress))
+	if bz != nil {
+		k.cdc.MustUnmarshalBinaryBare(bz, &entries)
+	}
+	return entries
+}
+
+func (k Keeper) appendToContractHistory(ctx sdk.Context, contractAddress sdk.AccAddress, newEntries ...types.ContractCodeHistoryEntry) {
+	store := ctx.KVStore(k.storeKey)
+	entries := append(k.GetContractHistory(ctx, contractAddress), newEntries...)
+	store.Set(types.GetContractHistoryStoreKey(contractAddress), k.cdc.MustMarshalBinaryBare(entries))
+}
+
 func (k Keeper) ListContractInfo(ctx sdk.Context, cb func(sdk.AccAddress, types.ContractInfo) bool) {
 	prefixStore := prefix.NewStore(ctx.KVStore(k.storeKey), types.ContractKeyPrefix)
 	iter := prefixStore.Iterator(nil, nil)
+	defer iter.Close()
 	for ; iter.Valid(); iter.Next() {
 		var contract types.ContractInfo
 		k.cdc.MustUnmarshalBinaryBare(iter.Value(), &contract)
@@ -250,17 +675,173 @@ func (k Keeper) ListContractInfo(ctx sdk.Context, cb func(sdk.AccAddress, types.
 	}
 }
 
+// IterateContractsByCode iterates over all contracts instantiated from the given code using the
+// code id secondary index. The callback returns true to stop early.
+func (k Keeper) IterateContractsByCode(ctx sdk.Context, codeID uint64, cb func(sdk.AccAddress, types.ContractInfo) bool) {
+	prefixStore := prefix.NewStore(ctx.KVStore(k.storeKey), types.GetContractByCodeIDSecondaryIndexPrefix(codeID))
+	iter := prefixStore.Iterator(nil, nil)
+	defer iter.Close()
+	for ; iter.Valid(); iter.Next() {
+		contractAddr := sdk.AccAddress(iter.Key())
+		contract := k.GetContractInfo(ctx, contractAddr)
+		if contract == nil {
+			panic(fmt.Sprintf("contract from code index not found: %s", contractAddr))
+		}
+		if cb(contractAddr, *contract) {
+			return
+		}
+	}
+}
+
+// addToContractCodeSecondaryIndex adds the contract to the code id -> contract address index
+func (k Keeper) addToContractCodeSecondaryIndex(ctx sdk.Context, contractAddress sdk.AccAddress, codeID uint64) {
+	store := ctx.KVStore(k.storeKey)
+	store.Set(types.GetContractByCodeIDSecondaryIndexKey(codeID, contractAddress), []byte{})
+	k.setContractCountByCode(ctx, codeID, k.GetContractCountByCode(ctx, codeID)+1)
+}
+
+// addToContractLabelSecondaryIndex adds the contract to the creator and label -> contract address index
+func (k Keeper) addToContractLabelSecondaryIndex(ctx sdk.Context, contractAddress sdk.AccAddress, creator sdk.AccAddress, label string) {
+	store := ctx.KVStore(k.storeKey)
+	store.Set(types.GetContractByLabelSecondaryIndexKey(creator, label, contractAddress), []byte{})
+}
+
+// IterateContractsByLabel iterates over all contracts of the creator with the given label in ascending address
+// order using the creator and label secondary index. The callback returns true to stop early.
+func (k Keeper) IterateContractsByLabel(ctx sdk.Context, creator sdk.AccAddress, label string, cb func(sdk.AccAddress, types.ContractInfo) bool) {
+	prefixStore := prefix.NewStore(ctx.KVStore(k.storeKey), types.GetContractByLabelSecondaryIndexPrefix(creator, label))
+	iter := prefixStore.Iterator(nil, nil)
+	defer iter.Close()
+	for ; iter.Valid(); iter.Next() {
+		contractAddr := sdk.AccAddress(iter.Key())
+		contract := k.GetContractInfo(ctx, contractAddr)
+		if contract == nil {
+			panic(fmt.Sprintf("contract from label index not found: %s", contractAddr))
+		}
+		if cb(contractAddr, *contract) {
+			return
+		}
+	}
+}
+
+// addToContractCreatedSecondaryIndex adds the contract to the created position -> contract address index.
+// Contracts without a recorded position are not indexed.
+func (k Keeper) addToContractCreatedSecondaryIndex(ctx sdk.Context, contractAddress sdk.AccAddress, created *types.AbsoluteTxPosition) {
+	if created == nil {
+		return
+	}
+	store := ctx.KVStore(k.storeKey)
+	store.Set(types.GetContractCreatedSecondaryIndexKey(*created, contractAddress), []byte{})
+}
+
+// IterateContractsByCreated iterates over the contracts in creation order using the created position secondary
+// index. It starts at the index key start, without the index prefix, and passes the index key of every contract
+// to the callback. The callback returns true to stop early.
+func (k Keeper) IterateContractsByCreated(ctx sdk.Context, start []byte, cb func([]byte, sdk.AccAddress, types.ContractInfo) bool) {
+	prefixStore := prefix.NewStore(ctx.KVStore(k.storeKey), types.ContractCreatedSecondaryIndexPrefix)
+	iter := prefixStore.Iterator(start, nil)
+	defer iter.Close()
+	for ; iter.Valid(); iter.Next() {
+		// height and tx index come before the address
+		contractAddr := sdk.AccAddress(iter.Key()[16:])
+		contract := k.GetContractInfo(ctx, contractAddr)
+		if contract == nil {
+			panic(fmt.Sprintf("contract from created index not found: %s", contractAddr))
+		}
+		if cb(iter.Key(), contractAddr, *contract) {
+			return
+		}
+	}
+}
+
+// removeFromContractCodeSecondaryIndex removes the contract from the code id -> contract address index
+func (k Keeper) removeFromContractCodeSecondaryIndex(ctx sdk.Context, contractAddress sdk.AccAddress, codeID uint64) {
+	store := ctx.KVStore(k.storeKey)
+	store.Delete(types.GetContractByCodeIDSecondaryIndexKey(codeID, contractAddress))
+	k.setContractCountByCode(ctx, codeID, k.GetContractCountByCode(ctx, codeID)-1)
+}
+
+// GetContractCountByCode returns the number of contracts in the code id -> contract address index for the code.
+// Contracts that were migrated to another code are counted for the new code only.
+func (k Keeper) GetContractCountByCode(ctx sdk.Context, codeID uint64) uint64 {
+	bz := ctx.KVStore(k.storeKey).Get(types.GetContractCountByCodeIDKey(codeID))
+	if bz == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(bz)
+}
+
+func (k Keeper) setContractCountByCode(ctx sdk.Context, codeID uint64, count uint64) {
+	store := ctx.KVStore(k.storeKey)
+	if count == 0 {
+		store.Delete(types.GetContractCountByCodeIDKey(codeID))
+		return
+	}
+	store.Set(types.GetContractCountByCodeIDKey(codeID), sdk.Uint64ToBigEndian(count))
+}
+
+// IterateContractState iterates over all state entries of the contract in ascending lexicographical key order.
+// The callback returns true to stop early.
+func (k Keeper) IterateContractState(ctx sdk.Context, contractAddress sdk.AccAddress, cb func(key, value []byte) bool) {
+	iter := k.GetContractState(ctx, contractAddress)
+	defer iter.Close()
+	for ; iter.Valid(); iter.Next() {
+		if cb(iter.Key(), iter.Value()) {
+			return
+		}
+	}
+}
+
+// GetContractState returns an iterator over all state entries of the contract in ascending
+// lexicographical key order.
 func (k Keeper) GetContractState(ctx sdk.Context, contractAddress sdk.AccAddress) sdk.Iterator {
 	prefixStoreKey := types.GetContractStorePrefixKey(contractAddress)
 	prefixStore := prefix.NewStore(ctx.KVStore(k.storeKey), prefixStoreKey)
 	return prefixStore.Iterator(nil, nil)
 }
 
+// GetContractStateWithPrefix returns an iterator over the contract state entries whose key starts with keyPrefix.
+// The keys are not stripped of the prefix.
+func (k Keeper) GetContractStateWithPrefix(ctx sdk.Context, contractAddress sdk.AccAddress, keyPrefix []byte) sdk.Iterator {
+	prefixStoreKey := types.GetContractStorePrefixKey(contractAddress)
+	prefixStore := prefix.NewStore(ctx.KVStore(k.storeKey), prefixStoreKey)
+	if len(keyPrefix) == 0 {
+		return prefixStore.Iterator(nil, nil)
+	}
+	return prefixStore.Iterator(keyPrefix, sdk.PrefixEndBytes(keyPrefix))
+}
+
+// GetContractStateAfter returns an iterator over the contract state entries with a key greater than startAfterKey,
+// in ascending key order. An empty startAfterKey starts with the first entry.
+func (k Keeper) GetContractStateAfter(ctx sdk.Context, contractAddress sdk.AccAddress, startAfterKey []byte) sdk.Iterator {
+	prefixStoreKey := types.GetContractStorePrefixKey(contractAddress)
+	prefixStore := prefix.NewStore(ctx.KVStore(k.storeKey), prefixStoreKey)
+	if len(startAfterKey) == 0 {
+		return prefixStore.Iterator(nil, nil)
+	}
+	// the smallest key greater than startAfterKey has a zero byte appended
+	start := append(append([]byte{}, startAfterKey...), 0)
+	return prefixStore.Iterator(start, nil)
+}
+
+// ImportContractState writes the models into the store of an existing contract in one operation. Entries with
+// existing keys are overwritten. This is a privileged operation that is meant for governance.
+func (k Keeper) ImportContractState(ctx sdk.Context, contractAddress sdk.AccAddress, models []types.Model) error {
+	if k.GetContractInfo(ctx, contractAddress) == nil {
+		return sdkErrors.Wrap(types.ErrNotFound, "contract")
+	}
+	if err := types.ValidateModels(models); err != nil {
+		return err
+	}
+	k.setContractState(ctx, contractAddress, models)
+	return nil
+}
+
 func (k Keeper) setContractState(ctx sdk.Context, contractAddress sdk.AccAddress, models []types.Model) {
 	prefixStoreKey := types.GetContractStorePrefixKey(contractAddress)
 	prefixStore := prefix.NewStore(ctx.KVStore(k.storeKey), prefixStoreKey)
 	for _, model := range models {
-		prefixStore.Set([]byte(model.Key), []byte(model.Value))
+		prefixStore.Set(model.Key, model.Value)
 	}
 }
 
@@ -275,6 +856,79 @@ func (k Keeper) GetCodeInfo(ctx sdk.Context, codeID uint64) *types.CodeInfo {
 	return &codeInfo
 }
 
+// IterateCodeInfos iterates over all stored code infos in ascending code ID order.
+// The callback returns true to stop early.
+func (k Keeper) IterateCodeInfos(ctx sdk.Context, cb func(uint64, types.CodeInfo) bool) {
+	prefixStore := prefix.NewStore(ctx.KVStore(k.storeKey), types.CodeKeyPrefix)
+	iter := prefixStore.Iterator(nil, nil)
+	defer iter.Close()
+	for ; iter.Valid(); iter.Next() {
+		var c types.CodeInfo
+		k.cdc.MustUnmarshalBinaryBare(iter.Value(), &c)
+		// cb returns true to stop early
+		if cb(binary.BigEndian.Uint64(iter.Key()), c) {
+			return
+		}
+	}
+}
+
+// addToCodeCreatorSecondaryIndex adds the code to the creator -> code id index
+func (k Keeper) addToCodeCreatorSecondaryIndex(ctx sdk.Context, creator sdk.AccAddress, codeID uint64) {
+	store := ctx.KVStore(k.storeKey)
+	store.Set(types.GetCodeByCreatorSecondaryIndexKey(creator, codeID), []byte{})
+}
+
+// IterateCodesByCreator iterates over all codes uploaded by the given creator in ascending code ID order
+// using the creator secondary index. The callback returns true to stop early.
+func (k Keeper) IterateCodesByCreator(ctx sdk.Context, creator sdk.AccAddress, cb func(uint64, types.CodeInfo) bool) {
+	prefixStore := prefix.NewStore(ctx.KVStore(k.storeKey), types.GetCodeByCreatorSecondaryIndexPrefix(creator))
+	iter := prefixStore.Iterator(nil, nil)
+	defer iter.Close()
+	for ; iter.Valid(); iter.Next() {
+		codeID := binary.BigEndian.Uint64(iter.Key())
+		info := k.GetCodeInfo(ctx, codeID)
+		if info == nil {
+			continue
+		}
+		if cb(codeID, *info) {
+			return
+		}
+	}
+}
+
+// addToCodeHashSecondaryIndex adds the code to the code hash -> code id index
+func (k Keeper) addToCodeHashSecondaryIndex(ctx sdk.Context, codeHash []byte, codeID uint64) {
+	store := ctx.KVStore(k.storeKey)
+	store.Set(types.GetCodeByHashSecondaryIndexKey(codeHash, codeID), []byte{})
+}
+
+// IterateCodesByHash iterates over all codes with the given code hash in ascending code ID order
+// using the code hash secondary index. The callback returns true to stop early.
+func (k Keeper) IterateCodesByHash(ctx sdk.Context, codeHash []byte, cb func(uint64, types.CodeInfo) bool) {
+	prefixStore := prefix.NewStore(ctx.KVStore(k.storeKey), types.GetCodeByHashSecondaryIndexPrefix(codeHash))
+	iter := prefixStore.Iterator(nil, nil)
+	defer iter.Close()
+	for ; iter.Valid(); iter.Next() {
+		codeID := binary.BigEndian.Uint64(iter.Key())
+		info := k.GetCodeInfo(ctx, codeID)
+		if info == nil {
+			continue
+		}
+		if cb(codeID, *info) {
+			return
+		}
+	}
+}
+
+// GetCodeHash returns the sha256 hash of the wasm bytecode without loading the code. It is nil for unknown codes.
+func (k Keeper) GetCodeHash(ctx sdk.Context, codeID uint64) []byte {
+	codeInfo := k.GetCodeInfo(ctx, codeID)
+	if codeInfo == nil {
+		return nil
+	}
+	return codeInfo.CodeHash
+}
+
 func (k Keeper) GetByteCode(ctx sdk.Context, codeID uint64) ([]byte, error) {
 	store := ctx.KVStore(k.storeKey)
 	var codeInfo types.CodeInfo
@@ -283,10 +937,115 @@ func (k Keeper) GetByteCode(ctx sdk.Context, codeID uint64) ([]byte, error) {
 		return nil, nil
 	}
 	k.cdc.MustUnmarshalBinaryBare(codeInfoBz, &codeInfo)
-	return k.wasmer.GetCode(codeInfo.CodeHash)
+	code, err := k.wasmer.GetCode(codeInfo.CodeHash)
+	if err != nil {
+		// the code info is in the state but the vm can not read the bytecode from its data dir
+		return nil, sdkErrors.Wrap(types.ErrBytecodePruned, err.Error())
+	}
+	return code, nil
+}
+
+// PinCode adds the code to the pinned code index. The index is the state that a chain agrees on; the VM in
+// use (go-cosmwasm v0.6) only offers an LRU cache for compiled modules and no API to keep a module in memory,
+// so pinning does not change how modules are cached or the gas charged for now.
+func (k Keeper) PinCode(ctx sdk.Context, codeID uint64) error {
+	if k.GetCodeInfo(ctx, codeID) == nil {
+		return sdkErrors.Wrap(types.ErrNotFound, "code")
+	}
+	store := ctx.KVStore(k.storeKey)
+	store.Set(types.GetPinnedCodeIndexPrefix(codeID), []byte{})
+	return nil
+}
+
+// UnpinCode removes the code from the pinned code index. Unpinning a code that is not pinned is a no-op.
+func (k Keeper) UnpinCode(ctx sdk.Context, codeID uint64) error {
+	if k.GetCodeInfo(ctx, codeID) == nil {
+		return sdkErrors.Wrap(types.ErrNotFound, "code")
+	}
+	store := ctx.KVStore(k.storeKey)
+	store.Delete(types.GetPinnedCodeIndexPrefix(codeID))
+	return nil
+}
+
+// IsPinnedCode returns true when the code is in the pinned code index
+func (k Keeper) IsPinnedCode(ctx sdk.Context, codeID uint64) bool {
+	store := ctx.KVStore(k.storeKey)
+	return store.Has(types.GetPinnedCodeIndexPrefix(codeID))
+}
+
+// IteratePinnedCodes iterates over all pinned code IDs in ascending order.
+// The callback returns true to stop early.
+func (k Keeper) IteratePinnedCodes(ctx sdk.Context, cb func(codeID uint64) bool) {
+	prefixStore := prefix.NewStore(ctx.KVStore(k.storeKey), types.PinnedCodeIndexPrefix)
+	iter := prefixStore.Iterator(nil, nil)
+	defer iter.Close()
+	for ; iter.Valid(); iter.Next() {
+		if cb(binary.BigEndian.Uint64(iter.Key())) {
+			return
+		}
+	}
+}
+
+// dispatchDepthKey is the context key of the current message dispatch depth
+type dispatchDepthKey struct{}
+
+// simulationKey is the context key that marks an execution as a simulation whose state is discarded
+type simulationKey struct{}
+
+// withSimulation returns a context that marks all executions in it as simulated
+func withSimulation(ctx sdk.Context) sdk.Context {
+	return ctx.WithValue(simulationKey{}, true)
+}
+
+// isSimulation is true within a simulated execution. The hooks are not called for simulations as the
+// state changes that they react on are never committed.
+func isSimulation(ctx sdk.Context) bool {
+	simulated, _ := ctx.Value(simulationKey{}).(bool)
+	return simulated
+}
+
+// callStackKey is the context key of the contracts that are executing while messages are dispatched
+type callStackKey struct{}
+
+// pushCallStack returns a context with the contract added on top of the call stack
+func pushCallStack(ctx sdk.Context, contractAddress sdk.AccAddress) sdk.Context {
+	stack, _ := ctx.Value(callStackKey{}).([]sdk.AccAddress)
+	// copy so that sibling calls do not share the backing array
+	newStack := make([]sdk.AccAddress, len(stack), len(stack)+1)
+	copy(newStack, stack)
+	return ctx.WithValue(callStackKey{}, append(newStack, contractAddress))
+}
+
+// enterContract pushes the contract on the call stack. A contract that is on the stack already is called
+// back re-entrant, which fails when RejectContractReentrancy is set and is flagged with an event otherwise.
+func (k Keeper) enterContract(ctx sdk.Context, contractAddress sdk.AccAddress) (sdk.Context, error) {
+	stack, _ := ctx.Value(callStackKey{}).([]sdk.AccAddress)
+	for _, addr := range stack {
+		if !addr.Equals(contractAddress) {
+			continue
+		}
+		if k.GetParams(ctx).RejectContractReentrancy {
+			return ctx, sdkErrors.Wrap(types.ErrReentrancy, contractAddress.String())
+		}
+		ctx.EventManager().EmitEvent(sdk.NewEvent(
+			types.EventTypeContractReentrancy,
+			sdk.NewAttribute(types.AttributeKeyContract, contractAddress.String()),
+		))
+		break
+	}
+	return pushCallStack(ctx, contractAddress), nil
 }
 
 func (k Keeper) dispatchMessages(ctx sdk.Context, contract exported.Account, msgs []wasmTypes.CosmosMsg) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	depth, _ := ctx.Value(dispatchDepthKey{}).(int)
+	if depth >= MaxDispatchDepth {
+		return sdkErrors.Wrap(types.ErrExecuteFailed, "max dispatch depth exceeded")
+	}
+	ctx = ctx.WithValue(dispatchDepthKey{}, depth+1)
+
 	for _, msg := range msgs {
 		if err := k.dispatchMessage(ctx, contract, msg); err != nil {
 			return err
@@ -305,6 +1064,14 @@ func (k Keeper) dispatchMessage(ctx sdk.Context, contract exported.Account, msg
 		if stderr != nil {
 			return sdk.ErrInvalidAddress(msg.Contract.ContractAddr)
 		}
+		sendMsg, sdkerr := convertCosmosSendMsg(contractAddr.String(), targetAddr.String(), msg.Contract.Send)
+		if sdkerr != nil {
+			return sdkerr
+		}
+		// the tokens are sent to a contract, so the same limits apply as for the funds of an execute msg
+		if err := checkFunds(k.GetParams(ctx), sendMsg.Amount); err != nil {
+			return sdkErrors.Wrap(types.ErrExecuteFailed, err.Error())
+		}
 		err := k.sendTokens(ctx, contractAddr, contractAddr.String(), targetAddr.String(), msg.Contract.Send)
 		if err != nil {
 			return err
@@ -316,13 +1083,78 @@ func (k Keeper) dispatchMessage(ctx sdk.Context, contract exported.Account, msg
 		if err != nil {
 			return err
 		}
+		if handled, err := k.handleWasmMessage(ctx, contractAddr, msg); handled {
+			return err
+		}
+		if handled, err := k.handleStakingMessage(ctx, contractAddr, msg); handled {
+			return err
+		}
 		return k.handleSdkMessage(ctx, contractAddr, msg)
 	}
 	// what is it?
 	panic(fmt.Sprintf("Unknown CosmosMsg: %#v", msg))
 }
 
-func (k Keeper) sendTokens(ctx sdk.Context, signer sdk.AccAddress, origin string, target string, tokens []wasmTypes.Coin) error {
+// handleWasmMessage routes the wasm execute, instantiate and migrate messages of a contract back through the
+// keeper with the contract as sender. It returns false for all other messages.
+func (k Keeper) handleWasmMessage(ctx sdk.Context, contractAddr sdk.AccAddress, msg sdk.Msg) (bool, error) {
+	switch msg.(type) {
+	case *types.MsgExecuteContract, *types.MsgInstantiateContract, *types.MsgMigrateContract:
+	default:
+		return false, nil
+	}
+	if err := msg.ValidateBasic(); err != nil {
+		return true, err
+	}
+	for _, acct := range msg.GetSigners() {
+		if !acct.Equals(contractAddr) {
+			return true, sdkErrors.Wrap(sdkErrors.ErrUnauthorized, "contract doesn't have permission")
+		}
+	}
+
+	switch msg := msg.(type) {
+	case *types.MsgExecuteContract:
+		_, err := k.Execute(ctx, msg.Contract, contractAddr, msg.Msg, msg.SentFunds)
+		return true, err
+	case *types.MsgInstantiateContract:
+		_, err := k.Instantiate(ctx, msg.Code, contractAddr, msg.Admin, msg.InitMsg, msg.Label, msg.AdminOnlyExecute, msg.InitFunds)
+		return true, err
+	case *types.MsgMigrateContract:
+		return true, k.Migrate(ctx, msg.Contract, contractAddr, msg.Code, msg.MigrateMsg)
+	}
+	return false, nil
+}
+
+// handleStakingMessage routes the delegate, undelegate and redelegate messages of a contract to the staking
+// module when the ContractStakingMsgs param allows their type. The contract must be the delegator.
+// It returns false for all other messages.
+func (k Keeper) handleStakingMessage(ctx sdk.Context, contractAddr sdk.AccAddress, msg sdk.Msg) (bool, error) {
+	var delegator sdk.AccAddress
+	switch msg := msg.(type) {
+	case staking.MsgDelegate:
+		delegator = msg.DelegatorAddress
+	case staking.MsgUndelegate:
+		delegator = msg.DelegatorAddress
+	case staking.MsgBeginRedelegate:
+		delegator = msg.DelegatorAddress
+	default:
+		return false, nil
+	}
+	if !k.GetParams(ctx).ContractStakingMsgAllowed(msg.Type()) {
+		return true, sdkErrors.Wrapf(sdkErrors.ErrUnauthorized, "staking msg type not enabled for contracts: %s", msg.Type())
+	}
+	if !delegator.Equals(contractAddr) {
+		return true, sdkErrors.Wrap(sdkErrors.ErrUnauthorized, "contract must be the delegator")
+	}
+	if err := msg.ValidateBasic(); err != nil {
+		return true, err
+	}
+	return true, k.handleSdkMessage(ctx, contractAddr, msg)
+}
+
+// sendTokens moves the tokens from the contract account with the bank keeper. The origin must be the contract
+// and its balance must cover the tokens.
+func (k Keeper) sendTokens(ctx sdk.Context, contractAddr sdk.AccAddress, origin string, target string, tokens []wasmTypes.Coin) error {
 	if len(tokens) == 0 {
 		return nil
 	}
@@ -330,7 +1162,19 @@ func (k Keeper) sendTokens(ctx sdk.Context, signer sdk.AccAddress, origin string
 	if err != nil {
 		return err
 	}
-	return k.handleSdkMessage(ctx, signer, msg)
+	if !msg.FromAddress.Equals(contractAddr) {
+		return sdkErrors.Wrap(sdkErrors.ErrUnauthorized, "contract doesn't have permission")
+	}
+	if err := msg.ValidateBasic(); err != nil {
+		return err
+	}
+	if balance := k.bankKeeper.GetCoins(ctx, contractAddr); !balance.IsAllGTE(msg.Amount) {
+		return sdkErrors.Wrapf(sdkErrors.ErrInsufficientFunds, "contract balance %s is smaller than %s", balance, msg.Amount)
+	}
+	if sdkerr := k.bankKeeper.SendCoins(ctx, msg.FromAddress, msg.ToAddress, msg.Amount); sdkerr != nil {
+		return sdkerr
+	}
+	return nil
 }
 
 func convertCosmosSendMsg(from string, to string, coins []wasmTypes.Coin) (bank.MsgSend, sdk.Error) {
@@ -358,7 +1202,8 @@ func convertCosmosSendMsg(from string, to string, coins []wasmTypes.Coin) (bank.
 	sendMsg := bank.MsgSend{
 		FromAddress: fromAddr,
 		ToAddress:   toAddr,
-		Amount:      toSend,
+		// contracts may return the coins in any order
+		Amount: toSend.Sort(),
 	}
 	return sendMsg, nil
 }
@@ -397,6 +1242,39 @@ func consumeGas(ctx sdk.Context, gas uint64) {
 	ctx.GasMeter().ConsumeGas(consumed, "wasm contract")
 }
 
+// withContractGasLimit runs the wasm call of a contract within the MaxContractGas. When the param is set and
+// lower than the gas left in the transaction, the call gets a context with a gas meter capped at MaxContractGas
+// and the gas it consumed is charged on the transaction gas meter afterwards. Running out of the capped gas
+// returns ErrMaxContractGas, while running out of the transaction gas keeps the sdk out of gas behaviour.
+// Out of gas is read from the capped gas meter, not from the error of the call.
+func (k Keeper) withContractGasLimit(ctx sdk.Context, call func(ctx sdk.Context) error) (err error) {
+	maxGas := k.GetParams(ctx).MaxContractGas
+	txMeter := ctx.GasMeter()
+	if maxGas == 0 || (txMeter.Limit() != 0 && txMeter.Limit()-txMeter.GasConsumed() <= maxGas) {
+		return call(ctx)
+	}
+
+	contractMeter := sdk.NewGasMeter(maxGas)
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(sdk.ErrorOutOfGas); !ok {
+				panic(r)
+			}
+			err = sdkErrors.Wrapf(types.ErrMaxContractGas, "limit %d", maxGas)
+		}
+		if err != nil && contractMeter.IsOutOfGas() {
+			err = sdkErrors.Wrapf(types.ErrMaxContractGas, "limit %d", maxGas)
+		}
+		if types.ErrMaxContractGas.Is(err) {
+			// the whole contract gas was used up
+			txMeter.ConsumeGas(maxGas, "wasm contract")
+			return
+		}
+		txMeter.ConsumeGas(contractMeter.GasConsumed(), "wasm contract")
+	}()
+	return call(ctx.WithGasMeter(contractMeter))
+}
+
 // generates a contract address from codeID + instanceID
 func (k Keeper) generateContractAddress(ctx sdk.Context, codeID uint64) sdk.AccAddress {
 	instanceID := k.autoIncrementID(ctx, types.KeyLastInstanceID)
@@ -416,6 +1294,17 @@ func (k Keeper) GetNextCodeID(ctx sdk.Context) uint64 {
 	return id
 }
 
+// GetNextInstanceID returns the instance ID that will be assigned to the next contract
+func (k Keeper) GetNextInstanceID(ctx sdk.Context) uint64 {
+	store := ctx.KVStore(k.storeKey)
+	bz := store.Get(types.KeyLastInstanceID)
+	id := uint64(1)
+	if bz != nil {
+		id = binary.BigEndian.Uint64(bz)
+	}
+	return id
+}
+
 func (k Keeper) autoIncrementID(ctx sdk.Context, lastIDKey []byte) uint64 {
 	store := ctx.KVStore(k.storeKey)
 	bz := store.Get(lastIDKey)
@@ -428,9 +1317,113 @@ func (k Keeper) autoIncrementID(ctx sdk.Context, lastIDKey []byte) uint64 {
 	return id
 }
 
+// importAutoIncrementID sets the next ID that the counter at lastIDKey returns
+func (k Keeper) importAutoIncrementID(ctx sdk.Context, lastIDKey []byte, val uint64) {
+	store := ctx.KVStore(k.storeKey)
+	store.Set(lastIDKey, sdk.Uint64ToBigEndian(val))
+}
+
+// importCode stores the code info under the given code ID. The wasm code is compiled again and must match
+// the code hash of the info.
+func (k Keeper) importCode(ctx sdk.Context, codeID uint64, codeInfo types.CodeInfo, wasmCode []byte) error {
+	wasmCode, err := uncompress(wasmCode, k.GetParams(ctx).MaxWasmCodeSize)
+	if err != nil {
+		return uncompressError(err)
+	}
+	newCodeHash, err := k.wasmer.Create(wasmCode)
+	if err != nil {
+		return sdkErrors.Wrap(types.ErrCreateFailed, err.Error())
+	}
+	if !bytes.Equal(codeInfo.CodeHash, newCodeHash) {
+		return sdkErrors.Wrap(types.ErrInvalidGenesis, "code hashes not same")
+	}
+	// codes exported before the api version was recorded
+	if codeInfo.APIVersion == "" {
+		codeInfo.APIVersion = contractAPIVersion(wasmCode)
+	}
+
+	store := ctx.KVStore(k.storeKey)
+	key := types.GetCodeKey(codeID)
+	if store.Has(key) {
+		return sdkErrors.Wrapf(types.ErrInvalidGenesis, "duplicate code: %d", codeID)
+	}
+	store.Set(key, k.cdc.MustMarshalBinaryBare(codeInfo))
+	k.addToCodeCreatorSecondaryIndex(ctx, codeInfo.Creator, codeID)
+	k.addToCodeHashSecondaryIndex(ctx, codeInfo.CodeHash, codeID)
+	k.addToCodeStorageStats(ctx, wasmCode)
+	return nil
+}
+
+// GetCodeStorageStats returns the running totals of the stored codes
+func (k Keeper) GetCodeStorageStats(ctx sdk.Context) types.CodeStorageStats {
+	var stats types.CodeStorageStats
+	bz := ctx.KVStore(k.storeKey).Get(types.KeyCodeStorageStats)
+	if bz != nil {
+		k.cdc.MustUnmarshalBinaryBare(bz, &stats)
+	}
+	return stats
+}
+
+// addToCodeStorageStats counts a new code with its uncompressed wasm bytecode
+func (k Keeper) addToCodeStorageStats(ctx sdk.Context, wasmCode []byte) {
+	stats := k.GetCodeStorageStats(ctx)
+	stats.CodeCount++
+	stats.TotalBytes += uint64(len(wasmCode))
+	ctx.KVStore(k.storeKey).Set(types.KeyCodeStorageStats, k.cdc.MustMarshalBinaryBare(stats))
+}
+
+// importContract stores the contract info, code index entry, state and code history of a contract from genesis.
+// The contract info and history are written as exported, the contract is not instantiated again. Contracts
+// without history get a single genesis entry.
+func (k Keeper) importContract(ctx sdk.Context, contractAddr sdk.AccAddress, c types.ContractInfo, state []types.Model, history []types.ContractCodeHistoryEntry) error {
+	if k.GetCodeInfo(ctx, c.CodeID) == nil {
+		return sdkErrors.Wrapf(types.ErrNotFound, "code id: %d", c.CodeID)
+	}
+	if k.GetContractInfo(ctx, contractAddr) != nil {
+		return sdkErrors.Wrapf(types.ErrInvalidGenesis, "duplicate contract: %s", contractAddr)
+	}
+
+	k.setContractInfo(ctx, contractAddr, c)
+	k.addToContractCodeSecondaryIndex(ctx, contractAddr, c.CodeID)
+	k.addToContractLabelSecondaryIndex(ctx, contractAddr, c.Creator, c.Label)
+	k.addToContractCreatedSecondaryIndex(ctx, contractAddr, c.Created)
+	k.setContractState(ctx, contractAddr, state)
+	if len(history) == 0 {
+		history = []types.ContractCodeHistoryEntry{{
+			Operation: types.GenesisContractCodeHistoryType,
+			CodeID:    c.CodeID,
+			Updated:   ctx.BlockHeight(),
+		}}
+	}
+	k.appendToContractHistory(ctx, contractAddr, history...)
+	return nil
+}
+
+// PredictableContractAddress returns the contract address used by Instantiate2. It is the first 20 bytes of
+//
+//	sha256("instantiate2" | big endian uint64 code id | uint8 length of creator | creator address bytes | salt)
+//
+// so it can be computed off-chain before the contract exists. Messages of a transaction run in order on the same
+// state, so later messages of the transaction that instantiates the contract can address it already.
+func PredictableContractAddress(codeID uint64, creator sdk.AccAddress, salt []byte) sdk.AccAddress {
+	bz := append([]byte("instantiate2"), sdk.Uint64ToBigEndian(codeID)...)
+	bz = append(bz, byte(len(creator)))
+	bz = append(bz, creator...)
+	bz = append(bz, salt...)
+	return sdk.AccAddress(crypto.AddressHash(bz))
+}
+
 func addrFromUint64(id uint64) sdk.AccAddress {
 	addr := make([]byte, 20)
 	addr[0] = 'C'
 	binary.PutUvarint(addr[1:], id)
 	return sdk.AccAddress(crypto.AddressHash(addr))
 }
+
+// uncompressError maps the errors of uncompress to the registered wasm errors
+func uncompressError(err error) error {
+	if err == errLimit {
+		return sdkErrors.Wrap(types.ErrCodeTooLarge, err.Error())
+	}
+	return sdkErrors.Wrap(types.ErrInvalidWasmCode, err.Error())
+}
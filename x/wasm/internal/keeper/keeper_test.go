@@ -3,6 +3,7 @@ package keeper
 import (
 	"encoding/binary"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"testing"
@@ -13,6 +14,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkErrors "github.com/cosmos/cosmos-sdk/types/errors"
 	"github.com/cosmos/cosmos-sdk/x/auth"
 	"github.com/tendermint/tendermint/crypto"
 	"github.com/tendermint/tendermint/crypto/ed25519"
@@ -38,13 +40,15 @@ func TestCreate(t *testing.T) {
 	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
 	require.NoError(t, err)
 
-	contractID, err := keeper.Create(ctx, creator, wasmCode, "https://github.com/cosmwasm/wasmd/blob/master/x/wasm/testdata/escrow.wasm", "cosmwasm-opt:0.5.2")
+	contractID, err := keeper.Create(ctx, creator, wasmCode, "https://github.com/cosmwasm/wasmd/blob/master/x/wasm/testdata/escrow.wasm", "cosmwasm-opt:0.5.2", nil)
 	require.NoError(t, err)
 	require.Equal(t, uint64(1), contractID)
 	// and verify content
 	storedCode, err := keeper.GetByteCode(ctx, contractID)
 	require.NoError(t, err)
 	require.Equal(t, wasmCode, storedCode)
+	// and defaults to open instantiation
+	assert.Equal(t, types.AllowEverybody, keeper.GetCodeInfo(ctx, contractID).InstantiateConfig)
 }
 
 func TestCreateWithGzippedPayload(t *testing.T) {
@@ -59,7 +63,7 @@ func TestCreateWithGzippedPayload(t *testing.T) {
 	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm.gzip")
 	require.NoError(t, err)
 
-	contractID, err := keeper.Create(ctx, creator, wasmCode, "https://github.com/cosmwasm/wasmd/blob/master/x/wasm/testdata/escrow.wasm", "")
+	contractID, err := keeper.Create(ctx, creator, wasmCode, "https://github.com/cosmwasm/wasmd/blob/master/x/wasm/testdata/escrow.wasm", "", nil)
 	require.NoError(t, err)
 	require.Equal(t, uint64(1), contractID)
 	// and verify content
@@ -70,6 +74,124 @@ func TestCreateWithGzippedPayload(t *testing.T) {
 	require.Equal(t, rawCode, storedCode)
 }
 
+func TestCreateOrReuse(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+	otherCreator := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	gzippedCode, err := ioutil.ReadFile("./testdata/contract.wasm.gzip")
+	require.NoError(t, err)
+	maskCode, err := ioutil.ReadFile("./testdata/mask.wasm")
+	require.NoError(t, err)
+
+	codeID, created, err := keeper.CreateOrReuse(ctx, creator, wasmCode, "https://example.com/source", "", nil)
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	// the hash is taken from the uncompressed code
+	reusedID, created, err := keeper.CreateOrReuse(ctx, otherCreator, gzippedCode, "", "", &types.AllowNobody)
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, codeID, reusedID)
+	// metadata of the existing code is kept
+	info := keeper.GetCodeInfo(ctx, codeID)
+	require.NotNil(t, info)
+	assert.Equal(t, creator, info.Creator)
+	assert.Equal(t, "https://example.com/source", info.Source)
+	assert.Equal(t, types.AllowEverybody, info.InstantiateConfig)
+
+	otherID, created, err := keeper.CreateOrReuse(ctx, creator, maskCode, "", "", nil)
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.NotEqual(t, codeID, otherID)
+}
+
+func TestCreateWithGzippedPayloadErrors(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm.gzip")
+	require.NoError(t, err)
+
+	params := keeper.GetParams(ctx)
+	params.MaxWasmCodeSize = uint64(len(wasmCode))
+	keeper.SetParams(ctx, params)
+
+	// the uncompressed code is larger than the gzip payload
+	_, err = keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.True(t, types.ErrCodeTooLarge.Is(err), "got %+v", err)
+
+	brokenGzip := append([]byte{}, wasmCode[:10]...)
+	_, err = keeper.Create(ctx, creator, brokenGzip, "", "", nil)
+	require.True(t, types.ErrInvalidWasmCode.Is(err), "got %+v", err)
+}
+
+func TestStoreCodeAndPin(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+
+	codeID, err := keeper.StoreCodeAndPin(ctx, types.MsgStoreCode{Sender: creator, WASMByteCode: wasmCode, InstantiatePermission: &types.AllowNobody})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), codeID)
+	assert.True(t, keeper.IsPinnedCode(ctx, codeID))
+	assert.Equal(t, types.AllowNobody, keeper.GetCodeInfo(ctx, codeID).InstantiateConfig)
+	storedCode, err := keeper.GetByteCode(ctx, codeID)
+	require.NoError(t, err)
+	assert.Equal(t, wasmCode, storedCode)
+
+	// nothing is pinned when the code can not be stored
+	_, err = keeper.StoreCodeAndPin(ctx, types.MsgStoreCode{Sender: creator, WASMByteCode: []byte("not wasm")})
+	require.Error(t, err)
+	assert.False(t, keeper.IsPinnedCode(ctx, 2))
+}
+
+func TestCreateGzipNotAllowed(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	gzippedCode, err := ioutil.ReadFile("./testdata/contract.wasm.gzip")
+	require.NoError(t, err)
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+
+	params := keeper.GetParams(ctx)
+	params.AllowGzipUpload = false
+	keeper.SetParams(ctx, params)
+
+	_, err = keeper.Create(ctx, creator, gzippedCode, "", "", nil)
+	require.True(t, types.ErrInvalidWasmCode.Is(err), "got %+v", err)
+
+	// raw wasm is still accepted
+	codeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), codeID)
+}
+
 func TestInstantiate(t *testing.T) {
 	tempDir, err := ioutil.TempDir("", "wasm")
 	require.NoError(t, err)
@@ -82,7 +204,7 @@ func TestInstantiate(t *testing.T) {
 	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
 	require.NoError(t, err)
 
-	contractID, err := keeper.Create(ctx, creator, wasmCode, "https://github.com/cosmwasm/wasmd/blob/master/x/wasm/testdata/escrow.wasm", "")
+	contractID, err := keeper.Create(ctx, creator, wasmCode, "https://github.com/cosmwasm/wasmd/blob/master/x/wasm/testdata/escrow.wasm", "", nil)
 	require.NoError(t, err)
 
 	_, _, bob := keyPubAddr()
@@ -98,12 +220,230 @@ func TestInstantiate(t *testing.T) {
 	gasBefore := ctx.GasMeter().GasConsumed()
 
 	// create with no balance is also legal
-	addr, err := keeper.Instantiate(ctx, contractID, creator, initMsgBz, nil)
+	addr, err := keeper.Instantiate(ctx, contractID, creator, nil, initMsgBz, "demo contract", false, nil)
 	require.NoError(t, err)
 	require.Equal(t, "cosmos18vd8fpwxzck93qlwghaj6arh4p7c5n89uzcee5", addr.String())
 
 	gasAfter := ctx.GasMeter().GasConsumed()
-	require.Equal(t, uint64(36923), gasAfter-gasBefore)
+	require.Equal(t, uint64(46522), gasAfter-gasBefore)
+
+	// the label is persisted with the contract info
+	info := keeper.GetContractInfo(ctx, addr)
+	require.NotNil(t, info)
+	assert.Equal(t, "demo contract", info.Label)
+}
+
+func TestInstantiate2(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+	otherCreator := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	codeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	_, _, fred := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: fred, Beneficiary: bob})
+	require.NoError(t, err)
+
+	// specs run in order as they depend on each other
+	specs := []struct {
+		name    string
+		creator sdk.AccAddress
+		salt    []byte
+		expErr  *sdkErrors.Error
+	}{
+		{
+			name:    "first salt",
+			creator: creator,
+			salt:    []byte("salt"),
+		},
+		{
+			name:    "same salt again",
+			creator: creator,
+			salt:    []byte("salt"),
+			expErr:  types.ErrAccountExists,
+		},
+		{
+			name:    "other salt",
+			creator: creator,
+			salt:    []byte("other salt"),
+		},
+		{
+			name:    "same salt by other creator",
+			creator: otherCreator,
+			salt:    []byte("salt"),
+		},
+	}
+	for _, spec := range specs {
+		t.Run(spec.name, func(t *testing.T) {
+			expAddr := PredictableContractAddress(codeID, spec.creator, spec.salt)
+			addr, err := keeper.Instantiate2(ctx, codeID, spec.creator, nil, initMsgBz, "demo contract", false, nil, spec.salt)
+			require.True(t, spec.expErr.Is(err), "expected %v but got %+v", spec.expErr, err)
+			if spec.expErr != nil {
+				return
+			}
+			assert.Equal(t, expAddr, addr)
+			require.NotNil(t, keeper.GetContractInfo(ctx, addr))
+		})
+	}
+	// all successful instantiations are counted
+	assert.Equal(t, uint64(4), keeper.GetNextInstanceID(ctx))
+}
+
+func TestInstantiateWithPermissions(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	myAddr := createFakeFundedAccount(ctx, accKeeper, deposit)
+	otherAddr := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	_, _, fred := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: fred, Beneficiary: bob})
+	require.NoError(t, err)
+
+	onlyMe := types.OnlyAddress(myAddr)
+	specs := map[string]struct {
+		srcPermission *types.AccessConfig
+		srcActor      sdk.AccAddress
+		expErr        *sdkErrors.Error
+	}{
+		"default": {
+			srcActor: otherAddr,
+		},
+		"everybody": {
+			srcPermission: &types.AllowEverybody,
+			srcActor:      otherAddr,
+		},
+		"nobody": {
+			srcPermission: &types.AllowNobody,
+			srcActor:      myAddr,
+			expErr:        sdkErrors.ErrUnauthorized,
+		},
+		"onlyAddress with matching address": {
+			srcPermission: &onlyMe,
+			srcActor:      myAddr,
+		},
+		"onlyAddress with non matching address": {
+			srcPermission: &onlyMe,
+			srcActor:      otherAddr,
+			expErr:        sdkErrors.ErrUnauthorized,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			codeID, err := keeper.Create(ctx, myAddr, wasmCode, "", "", spec.srcPermission)
+			require.NoError(t, err)
+
+			_, err = keeper.Instantiate(ctx, codeID, spec.srcActor, nil, initMsgBz, "demo contract", false, nil)
+			assert.True(t, spec.expErr.Is(err), "got %+v", err)
+		})
+	}
+}
+
+type mockWasmHooks struct {
+	calls []string
+}
+
+func (m *mockWasmHooks) AfterStoreCode(ctx sdk.Context, codeID uint64, creator sdk.AccAddress) {
+	m.calls = append(m.calls, fmt.Sprintf("store %d", codeID))
+}
+
+func (m *mockWasmHooks) AfterInstantiate(ctx sdk.Context, codeID uint64, contractAddr sdk.AccAddress, creator sdk.AccAddress) {
+	m.calls = append(m.calls, fmt.Sprintf("instantiate %d", codeID))
+}
+
+func (m *mockWasmHooks) AfterExecute(ctx sdk.Context, contractAddr sdk.AccAddress, caller sdk.AccAddress) {
+	m.calls = append(m.calls, "execute")
+}
+
+func (m *mockWasmHooks) AfterMigrate(ctx sdk.Context, contractAddr sdk.AccAddress, oldCodeID uint64, newCodeID uint64) {
+	m.calls = append(m.calls, fmt.Sprintf("migrate %d -> %d", oldCodeID, newCodeID))
+}
+
+func TestWasmHooks(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	first, second := &mockWasmHooks{}, &mockWasmHooks{}
+	keeper.SetHooks(types.NewMultiWasmHooks(first, second))
+	require.Panics(t, func() { keeper.SetHooks(first) })
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+	fred := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	codeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+	newCodeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: fred, Beneficiary: bob})
+	require.NoError(t, err)
+	addr, err := keeper.Instantiate(ctx, codeID, creator, creator, initMsgBz, "demo contract", false, deposit)
+	require.NoError(t, err)
+
+	// simulations are not reported
+	res := simulateExecute(ctx, keeper, types.MsgExecuteContract{Sender: fred, Contract: addr, Msg: []byte(`{}`)})
+	require.Empty(t, res.Error)
+
+	_, err = keeper.Execute(ctx, addr, fred, []byte(`{}`), nil)
+	require.NoError(t, err)
+
+	require.NoError(t, keeper.Migrate(ctx, addr, creator, newCodeID, []byte(`{}`)))
+
+	exp := []string{"store 1", "store 2", "instantiate 1", "execute", "migrate 1 -> 2"}
+	assert.Equal(t, exp, first.calls)
+	assert.Equal(t, exp, second.calls)
+}
+
+func TestQuerySmartGasLimit(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	codeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	_, _, fred := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: fred, Beneficiary: bob})
+	require.NoError(t, err)
+	addr, err := keeper.Instantiate(ctx, codeID, creator, nil, initMsgBz, "demo contract", false, nil)
+	require.NoError(t, err)
+
+	_, err = keeper.QuerySmart(ctx, addr, []byte(`{"verifier":{}}`))
+	require.NoError(t, err)
+
+	// a limit below a single store read
+	keeper.queryGasLimit = 10
+	_, err = keeper.QuerySmart(ctx, addr, []byte(`{"verifier":{}}`))
+	require.True(t, types.ErrGasLimit.Is(err), "expected %v but got %+v", types.ErrGasLimit, err)
 }
 
 func TestInstantiateWithNonExistingCodeID(t *testing.T) {
@@ -122,7 +462,7 @@ func TestInstantiateWithNonExistingCodeID(t *testing.T) {
 	require.NoError(t, err)
 
 	const nonExistingCodeID = 9999
-	addr, err := keeper.Instantiate(ctx, nonExistingCodeID, creator, initMsgBz, nil)
+	addr, err := keeper.Instantiate(ctx, nonExistingCodeID, creator, nil, initMsgBz, "demo contract", false, nil)
 	require.True(t, types.ErrNotFound.Is(err), err)
 	require.Nil(t, addr)
 }
@@ -141,7 +481,7 @@ func TestExecute(t *testing.T) {
 	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
 	require.NoError(t, err)
 
-	contractID, err := keeper.Create(ctx, creator, wasmCode, "", "")
+	contractID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
 	require.NoError(t, err)
 
 	_, _, bob := keyPubAddr()
@@ -152,7 +492,7 @@ func TestExecute(t *testing.T) {
 	initMsgBz, err := json.Marshal(initMsg)
 	require.NoError(t, err)
 
-	addr, err := keeper.Instantiate(ctx, contractID, creator, initMsgBz, deposit)
+	addr, err := keeper.Instantiate(ctx, contractID, creator, nil, initMsgBz, "demo contract", false, deposit)
 	require.NoError(t, err)
 	require.Equal(t, "cosmos18vd8fpwxzck93qlwghaj6arh4p7c5n89uzcee5", addr.String())
 
@@ -189,7 +529,7 @@ func TestExecute(t *testing.T) {
 
 	// make sure gas is properly deducted from ctx
 	gasAfter := ctx.GasMeter().GasConsumed()
-	require.Equal(t, uint64(31723), gasAfter-gasBefore)
+	require.Equal(t, uint64(31813), gasAfter-gasBefore)
 
 	// ensure bob now exists and got both payments released
 	bobAcct = accKeeper.GetAccount(ctx, bob)
@@ -205,6 +545,215 @@ func TestExecute(t *testing.T) {
 	t.Logf("Duration: %v (81488 gas)\n", diff)
 }
 
+func BenchmarkExecute(b *testing.B) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(b, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(b, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	topUp := sdk.NewCoins(sdk.NewInt64Coin("denom", 5000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+	fred := createFakeFundedAccount(ctx, accKeeper, topUp)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(b, err)
+	codeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(b, err)
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: fred, Beneficiary: bob})
+	require.NoError(b, err)
+	addr, err := keeper.Instantiate(ctx, codeID, creator, nil, initMsgBz, "demo contract", false, deposit)
+	require.NoError(b, err)
+
+	specs := map[string]struct {
+		funds sdk.Coins
+	}{
+		"without funds": {},
+		"with funds":    {funds: topUp},
+	}
+	for msg, spec := range specs {
+		b.Run(msg, func(b *testing.B) {
+			var gasUsed uint64
+			for i := 0; i < b.N; i++ {
+				// each run starts from the same state so the contract balance does not drain
+				cacheCtx, _ := ctx.CacheContext()
+				cacheCtx = cacheCtx.WithGasMeter(sdk.NewGasMeter(1000000))
+				_, err := keeper.Execute(cacheCtx, addr, fred, []byte(`{}`), spec.funds)
+				require.NoError(b, err)
+				gasUsed += cacheCtx.GasMeter().GasConsumed()
+			}
+			b.ReportMetric(float64(gasUsed)/float64(b.N), "gas/op")
+		})
+	}
+}
+
+func TestExecuteAdminOnly(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	topUp := sdk.NewCoins(sdk.NewInt64Coin("denom", 5000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit.Add(deposit))
+	fred := createFakeFundedAccount(ctx, accKeeper, topUp)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	codeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: fred, Beneficiary: bob})
+	require.NoError(t, err)
+
+	// an admin is required
+	_, err = keeper.Instantiate(ctx, codeID, creator, nil, initMsgBz, "demo contract", true, nil)
+	require.True(t, types.ErrInstantiateFailed.Is(err), "got %+v", err)
+
+	addr, err := keeper.Instantiate(ctx, codeID, creator, fred, initMsgBz, "demo contract", true, deposit)
+	require.NoError(t, err)
+	assert.True(t, keeper.GetContractInfo(ctx, addr).AdminOnlyExecute)
+
+	specs := map[string]struct {
+		caller sdk.AccAddress
+		expErr *sdkErrors.Error
+	}{
+		"admin": {
+			caller: fred,
+		},
+		"creator is not admin": {
+			caller: creator,
+			expErr: sdkErrors.ErrUnauthorized,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			ctx, _ := ctx.CacheContext()
+			balanceBefore := accKeeper.GetAccount(ctx, spec.caller).GetCoins()
+
+			_, err := keeper.Execute(ctx, addr, spec.caller, []byte(`{}`), topUp)
+			require.True(t, spec.expErr.Is(err), "got %+v", err)
+			if spec.expErr != nil {
+				// no funds are moved
+				assert.Equal(t, balanceBefore, accKeeper.GetAccount(ctx, spec.caller).GetCoins())
+			}
+		})
+	}
+}
+
+func TestExecuteWithMaxContractGas(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit.Add(deposit))
+	fred := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	codeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: fred, Beneficiary: bob})
+	require.NoError(t, err)
+	addr, err := keeper.Instantiate(ctx, codeID, creator, nil, initMsgBz, "demo contract", false, deposit)
+	require.NoError(t, err)
+
+	// execute returns the gas consumed on a fresh tx gas meter
+	execute := func(maxContractGas, txGasLimit uint64) (uint64, error) {
+		params := keeper.GetParams(ctx)
+		params.MaxContractGas = maxContractGas
+		keeper.SetParams(ctx, params)
+
+		trialCtx := ctx.WithMultiStore(ctx.MultiStore().CacheWrap().(sdk.MultiStore)).
+			WithGasMeter(sdk.NewGasMeter(txGasLimit))
+		_, err := keeper.Execute(trialCtx, addr, fred, []byte(`{}`), nil)
+		return trialCtx.GasMeter().GasConsumed(), err
+	}
+
+	unlimitedGas, err := execute(0, 1_000_000)
+	require.NoError(t, err)
+
+	// a cap above the gas used does not change the gas consumed
+	gasUsed, err := execute(unlimitedGas*2, 1_000_000)
+	require.NoError(t, err)
+	assert.Equal(t, unlimitedGas, gasUsed)
+
+	// a cap below the gas used fails the execution and consumes the capped gas only
+	gasUsed, err = execute(500, 1_000_000)
+	require.True(t, types.ErrMaxContractGas.Is(err), "got %+v", err)
+	assert.True(t, gasUsed < unlimitedGas, "used %d, unlimited %d", gasUsed, unlimitedGas)
+
+	// the tx gas limit applies when it is below the cap
+	assert.Panics(t, func() {
+		_, _ = execute(1_000_000, 500)
+	})
+}
+
+func TestWithContractGasLimit(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, _, keeper := CreateTestInput(t, false, tempDir)
+
+	const maxContractGas = 1000
+	params := keeper.GetParams(ctx)
+	params.MaxContractGas = maxContractGas
+	keeper.SetParams(ctx, params)
+
+	specs := map[string]struct {
+		call       func(ctx sdk.Context) error
+		expErr     *sdkErrors.Error
+		expGasUsed uint64
+	}{
+		"within limit": {
+			call: func(ctx sdk.Context) error {
+				ctx.GasMeter().ConsumeGas(400, "testing")
+				return nil
+			},
+			expGasUsed: 400,
+		},
+		"out of gas panic": {
+			call: func(ctx sdk.Context) error {
+				ctx.GasMeter().ConsumeGas(maxContractGas+1, "testing")
+				return nil
+			},
+			expErr:     types.ErrMaxContractGas,
+			expGasUsed: maxContractGas,
+		},
+		"error with gas used up": {
+			call: func(ctx sdk.Context) error {
+				ctx.GasMeter().ConsumeGas(maxContractGas, "testing")
+				return sdkErrors.Wrap(types.ErrExecuteFailed, "testing")
+			},
+			expErr:     types.ErrMaxContractGas,
+			expGasUsed: maxContractGas,
+		},
+		"out of gas message within limit": {
+			call: func(ctx sdk.Context) error {
+				ctx.GasMeter().ConsumeGas(400, "testing")
+				return sdkErrors.Wrap(types.ErrExecuteFailed, "out of gas")
+			},
+			expErr:     types.ErrExecuteFailed,
+			expGasUsed: 400,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			txCtx := ctx.WithGasMeter(sdk.NewGasMeter(1_000_000))
+			err := keeper.withContractGasLimit(txCtx, spec.call)
+			require.True(t, spec.expErr.Is(err), "got %+v", err)
+			assert.Equal(t, spec.expGasUsed, txCtx.GasMeter().GasConsumed())
+		})
+	}
+}
+
 func TestExecuteWithNonExistingAddress(t *testing.T) {
 	tempDir, err := ioutil.TempDir("", "wasm")
 	require.NoError(t, err)
@@ -220,6 +769,325 @@ func TestExecuteWithNonExistingAddress(t *testing.T) {
 	require.True(t, types.ErrNotFound.Is(err), err)
 }
 
+func TestMigrate(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+	fred := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	originalCodeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+	newCodeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: fred, Beneficiary: bob})
+	require.NoError(t, err)
+
+	specs := map[string]struct {
+		admin     sdk.AccAddress
+		caller    sdk.AccAddress
+		codeID    uint64
+		expErr    *sdkErrors.Error
+		expCodeID uint64
+	}{
+		"all good": {
+			admin:     creator,
+			caller:    creator,
+			codeID:    newCodeID,
+			expCodeID: newCodeID,
+		},
+		"without admin": {
+			caller:    creator,
+			codeID:    newCodeID,
+			expErr:    types.ErrMigrationFailed,
+			expCodeID: originalCodeID,
+		},
+		"caller not admin": {
+			admin:     creator,
+			caller:    fred,
+			codeID:    newCodeID,
+			expErr:    sdkErrors.ErrUnauthorized,
+			expCodeID: originalCodeID,
+		},
+		"unknown code": {
+			admin:     creator,
+			caller:    creator,
+			codeID:    9999,
+			expErr:    types.ErrNotFound,
+			expCodeID: originalCodeID,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			contractAddr, err := keeper.Instantiate(ctx, originalCodeID, creator, spec.admin, initMsgBz, "demo contract", false, nil)
+			require.NoError(t, err)
+
+			err = keeper.Migrate(ctx, contractAddr, spec.caller, spec.codeID, []byte(`{}`))
+			require.True(t, spec.expErr.Is(err), "expected %v but got %+v", spec.expErr, err)
+			assert.Equal(t, spec.expCodeID, keeper.GetContractInfo(ctx, contractAddr).CodeID)
+
+			// the code id index follows the migration
+			var found bool
+			keeper.IterateContractsByCode(ctx, spec.expCodeID, func(addr sdk.AccAddress, _ types.ContractInfo) bool {
+				found = found || addr.Equals(contractAddr)
+				return false
+			})
+			assert.True(t, found)
+		})
+	}
+}
+
+func TestUpdateContractAdmin(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+	fred := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	codeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: fred, Beneficiary: bob})
+	require.NoError(t, err)
+
+	specs := map[string]struct {
+		instAdmin sdk.AccAddress
+		newAdmin  sdk.AccAddress
+		caller    sdk.AccAddress
+		expErr    *sdkErrors.Error
+		expAdmin  sdk.AccAddress
+	}{
+		"admin sets new admin": {
+			instAdmin: creator,
+			newAdmin:  fred,
+			caller:    creator,
+			expAdmin:  fred,
+		},
+		"admin clears admin": {
+			instAdmin: creator,
+			caller:    creator,
+		},
+		"other sets new admin": {
+			instAdmin: creator,
+			newAdmin:  fred,
+			caller:    fred,
+			expErr:    sdkErrors.ErrUnauthorized,
+			expAdmin:  creator,
+		},
+		"no admin set": {
+			newAdmin: fred,
+			caller:   creator,
+			expErr:   sdkErrors.ErrUnauthorized,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			addr, err := keeper.Instantiate(ctx, codeID, creator, spec.instAdmin, initMsgBz, "demo contract", false, nil)
+			require.NoError(t, err)
+			if spec.newAdmin != nil {
+				err = keeper.UpdateContractAdmin(ctx, addr, spec.caller, spec.newAdmin)
+			} else {
+				err = keeper.ClearContractAdmin(ctx, addr, spec.caller)
+			}
+			require.True(t, spec.expErr.Is(err), err)
+			assert.Equal(t, spec.expAdmin, keeper.GetContractInfo(ctx, addr).Admin)
+		})
+	}
+}
+
+func TestUpdateInstantiateConfig(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+	fred := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+
+	specs := map[string]struct {
+		newConfig   types.AccessConfig
+		caller      sdk.AccAddress
+		confirmLock bool
+		expErr      *sdkErrors.Error
+	}{
+		"creator restricts to self": {
+			newConfig: types.OnlyAddress(creator),
+			caller:    creator,
+		},
+		"creator locks out self without confirmation": {
+			newConfig: types.OnlyAddress(fred),
+			caller:    creator,
+			expErr:    types.ErrInvalidMsg,
+		},
+		"creator locks out self with confirmation": {
+			newConfig:   types.AllowNobody,
+			caller:      creator,
+			confirmLock: true,
+		},
+		"other updates": {
+			newConfig: types.AllowNobody,
+			caller:    fred,
+			expErr:    sdkErrors.ErrUnauthorized,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			codeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+			require.NoError(t, err)
+			err = keeper.UpdateInstantiateConfig(ctx, codeID, spec.caller, spec.newConfig, spec.confirmLock)
+			require.True(t, spec.expErr.Is(err), "got %+v", err)
+			expConfig := spec.newConfig
+			if spec.expErr != nil {
+				expConfig = types.AllowEverybody
+			}
+			assert.Equal(t, expConfig, keeper.GetCodeInfo(ctx, codeID).InstantiateConfig)
+		})
+	}
+
+	t.Run("unknown code", func(t *testing.T) {
+		err := keeper.UpdateInstantiateConfig(ctx, 99, creator, types.AllowNobody, true)
+		assert.True(t, types.ErrNotFound.Is(err), "got %+v", err)
+	})
+	t.Run("by governance", func(t *testing.T) {
+		codeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+		require.NoError(t, err)
+		require.NoError(t, keeper.UpdateInstantiateConfigByGovernance(ctx, codeID, types.AllowNobody))
+		assert.Equal(t, types.AllowNobody, keeper.GetCodeInfo(ctx, codeID).InstantiateConfig)
+		_, err = keeper.Instantiate(ctx, codeID, creator, nil, []byte("{}"), "demo contract", false, nil)
+		assert.True(t, sdkErrors.ErrUnauthorized.Is(err), "got %+v", err)
+	})
+}
+
+func TestDeprecateCode(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+	fred := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	codeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: fred, Beneficiary: bob})
+	require.NoError(t, err)
+
+	// only the creator can deprecate
+	err = keeper.DeprecateCode(ctx, codeID, fred, true)
+	require.True(t, sdkErrors.ErrUnauthorized.Is(err), "got %+v", err)
+	err = keeper.DeprecateCode(ctx, 99, creator, true)
+	require.True(t, types.ErrNotFound.Is(err), "got %+v", err)
+	require.NoError(t, keeper.DeprecateCode(ctx, codeID, creator, true))
+	assert.True(t, keeper.GetCodeInfo(ctx, codeID).Deprecated)
+
+	specs := map[string]struct {
+		reject     bool
+		expErr     *sdkErrors.Error
+		expWarning bool
+	}{
+		"instantiated with warning": {
+			expWarning: true,
+		},
+		"rejected": {
+			reject: true,
+			expErr: types.ErrInstantiateFailed,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			ctx, _ := ctx.CacheContext()
+			ctx = ctx.WithEventManager(sdk.NewEventManager())
+			params := keeper.GetParams(ctx)
+			params.RejectDeprecatedInstantiate = spec.reject
+			keeper.SetParams(ctx, params)
+
+			_, err := keeper.Instantiate(ctx, codeID, creator, nil, initMsgBz, "demo contract", false, nil)
+			require.True(t, spec.expErr.Is(err), "got %+v", err)
+			var warned bool
+			for _, e := range ctx.EventManager().Events() {
+				warned = warned || e.Type == types.EventTypeInstantiateDeprecated
+			}
+			assert.Equal(t, spec.expWarning, warned)
+		})
+	}
+
+	t.Run("undeprecated", func(t *testing.T) {
+		ctx, _ := ctx.CacheContext()
+		ctx = ctx.WithEventManager(sdk.NewEventManager())
+		require.NoError(t, keeper.DeprecateCodeByGovernance(ctx, codeID, false))
+		assert.False(t, keeper.GetCodeInfo(ctx, codeID).Deprecated)
+		_, err := keeper.Instantiate(ctx, codeID, creator, nil, initMsgBz, "demo contract", false, nil)
+		require.NoError(t, err)
+		for _, e := range ctx.EventManager().Events() {
+			assert.NotEqual(t, types.EventTypeInstantiateDeprecated, e.Type)
+		}
+	})
+}
+
+func TestIterateContractState(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, _, keeper := CreateTestInput(t, false, tempDir)
+
+	_, _, contractAddr := keyPubAddr()
+	_, _, otherAddr := keyPubAddr()
+	keeper.setContractState(ctx, contractAddr, []types.Model{
+		{Key: []byte("b"), Value: []byte("2")},
+		{Key: []byte("a"), Value: []byte("1")},
+		{Key: []byte("c"), Value: []byte("3")},
+	})
+	keeper.setContractState(ctx, otherAddr, []types.Model{{Key: []byte("a"), Value: []byte("other")}})
+
+	var all []types.Model
+	keeper.IterateContractState(ctx, contractAddr, func(key, value []byte) bool {
+		all = append(all, types.Model{Key: key, Value: value})
+		return false
+	})
+	assert.Equal(t, []types.Model{
+		{Key: []byte("a"), Value: []byte("1")},
+		{Key: []byte("b"), Value: []byte("2")},
+		{Key: []byte("c"), Value: []byte("3")},
+	}, all)
+
+	// stops when the callback returns true
+	var keys []string
+	keeper.IterateContractState(ctx, contractAddr, func(key, _ []byte) bool {
+		keys = append(keys, string(key))
+		return len(keys) == 2
+	})
+	assert.Equal(t, []string{"a", "b"}, keys)
+
+	// no callback for contracts without state
+	_, _, emptyAddr := keyPubAddr()
+	keeper.IterateContractState(ctx, emptyAddr, func(_, _ []byte) bool {
+		t.Fatal("unexpected callback")
+		return true
+	})
+}
+
 type InitMsg struct {
 	Verifier    sdk.AccAddress `json:"verifier"`
 	Beneficiary sdk.AccAddress `json:"beneficiary"`
@@ -11,8 +11,12 @@ import (
 
 	wasmTypes "github.com/confio/go-cosmwasm/types"
 	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkErrors "github.com/cosmos/cosmos-sdk/types/errors"
 	"github.com/cosmos/cosmos-sdk/x/auth"
 	"github.com/cosmos/cosmos-sdk/x/bank"
+	"github.com/cosmos/cosmos-sdk/x/staking"
+
+	"github.com/cosmwasm/wasmd/x/wasm/internal/types"
 )
 
 // MaskInitMsg is {}
@@ -44,13 +48,13 @@ func TestMaskReflectOpaque(t *testing.T) {
 	// upload code
 	maskCode, err := ioutil.ReadFile("./testdata/mask.wasm")
 	require.NoError(t, err)
-	codeID, err := keeper.Create(ctx, creator, maskCode, "", "")
+	codeID, err := keeper.Create(ctx, creator, maskCode, "", "", nil)
 	require.NoError(t, err)
 	require.Equal(t, uint64(1), codeID)
 
 	// creator instantiates a contract and gives it tokens
 	contractStart := sdk.NewCoins(sdk.NewInt64Coin("denom", 40000))
-	contractAddr, err := keeper.Instantiate(ctx, codeID, creator, []byte("{}"), contractStart)
+	contractAddr, err := keeper.Instantiate(ctx, codeID, creator, nil, []byte("{}"), "demo contract", false, contractStart)
 	require.NoError(t, err)
 	require.NotEmpty(t, contractAddr)
 
@@ -138,20 +142,20 @@ func TestMaskReflectContractSend(t *testing.T) {
 	// upload mask code
 	maskCode, err := ioutil.ReadFile("./testdata/mask.wasm")
 	require.NoError(t, err)
-	maskID, err := keeper.Create(ctx, creator, maskCode, "", "")
+	maskID, err := keeper.Create(ctx, creator, maskCode, "", "", nil)
 	require.NoError(t, err)
 	require.Equal(t, uint64(1), maskID)
 
 	// upload hackatom escrow code
 	escrowCode, err := ioutil.ReadFile("./testdata/contract.wasm")
 	require.NoError(t, err)
-	escrowID, err := keeper.Create(ctx, creator, escrowCode, "", "")
+	escrowID, err := keeper.Create(ctx, creator, escrowCode, "", "", nil)
 	require.NoError(t, err)
 	require.Equal(t, uint64(2), escrowID)
 
 	// creator instantiates a contract and gives it tokens
 	maskStart := sdk.NewCoins(sdk.NewInt64Coin("denom", 40000))
-	maskAddr, err := keeper.Instantiate(ctx, maskID, creator, []byte("{}"), maskStart)
+	maskAddr, err := keeper.Instantiate(ctx, maskID, creator, nil, []byte("{}"), "demo contract", false, maskStart)
 	require.NoError(t, err)
 	require.NotEmpty(t, maskAddr)
 
@@ -163,7 +167,7 @@ func TestMaskReflectContractSend(t *testing.T) {
 	initMsgBz, err := json.Marshal(initMsg)
 	require.NoError(t, err)
 	escrowStart := sdk.NewCoins(sdk.NewInt64Coin("denom", 25000))
-	escrowAddr, err := keeper.Instantiate(ctx, escrowID, creator, initMsgBz, escrowStart)
+	escrowAddr, err := keeper.Instantiate(ctx, escrowID, creator, nil, initMsgBz, "demo contract", false, escrowStart)
 	require.NoError(t, err)
 	require.NotEmpty(t, escrowAddr)
 
@@ -206,6 +210,487 @@ func TestMaskReflectContractSend(t *testing.T) {
 
 }
 
+func TestMaskReflectSendErrors(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+	_, _, fred := keyPubAddr()
+
+	maskCode, err := ioutil.ReadFile("./testdata/mask.wasm")
+	require.NoError(t, err)
+	codeID, err := keeper.Create(ctx, creator, maskCode, "", "", nil)
+	require.NoError(t, err)
+	contractStart := sdk.NewCoins(sdk.NewInt64Coin("denom", 40000))
+	contractAddr, err := keeper.Instantiate(ctx, codeID, creator, nil, []byte("{}"), "demo contract", false, contractStart)
+	require.NoError(t, err)
+
+	specs := map[string]struct {
+		from   string
+		amount []wasmTypes.Coin
+		expErr *sdkErrors.Error
+	}{
+		"all funds": {
+			from:   contractAddr.String(),
+			amount: []wasmTypes.Coin{{Denom: "denom", Amount: "40000"}},
+		},
+		"more than balance": {
+			from:   contractAddr.String(),
+			amount: []wasmTypes.Coin{{Denom: "denom", Amount: "40001"}},
+			expErr: sdkErrors.ErrInsufficientFunds,
+		},
+		"unknown denom": {
+			from:   contractAddr.String(),
+			amount: []wasmTypes.Coin{{Denom: "denom", Amount: "1"}, {Denom: "alx", Amount: "1"}},
+			expErr: sdkErrors.ErrInsufficientFunds,
+		},
+		"from other account": {
+			from:   creator.String(),
+			amount: []wasmTypes.Coin{{Denom: "denom", Amount: "1"}},
+			expErr: sdkErrors.ErrUnauthorized,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			ctx, _ := ctx.CacheContext()
+			reflectSendBz, err := json.Marshal(MaskHandleMsg{Reflect: &reflectPayload{Msg: wasmTypes.CosmosMsg{
+				Send: &wasmTypes.SendMsg{FromAddress: spec.from, ToAddress: fred.String(), Amount: spec.amount},
+			}}})
+			require.NoError(t, err)
+
+			_, err = keeper.Execute(ctx, contractAddr, creator, reflectSendBz, nil)
+			require.True(t, spec.expErr.Is(err), "got %+v", err)
+			if spec.expErr != nil {
+				checkAccount(t, ctx, accKeeper, contractAddr, contractStart)
+				checkAccount(t, ctx, accKeeper, fred, nil)
+				return
+			}
+			checkAccount(t, ctx, accKeeper, contractAddr, sdk.Coins{})
+			checkAccount(t, ctx, accKeeper, fred, contractStart)
+		})
+	}
+}
+
+func TestMaskReflectWasmMessages(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+	_, _, bob := keyPubAddr()
+
+	maskCode, err := ioutil.ReadFile("./testdata/mask.wasm")
+	require.NoError(t, err)
+	maskID, err := keeper.Create(ctx, creator, maskCode, "", "", nil)
+	require.NoError(t, err)
+	escrowCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	escrowID, err := keeper.Create(ctx, creator, escrowCode, "", "", nil)
+	require.NoError(t, err)
+
+	maskStart := sdk.NewCoins(sdk.NewInt64Coin("denom", 40000))
+	maskAddr, err := keeper.Instantiate(ctx, maskID, creator, nil, []byte("{}"), "demo contract", false, maskStart)
+	require.NoError(t, err)
+
+	reflect := func(sender sdk.AccAddress, sdkMsg sdk.Msg) error {
+		opaque, err := ToOpaqueMsg(keeper.cdc, sdkMsg)
+		require.NoError(t, err)
+		reflectBz, err := json.Marshal(MaskHandleMsg{
+			Reflect: &reflectPayload{Msg: wasmTypes.CosmosMsg{Opaque: opaque}},
+		})
+		require.NoError(t, err)
+		_, err = keeper.Execute(ctx, maskAddr, sender, reflectBz, nil)
+		return err
+	}
+
+	// the mask instantiates an escrow with some of its funds
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: maskAddr, Beneficiary: bob})
+	require.NoError(t, err)
+	escrowStart := sdk.NewCoins(sdk.NewInt64Coin("denom", 25000))
+	err = reflect(creator, &types.MsgInstantiateContract{
+		Sender:    maskAddr,
+		Admin:     maskAddr,
+		Code:      escrowID,
+		Label:     "escrow",
+		InitMsg:   initMsgBz,
+		InitFunds: escrowStart,
+	})
+	require.NoError(t, err)
+
+	var escrowAddr sdk.AccAddress
+	keeper.IterateContractsByCode(ctx, escrowID, func(addr sdk.AccAddress, info types.ContractInfo) bool {
+		escrowAddr = addr
+		return true
+	})
+	require.NotEmpty(t, escrowAddr)
+	info := keeper.GetContractInfo(ctx, escrowAddr)
+	assert.Equal(t, maskAddr, info.Creator)
+	checkAccount(t, ctx, accKeeper, escrowAddr, escrowStart)
+	checkAccount(t, ctx, accKeeper, maskAddr, sdk.NewCoins(sdk.NewInt64Coin("denom", 15000)))
+
+	// as admin, the mask can migrate the escrow
+	err = reflect(creator, &types.MsgMigrateContract{
+		Sender:     maskAddr,
+		Contract:   escrowAddr,
+		Code:       escrowID,
+		MigrateMsg: []byte(`{}`),
+	})
+	require.NoError(t, err)
+	assert.Len(t, keeper.GetContractHistory(ctx, escrowAddr), 2)
+
+	// as verifier, the mask can release the escrow
+	err = reflect(creator, &types.MsgExecuteContract{
+		Sender:   maskAddr,
+		Contract: escrowAddr,
+		Msg:      []byte(`{}`),
+	})
+	require.NoError(t, err)
+	checkAccount(t, ctx, accKeeper, bob, escrowStart)
+
+	// the contract can not send messages on behalf of others
+	err = reflect(creator, &types.MsgExecuteContract{
+		Sender:   creator,
+		Contract: escrowAddr,
+		Msg:      []byte(`{}`),
+	})
+	require.True(t, sdkErrors.ErrUnauthorized.Is(err), "got %+v", err)
+}
+
+func TestMaskReflectFundDenomsNotAccepted(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000), sdk.NewInt64Coin("other", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+	_, _, bob := keyPubAddr()
+
+	maskCode, err := ioutil.ReadFile("./testdata/mask.wasm")
+	require.NoError(t, err)
+	maskID, err := keeper.Create(ctx, creator, maskCode, "", "", nil)
+	require.NoError(t, err)
+	escrowCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	escrowID, err := keeper.Create(ctx, creator, escrowCode, "", "", nil)
+	require.NoError(t, err)
+
+	maskStart := sdk.NewCoins(sdk.NewInt64Coin("denom", 40000), sdk.NewInt64Coin("other", 40000))
+	maskAddr, err := keeper.Instantiate(ctx, maskID, creator, nil, []byte("{}"), "demo contract", false, maskStart)
+	require.NoError(t, err)
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: maskAddr, Beneficiary: bob})
+	require.NoError(t, err)
+	escrowAddr, err := keeper.Instantiate(ctx, escrowID, creator, nil, initMsgBz, "demo contract", false, nil)
+	require.NoError(t, err)
+
+	params := keeper.GetParams(ctx)
+	params.AcceptedFundDenoms = []string{"denom"}
+	keeper.SetParams(ctx, params)
+
+	opaque := func(sdkMsg sdk.Msg) wasmTypes.CosmosMsg {
+		msg, err := ToOpaqueMsg(keeper.cdc, sdkMsg)
+		require.NoError(t, err)
+		return wasmTypes.CosmosMsg{Opaque: msg}
+	}
+	specs := map[string]struct {
+		srcMsg wasmTypes.CosmosMsg
+		expErr *sdkErrors.Error
+	}{
+		"contract send with accepted denom": {
+			srcMsg: wasmTypes.CosmosMsg{Contract: &wasmTypes.ContractMsg{
+				ContractAddr: escrowAddr.String(),
+				Msg:          "{}",
+				Send:         []wasmTypes.Coin{{Denom: "denom", Amount: "1"}},
+			}},
+		},
+		"contract send with other denom": {
+			srcMsg: wasmTypes.CosmosMsg{Contract: &wasmTypes.ContractMsg{
+				ContractAddr: escrowAddr.String(),
+				Msg:          "{}",
+				Send:         []wasmTypes.Coin{{Denom: "other", Amount: "1"}},
+			}},
+			expErr: types.ErrExecuteFailed,
+		},
+		"execute msg with other denom": {
+			srcMsg: opaque(&types.MsgExecuteContract{
+				Sender:    maskAddr,
+				Contract:  escrowAddr,
+				Msg:       []byte(`{}`),
+				SentFunds: sdk.NewCoins(sdk.NewInt64Coin("other", 1)),
+			}),
+			expErr: types.ErrExecuteFailed,
+		},
+		"instantiate msg with other denom": {
+			srcMsg: opaque(&types.MsgInstantiateContract{
+				Sender:    maskAddr,
+				Code:      escrowID,
+				Label:     "escrow",
+				InitMsg:   initMsgBz,
+				InitFunds: sdk.NewCoins(sdk.NewInt64Coin("other", 1)),
+			}),
+			expErr: types.ErrInstantiateFailed,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			ctx, _ := ctx.CacheContext()
+			reflectBz, err := json.Marshal(MaskHandleMsg{Reflect: &reflectPayload{Msg: spec.srcMsg}})
+			require.NoError(t, err)
+			_, err = keeper.Execute(ctx, maskAddr, creator, reflectBz, nil)
+			require.True(t, spec.expErr.Is(err), "got %+v", err)
+			if spec.expErr != nil {
+				checkAccount(t, ctx, accKeeper, maskAddr, maskStart)
+			}
+		})
+	}
+}
+
+func TestMaskReflectMaxDispatchDepth(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	maskCode, err := ioutil.ReadFile("./testdata/mask.wasm")
+	require.NoError(t, err)
+	maskID, err := keeper.Create(ctx, creator, maskCode, "", "", nil)
+	require.NoError(t, err)
+	maskAddr, err := keeper.Instantiate(ctx, maskID, creator, nil, []byte("{}"), "demo contract", false, nil)
+	require.NoError(t, err)
+
+	// the mask owns itself so that it can reflect its own messages
+	changeOwnerBz, err := json.Marshal(MaskHandleMsg{Change: &ownerPayload{Owner: maskAddr}})
+	require.NoError(t, err)
+	_, err = keeper.Execute(ctx, maskAddr, creator, changeOwnerBz, nil)
+	require.NoError(t, err)
+
+	// nestedReflect returns a msg that makes the mask call itself depth times before it changes the owner
+	nestedReflect := func(depth int) []byte {
+		msg := changeOwnerBz
+		for i := 0; i < depth; i++ {
+			var err error
+			msg, err = json.Marshal(MaskHandleMsg{Reflect: &reflectPayload{Msg: wasmTypes.CosmosMsg{
+				Contract: &wasmTypes.ContractMsg{ContractAddr: maskAddr.String(), Msg: string(msg)},
+			}}})
+			require.NoError(t, err)
+		}
+		return msg
+	}
+
+	specs := map[string]struct {
+		depth  int
+		expErr *sdkErrors.Error
+	}{
+		"max depth": {
+			depth: MaxDispatchDepth,
+		},
+		"max depth exceeded": {
+			depth:  MaxDispatchDepth + 1,
+			expErr: types.ErrExecuteFailed,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			ctx, _ := ctx.CacheContext()
+			_, err := keeper.Execute(ctx, maskAddr, maskAddr, nestedReflect(spec.depth), nil)
+			require.True(t, spec.expErr.Is(err), "got %+v", err)
+		})
+	}
+}
+
+func TestMaskReflectReentrancy(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	maskCode, err := ioutil.ReadFile("./testdata/mask.wasm")
+	require.NoError(t, err)
+	maskID, err := keeper.Create(ctx, creator, maskCode, "", "", nil)
+	require.NoError(t, err)
+	addrA, err := keeper.Instantiate(ctx, maskID, creator, nil, []byte("{}"), "mask a", false, nil)
+	require.NoError(t, err)
+	addrB, err := keeper.Instantiate(ctx, maskID, creator, nil, []byte("{}"), "mask b", false, nil)
+	require.NoError(t, err)
+
+	// the masks own each other so that they can reflect messages into one another
+	changeOwner := func(owner sdk.AccAddress) []byte {
+		bz, err := json.Marshal(MaskHandleMsg{Change: &ownerPayload{Owner: owner}})
+		require.NoError(t, err)
+		return bz
+	}
+	_, err = keeper.Execute(ctx, addrA, creator, changeOwner(addrB), nil)
+	require.NoError(t, err)
+	_, err = keeper.Execute(ctx, addrB, creator, changeOwner(addrA), nil)
+	require.NoError(t, err)
+
+	// callChain returns a msg for the first contract that calls the others in order, the last one changes its owner
+	callChain := func(chain ...sdk.AccAddress) []byte {
+		msg := changeOwner(chain[len(chain)-2])
+		for i := len(chain) - 1; i > 0; i-- {
+			var err error
+			msg, err = json.Marshal(MaskHandleMsg{Reflect: &reflectPayload{Msg: wasmTypes.CosmosMsg{
+				Contract: &wasmTypes.ContractMsg{ContractAddr: chain[i].String(), Msg: string(msg)},
+			}}})
+			require.NoError(t, err)
+		}
+		return msg
+	}
+
+	specs := map[string]struct {
+		chain         []sdk.AccAddress
+		reject        bool
+		expErr        *sdkErrors.Error
+		expReentrancy bool
+	}{
+		"A->B": {
+			chain:  []sdk.AccAddress{addrA, addrB},
+			reject: true,
+		},
+		"A->B->A rejected": {
+			chain:  []sdk.AccAddress{addrA, addrB, addrA},
+			reject: true,
+			expErr: types.ErrReentrancy,
+		},
+		"A->B->A flagged": {
+			chain:         []sdk.AccAddress{addrA, addrB, addrA},
+			expReentrancy: true,
+		},
+		"A->B->A->B rejected": {
+			chain:  []sdk.AccAddress{addrA, addrB, addrA, addrB},
+			reject: true,
+			expErr: types.ErrReentrancy,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			ctx, _ := ctx.CacheContext()
+			ctx = ctx.WithEventManager(sdk.NewEventManager())
+			params := keeper.GetParams(ctx)
+			params.RejectContractReentrancy = spec.reject
+			keeper.SetParams(ctx, params)
+
+			_, err := keeper.Execute(ctx, addrA, addrB, callChain(spec.chain...), nil)
+			require.True(t, spec.expErr.Is(err), "got %+v", err)
+			if spec.expErr != nil {
+				return
+			}
+			var flagged []string
+			for _, e := range ctx.EventManager().Events() {
+				if e.Type != types.EventTypeContractReentrancy {
+					continue
+				}
+				for _, attr := range e.Attributes {
+					flagged = append(flagged, string(attr.Value))
+				}
+			}
+			if spec.expReentrancy {
+				assert.Equal(t, []string{addrA.String()}, flagged)
+			} else {
+				assert.Empty(t, flagged)
+			}
+		})
+	}
+}
+
+func TestMaskReflectStakingMsgs(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	// record the routed messages instead of running a full staking module
+	var routed []sdk.Msg
+	keeper.router.AddRoute(staking.RouterKey, func(ctx sdk.Context, msg sdk.Msg) sdk.Result {
+		routed = append(routed, msg)
+		return sdk.Result{}
+	})
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+	_, _, valAddr := keyPubAddr()
+	_, _, otherValAddr := keyPubAddr()
+
+	maskCode, err := ioutil.ReadFile("./testdata/mask.wasm")
+	require.NoError(t, err)
+	maskID, err := keeper.Create(ctx, creator, maskCode, "", "", nil)
+	require.NoError(t, err)
+	maskAddr, err := keeper.Instantiate(ctx, maskID, creator, nil, []byte("{}"), "demo contract", false, nil)
+	require.NoError(t, err)
+
+	amount := sdk.NewInt64Coin("stake", 1000)
+	specs := map[string]struct {
+		msg       sdk.Msg
+		allowed   []string
+		expErr    *sdkErrors.Error
+		expRouted bool
+	}{
+		"delegate": {
+			msg:       staking.NewMsgDelegate(maskAddr, sdk.ValAddress(valAddr), amount),
+			allowed:   types.DefaultParams().ContractStakingMsgs,
+			expRouted: true,
+		},
+		"undelegate": {
+			msg:       staking.NewMsgUndelegate(maskAddr, sdk.ValAddress(valAddr), amount),
+			allowed:   types.DefaultParams().ContractStakingMsgs,
+			expRouted: true,
+		},
+		"redelegate": {
+			msg:       staking.NewMsgBeginRedelegate(maskAddr, sdk.ValAddress(valAddr), sdk.ValAddress(otherValAddr), amount),
+			allowed:   types.DefaultParams().ContractStakingMsgs,
+			expRouted: true,
+		},
+		"msg type not enabled": {
+			msg:     staking.NewMsgUndelegate(maskAddr, sdk.ValAddress(valAddr), amount),
+			allowed: []string{types.StakingMsgTypeDelegate},
+			expErr:  sdkErrors.ErrUnauthorized,
+		},
+		"staking disabled": {
+			msg:    staking.NewMsgDelegate(maskAddr, sdk.ValAddress(valAddr), amount),
+			expErr: sdkErrors.ErrUnauthorized,
+		},
+		"other delegator": {
+			msg:     staking.NewMsgDelegate(creator, sdk.ValAddress(valAddr), amount),
+			allowed: types.DefaultParams().ContractStakingMsgs,
+			expErr:  sdkErrors.ErrUnauthorized,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			ctx, _ := ctx.CacheContext()
+			routed = nil
+			params := types.DefaultParams()
+			params.ContractStakingMsgs = spec.allowed
+			keeper.SetParams(ctx, params)
+
+			opaque, err := ToOpaqueMsg(keeper.cdc, spec.msg)
+			require.NoError(t, err)
+			reflectBz, err := json.Marshal(MaskHandleMsg{Reflect: &reflectPayload{Msg: wasmTypes.CosmosMsg{Opaque: opaque}}})
+			require.NoError(t, err)
+
+			_, err = keeper.Execute(ctx, maskAddr, creator, reflectBz, nil)
+			require.True(t, spec.expErr.Is(err), "got %+v", err)
+			if !spec.expRouted {
+				assert.Empty(t, routed)
+				return
+			}
+			require.Len(t, routed, 1)
+			assert.Equal(t, spec.msg, routed[0])
+		})
+	}
+}
+
 func checkAccount(t *testing.T, ctx sdk.Context, accKeeper auth.AccountKeeper, addr sdk.AccAddress, expected sdk.Coins) {
 	acct := accKeeper.GetAccount(ctx, addr)
 	if expected == nil {
@@ -0,0 +1,167 @@
+package keeper
+
+import (
+	"sort"
+	"sync"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// InvocationStats are the number of contract calls and the sdk gas they consumed
+type InvocationStats struct {
+	Invocations uint64 `json:"invocations"`
+	GasUsed     uint64 `json:"gas_used"`
+}
+
+// contractMetrics counts the instantiations and executions of contracts since the node started.
+// The counters live in memory on this node only and never affect consensus.
+type contractMetrics struct {
+	mu        sync.Mutex
+	codes     map[uint64]*InvocationStats
+	contracts map[string]*InvocationStats
+}
+
+func newContractMetrics() *contractMetrics {
+	return &contractMetrics{
+		codes:     make(map[uint64]*InvocationStats),
+		contracts: make(map[string]*InvocationStats),
+	}
+}
+
+// record adds a call of the contract to the counters. Calls in check tx are not counted, so that every
+// transaction is counted once when it is delivered.
+func (m *contractMetrics) record(ctx sdk.Context, codeID uint64, contractAddr sdk.AccAddress, gasUsed uint64) {
+	if m == nil || ctx.IsCheckTx() {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	codeStats, ok := m.codes[codeID]
+	if !ok {
+		codeStats = &InvocationStats{}
+		m.codes[codeID] = codeStats
+	}
+	codeStats.Invocations++
+	codeStats.GasUsed += gasUsed
+
+	contractStats, ok := m.contracts[contractAddr.String()]
+	if !ok {
+		contractStats = &InvocationStats{}
+		m.contracts[contractAddr.String()] = contractStats
+	}
+	contractStats.Invocations++
+	contractStats.GasUsed += gasUsed
+}
+
+// CodeMetrics are the invocation stats of all contracts of a code
+type CodeMetrics struct {
+	CodeID uint64 `json:"code_id"`
+	InvocationStats
+}
+
+// ContractMetrics are the invocation stats of a single contract
+type ContractMetrics struct {
+	Address string `json:"address"`
+	InvocationStats
+}
+
+// ContractMetricsResponse is returned by the contract-metrics debug query. Codes and contracts are sorted by
+// gas used, the highest first.
+type ContractMetricsResponse struct {
+	Codes     []CodeMetrics     `json:"codes"`
+	Contracts []ContractMetrics `json:"contracts"`
+}
+
+// snapshot returns a copy of the counters
+func (m *contractMetrics) snapshot() ContractMetricsResponse {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	res := ContractMetricsResponse{
+		Codes:     make([]CodeMetrics, 0, len(m.codes)),
+		Contracts: make([]ContractMetrics, 0, len(m.contracts)),
+	}
+	for codeID, stats := range m.codes {
+		res.Codes = append(res.Codes, CodeMetrics{CodeID: codeID, InvocationStats: *stats})
+	}
+	for addr, stats := range m.contracts {
+		res.Contracts = append(res.Contracts, ContractMetrics{Address: addr, InvocationStats: *stats})
+	}
+	sort.Slice(res.Codes, func(i, j int) bool {
+		if res.Codes[i].GasUsed != res.Codes[j].GasUsed {
+			return res.Codes[i].GasUsed > res.Codes[j].GasUsed
+		}
+		return res.Codes[i].CodeID < res.Codes[j].CodeID
+	})
+	sort.Slice(res.Contracts, func(i, j int) bool {
+		if res.Contracts[i].GasUsed != res.Contracts[j].GasUsed {
+			return res.Contracts[i].GasUsed > res.Contracts[j].GasUsed
+		}
+		return res.Contracts[i].Address < res.Contracts[j].Address
+	})
+	return res
+}
+
+// executedContracts is the in memory index of the contracts executed in the current block. Like the
+// contractMetrics it lives on this node only and never affects consensus. Executions of transactions that
+// fail later are indexed too.
+type executedContracts struct {
+	mu     sync.Mutex
+	height int64
+	addrs  []string
+	seen   map[string]bool
+}
+
+func newExecutedContracts() *executedContracts {
+	return &executedContracts{seen: make(map[string]bool)}
+}
+
+// reset clears the index for a new block
+func (e *executedContracts) reset(height int64) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.height = height
+	e.addrs = nil
+	e.seen = make(map[string]bool)
+}
+
+// record adds the contract to the index unless it was executed in this block before. Check tx is ignored.
+func (e *executedContracts) record(ctx sdk.Context, contractAddr sdk.AccAddress) {
+	if e == nil || ctx.IsCheckTx() {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.height != ctx.BlockHeight() {
+		e.height = ctx.BlockHeight()
+		e.addrs = nil
+		e.seen = make(map[string]bool)
+	}
+	addr := contractAddr.String()
+	if e.seen[addr] {
+		return
+	}
+	e.seen[addr] = true
+	e.addrs = append(e.addrs, addr)
+}
+
+// ExecutedThisBlockResponse is returned by the executed-this-block query. Contracts are in the order of their
+// first execution in the block.
+type ExecutedThisBlockResponse struct {
+	Height    int64    `json:"height"`
+	Contracts []string `json:"contracts"`
+}
+
+// snapshot returns a copy of the index
+func (e *executedContracts) snapshot() ExecutedThisBlockResponse {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return ExecutedThisBlockResponse{
+		Height:    e.height,
+		Contracts: append(make([]string, 0, len(e.addrs)), e.addrs...),
+	}
+}
@@ -0,0 +1,134 @@
+package keeper
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkErrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
+)
+
+func TestContractMetrics(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	// disabled by default
+	q := newQuerier(keeper)
+	_, err = q(ctx, []string{QueryContractMetrics}, abci.RequestQuery{})
+	require.True(t, sdkErrors.ErrUnknownRequest.Is(err), "got %+v", err)
+
+	keeper.metrics = newContractMetrics()
+	q = newQuerier(keeper)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+	fred := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	codeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: fred, Beneficiary: bob})
+	require.NoError(t, err)
+	contractAddr, err := keeper.Instantiate(ctx, codeID, creator, nil, initMsgBz, "demo contract", false, nil)
+	require.NoError(t, err)
+	otherAddr, err := keeper.Instantiate(ctx, codeID, creator, nil, initMsgBz, "other contract", false, nil)
+	require.NoError(t, err)
+
+	_, err = keeper.Execute(ctx, contractAddr, fred, []byte(`{}`), nil)
+	require.NoError(t, err)
+	// check tx is not counted
+	_, err = keeper.Execute(ctx.WithIsCheckTx(true), contractAddr, fred, []byte(`{}`), nil)
+	require.NoError(t, err)
+
+	bz, err := q(ctx, []string{QueryContractMetrics}, abci.RequestQuery{})
+	require.NoError(t, err)
+	var res ContractMetricsResponse
+	require.NoError(t, json.Unmarshal(bz, &res))
+
+	require.Len(t, res.Codes, 1)
+	assert.Equal(t, codeID, res.Codes[0].CodeID)
+	assert.Equal(t, uint64(3), res.Codes[0].Invocations)
+
+	require.Len(t, res.Contracts, 2)
+	// sorted by gas used, the executed contract first
+	assert.Equal(t, contractAddr.String(), res.Contracts[0].Address)
+	assert.Equal(t, uint64(2), res.Contracts[0].Invocations)
+	assert.Equal(t, otherAddr.String(), res.Contracts[1].Address)
+	assert.Equal(t, uint64(1), res.Contracts[1].Invocations)
+
+	assert.NotZero(t, res.Contracts[1].GasUsed)
+	assert.Equal(t, res.Contracts[0].GasUsed+res.Contracts[1].GasUsed, res.Codes[0].GasUsed)
+}
+
+func TestExecutedThisBlock(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	// disabled by default
+	q := newQuerier(keeper)
+	_, err = q(ctx, []string{QueryExecutedThisBlock}, abci.RequestQuery{})
+	require.True(t, sdkErrors.ErrUnknownRequest.Is(err), "got %+v", err)
+
+	keeper.executed = newExecutedContracts()
+	q = newQuerier(keeper)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+	fred := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	codeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: fred, Beneficiary: bob})
+	require.NoError(t, err)
+	contractAddr, err := keeper.Instantiate(ctx, codeID, creator, nil, initMsgBz, "demo contract", false, nil)
+	require.NoError(t, err)
+	otherAddr, err := keeper.Instantiate(ctx, codeID, creator, nil, initMsgBz, "other contract", false, nil)
+	require.NoError(t, err)
+
+	queryExecuted := func(ctx sdk.Context) ExecutedThisBlockResponse {
+		bz, err := q(ctx, []string{QueryExecutedThisBlock}, abci.RequestQuery{})
+		require.NoError(t, err)
+		var res ExecutedThisBlockResponse
+		require.NoError(t, json.Unmarshal(bz, &res))
+		return res
+	}
+
+	ctx = ctx.WithBlockHeight(10)
+	keeper.BeginBlock(ctx)
+	assert.Equal(t, ExecutedThisBlockResponse{Height: 10, Contracts: []string{}}, queryExecuted(ctx))
+
+	_, err = keeper.Execute(ctx, otherAddr, fred, []byte(`{}`), nil)
+	require.NoError(t, err)
+	_, err = keeper.Execute(ctx, contractAddr, fred, []byte(`{}`), nil)
+	require.NoError(t, err)
+	// executed again and in check tx are not added
+	_, err = keeper.Execute(ctx, otherAddr, fred, []byte(`{}`), nil)
+	require.NoError(t, err)
+	checkCtx, _ := ctx.CacheContext()
+	_, err = keeper.Execute(checkCtx.WithIsCheckTx(true), contractAddr, fred, []byte(`{}`), nil)
+	require.NoError(t, err)
+
+	exp := ExecutedThisBlockResponse{Height: 10, Contracts: []string{otherAddr.String(), contractAddr.String()}}
+	assert.Equal(t, exp, queryExecuted(ctx))
+
+	// reset with the next block
+	ctx = ctx.WithBlockHeight(11)
+	keeper.BeginBlock(ctx)
+	assert.Equal(t, ExecutedThisBlockResponse{Height: 11, Contracts: []string{}}, queryExecuted(ctx))
+}
@@ -1,8 +1,13 @@
 package keeper
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"math"
 	"strconv"
+	"strings"
 
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	sdkErrors "github.com/cosmos/cosmos-sdk/types/errors"
@@ -14,30 +19,95 @@ import (
 )
 
 const (
-	QueryListContracts    = "list-contracts"
-	QueryGetContract      = "contract-info"
-	QueryGetContractState = "contract-state"
-	QueryGetCode          = "code"
-	QueryListCode         = "list-code"
+	QueryListContracts         = "list-contracts"
+	QueryListContractsByCode   = "list-contracts-by-code"
+	QueryContractsByCodeDetail = "contracts-by-code-detailed"
+	QueryContractsCreatedAfter = "contracts-created-after"
+	QueryGetContract           = "contract-info"
+	QueryGetContractInfos      = "contract-infos"
+	QueryContractByLabel       = "contract-by-label"
+	QueryGetContractState      = "contract-state"
+	QueryContractStateJSON     = "state-json"
+	QueryContractHistory       = "contract-history"
+	QueryContractBalance       = "contract-balance"
+	QueryContractCodeInfo      = "contract-code-info"
+	QueryContractCodeIDs       = "contract-code-ids"
+	QueryContractMigratable    = "contract-migratable"
+	QueryGetCode               = "code"
+	QueryGetCodeInfo           = "code-info"
+	QueryListCode              = "list-code"
+	QueryListCodeByCreator     = "list-code-by-creator"
+	QueryCodeByHash            = "code-by-hash"
+	QueryContractsCount        = "contracts-count"
+	QueryCodesCount            = "codes-count"
+	QueryCodeUsage             = "code-usage"
+	QueryCodeStorageStats      = "code-storage-stats"
+	QueryPinnedCodes           = "pinned-codes"
+	QueryOrphanCodes           = "orphan-codes"
+	QueryCodePinned            = "code-pinned"
+	QueryCodeHasBytecode       = "code-has-bytecode"
+	QueryCodeVerification      = "code-verification"
+	QuerySimulateExecute       = "simulate-execute"
+	QueryContractMetrics       = "contract-metrics"
+	QueryExecutedThisBlock     = "executed-this-block"
 )
 
 const (
 	QueryMethodContractStateSmart = "smart"
 	QueryMethodContractStateAll   = "all"
-	QueryMethodContractStateRaw   = "raw"
+	// QueryMethodContractStateAllPrefix takes the key prefix as request data, like raw takes the key
+	QueryMethodContractStateAllPrefix = "all-prefix"
+	// QueryMethodContractStateAllPaginated takes a json encoded ContractStatePageRequest as request data
+	QueryMethodContractStateAllPaginated = "all-paginated"
+	QueryMethodContractStateRaw          = "raw"
+	// QueryMethodContractStateRawBatch takes a json array of base64 encoded keys
+	QueryMethodContractStateRawBatch = "raw-batch"
 )
 
-// controls error output on querier - set true when testing/debugging
-const debug = false
+// defaultQueryLimit is the page size used by list queries when no limit is given
+const defaultQueryLimit = 100
 
-// NewQuerier creates a new querier
+// pageLimit returns the page size for the requested limit. Zero selects the default. Limits above
+// MaxQueryResultEntries are rejected so that a single page can not exhaust the node memory.
+func pageLimit(ctx sdk.Context, keeper Keeper, limit uint64) (uint64, error) {
+	max := keeper.GetParams(ctx).MaxQueryResultEntries
+	if limit == 0 {
+		if defaultQueryLimit < max {
+			return defaultQueryLimit, nil
+		}
+		return max, nil
+	}
+	if limit > max {
+		return 0, sdkErrors.Wrapf(types.ErrQueryResultTooLarge, "limit %d exceeds max %d entries", limit, max)
+	}
+	return limit, nil
+}
+
+// pageWindow returns the number of entries before the 1 based page and the page size. Page 0 selects the first page.
+func pageWindow(ctx sdk.Context, keeper Keeper, page, limit uint64) (skip uint64, size uint64, err error) {
+	if page == 0 {
+		page = 1
+	}
+	if size, err = pageLimit(ctx, keeper, limit); err != nil {
+		return 0, 0, err
+	}
+	if page > math.MaxUint64/size {
+		return 0, 0, sdkErrors.Wrapf(sdkErrors.ErrUnknownRequest, "page %d out of range", page)
+	}
+	return (page - 1) * size, size, nil
+}
+
+// NewQuerier creates a new querier. Errors are redacted unless query debugging is enabled in the WasmConfig.
 func NewQuerier(keeper Keeper) sdk.Querier {
 	q := newQuerier(keeper)
 	return func(ctx sdk.Context, path []string, req abci.RequestQuery) ([]byte, sdk.Error) {
 		res, err := q(ctx, path, req)
 		// convert returned errors
+		if sdkErr, ok := err.(sdk.Error); ok {
+			return nil, sdkErr
+		}
 		if err != nil {
-			space, code, log := sdkErrors.ABCIInfo(err, debug)
+			space, code, log := sdkErrors.ABCIInfo(err, keeper.queryDebug)
 			sdkErr := sdk.NewError(sdk.CodespaceType(space), sdk.CodeType(code), log)
 			return nil, sdkErr
 		}
@@ -52,75 +122,601 @@ func newQuerier(keeper Keeper) func(sdk.Context, []string, abci.RequestQuery) ([
 		switch path[0] {
 		case QueryGetContract:
 			return queryContractInfo(ctx, path[1], req, keeper)
+		case QueryGetContractInfos:
+			return queryContractInfos(ctx, req, keeper)
+		case QueryContractByLabel:
+			if len(path) < 3 {
+				return nil, sdkErrors.Wrap(sdkErrors.ErrUnknownRequest, "unknown data query endpoint")
+			}
+			// labels may contain the path separator
+			return queryContractByLabel(ctx, path[1], strings.Join(path[2:], "/"), keeper)
 		case QueryListContracts:
 			return queryContractList(ctx, req, keeper)
+		case QueryListContractsByCode:
+			if len(path) < 2 {
+				return nil, sdkErrors.Wrap(sdkErrors.ErrUnknownRequest, "unknown data query endpoint")
+			}
+			return queryContractListByCode(ctx, path[1], req, keeper)
+		case QueryContractsByCodeDetail:
+			if len(path) < 2 {
+				return nil, sdkErrors.Wrap(sdkErrors.ErrUnknownRequest, "unknown data query endpoint")
+			}
+			return queryContractsByCodeDetailed(ctx, path[1], req, keeper)
+		case QueryContractsCreatedAfter:
+			if len(path) < 2 {
+				return nil, sdkErrors.Wrap(sdkErrors.ErrUnknownRequest, "unknown data query endpoint")
+			}
+			return queryContractsCreatedAfter(ctx, path[1], req, keeper)
 		case QueryGetContractState:
 			if len(path) < 3 {
 				return nil, sdkErrors.Wrap(sdkErrors.ErrUnknownRequest, "unknown data query endpoint")
 			}
 			return queryContractState(ctx, path[1], path[2], req, keeper)
+		case QueryContractStateJSON:
+			if len(path) < 2 {
+				return nil, sdkErrors.Wrap(sdkErrors.ErrUnknownRequest, "unknown data query endpoint")
+			}
+			return queryContractStateJSON(ctx, path[1], req, keeper)
+		case QueryContractHistory:
+			if len(path) < 2 {
+				return nil, sdkErrors.Wrap(sdkErrors.ErrUnknownRequest, "unknown data query endpoint")
+			}
+			return queryContractHistory(ctx, path[1], keeper)
+		case QueryContractCodeIDs:
+			if len(path) < 2 {
+				return nil, sdkErrors.Wrap(sdkErrors.ErrUnknownRequest, "unknown data query endpoint")
+			}
+			return queryContractCodeIDs(ctx, path[1], keeper)
+		case QueryContractMigratable:
+			if len(path) < 2 {
+				return nil, sdkErrors.Wrap(sdkErrors.ErrUnknownRequest, "unknown data query endpoint")
+			}
+			return queryContractMigratable(ctx, path[1], keeper)
+		case QueryContractBalance:
+			if len(path) < 2 {
+				return nil, sdkErrors.Wrap(sdkErrors.ErrUnknownRequest, "unknown data query endpoint")
+			}
+			return queryContractBalance(ctx, path[1], keeper)
+		case QueryContractCodeInfo:
+			if len(path) < 2 {
+				return nil, sdkErrors.Wrap(sdkErrors.ErrUnknownRequest, "unknown data query endpoint")
+			}
+			return queryContractCodeInfo(ctx, path[1], keeper)
+		case QuerySimulateExecute:
+			return querySimulateExecute(ctx, req, keeper)
+		case QueryContractMetrics:
+			return queryContractMetrics(keeper)
+		case QueryExecutedThisBlock:
+			return queryExecutedThisBlock(keeper)
 		case QueryGetCode:
 			return queryCode(ctx, path[1], req, keeper)
+		case QueryGetCodeInfo:
+			if len(path) < 2 {
+				return nil, sdkErrors.Wrap(sdkErrors.ErrUnknownRequest, "unknown data query endpoint")
+			}
+			return queryCodeInfo(ctx, path[1], keeper)
 		case QueryListCode:
 			return queryCodeList(ctx, req, keeper)
+		case QueryListCodeByCreator:
+			if len(path) < 2 {
+				return nil, sdkErrors.Wrap(sdkErrors.ErrUnknownRequest, "unknown data query endpoint")
+			}
+			return queryCodeListByCreator(ctx, path[1], req, keeper)
+		case QueryCodeByHash:
+			if len(path) < 2 {
+				return nil, sdkErrors.Wrap(sdkErrors.ErrUnknownRequest, "unknown data query endpoint")
+			}
+			return queryCodeByHash(ctx, path[1], req, keeper)
+		case QueryContractsCount:
+			return queryCount(keeper.GetNextInstanceID(ctx) - 1)
+		case QueryCodesCount:
+			return queryCount(keeper.GetNextCodeID(ctx) - 1)
+		case QueryCodeUsage:
+			if len(path) < 2 {
+				return nil, sdkErrors.Wrap(sdkErrors.ErrUnknownRequest, "unknown data query endpoint")
+			}
+			return queryCodeUsage(ctx, path[1], keeper)
+		case QueryCodeStorageStats:
+			return queryCodeStorageStats(ctx, keeper)
+		case QueryPinnedCodes:
+			return queryPinnedCodes(ctx, req, keeper)
+		case QueryOrphanCodes:
+			return queryOrphanCodes(ctx, req, keeper)
+		case QueryCodePinned:
+			if len(path) < 2 {
+				return nil, sdkErrors.Wrap(sdkErrors.ErrUnknownRequest, "unknown data query endpoint")
+			}
+			return queryCodePinned(ctx, path[1], keeper)
+		case QueryCodeHasBytecode:
+			if len(path) < 2 {
+				return nil, sdkErrors.Wrap(sdkErrors.ErrUnknownRequest, "unknown data query endpoint")
+			}
+			return queryCodeHasBytecode(ctx, path[1], keeper)
+		case QueryCodeVerification:
+			if len(path) < 2 {
+				return nil, sdkErrors.Wrap(sdkErrors.ErrUnknownRequest, "unknown data query endpoint")
+			}
+			return queryCodeVerification(ctx, path[1], keeper)
 		default:
 			return nil, sdkErrors.Wrap(sdkErrors.ErrUnknownRequest, "unknown data query endpoint")
 		}
 	}
 }
 
-func queryContractInfo(ctx sdk.Context, bech string, req abci.RequestQuery, keeper Keeper) ([]byte, sdk.Error) {
+// validateContractAddr parses the bech32 contract address of a query path. All contract endpoints use it so that
+// a malformed address is always reported as ErrInvalidAddress with the given input as message.
+func validateContractAddr(bech string) (sdk.AccAddress, error) {
 	addr, err := sdk.AccAddressFromBech32(bech)
 	if err != nil {
-		return nil, sdk.ErrUnknownRequest(err.Error())
+		return nil, sdkErrors.Wrap(sdkErrors.ErrInvalidAddress, bech)
+	}
+	return addr, nil
+}
+
+func queryContractInfo(ctx sdk.Context, bech string, req abci.RequestQuery, keeper Keeper) ([]byte, error) {
+	addr, err := validateContractAddr(bech)
+	if err != nil {
+		return nil, err
 	}
 	info := keeper.GetContractInfo(ctx, addr)
 
 	bz, err := json.MarshalIndent(info, "", "  ")
 	if err != nil {
-		return nil, sdk.ErrInvalidAddress(err.Error())
+		return nil, sdkErrors.Wrap(sdkErrors.ErrJSONMarshal, err.Error())
 	}
 	return bz, nil
 }
 
-func queryContractList(ctx sdk.Context, req abci.RequestQuery, keeper Keeper) ([]byte, error) {
+// queryContractInfos returns the contract infos for a json array of bech32 addresses in req.Data. The result
+// has the order of the request with null for unknown contracts. At most MaxContractInfosBatch addresses
+// are accepted.
+func queryContractInfos(ctx sdk.Context, req abci.RequestQuery, keeper Keeper) ([]byte, error) {
 	var addrs []string
-	keeper.ListContractInfo(ctx, func(addr sdk.AccAddress, _ types.ContractInfo) bool {
-		addrs = append(addrs, addr.String())
+	if err := json.Unmarshal(req.Data, &addrs); err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrJSONUnmarshal, err.Error())
+	}
+	if max := keeper.GetParams(ctx).MaxContractInfosBatch; uint64(len(addrs)) > max {
+		return nil, sdkErrors.Wrapf(sdkErrors.ErrUnknownRequest, "max %d addresses", max)
+	}
+	infos := make([]*types.ContractInfo, len(addrs))
+	for i, bech := range addrs {
+		addr, err := validateContractAddr(bech)
+		if err != nil {
+			return nil, err
+		}
+		infos[i] = keeper.GetContractInfo(ctx, addr)
+	}
+
+	bz, err := json.MarshalIndent(infos, "", "  ")
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrJSONMarshal, err.Error())
+	}
+	return bz, nil
+}
+
+// ListContractsRequest is the optional payload of the list-contracts query.
+// Pages start at 1. When WithInfo is set the full contract infos are returned as well.
+type ListContractsRequest struct {
+	Page     uint64 `json:"page"`
+	Limit    uint64 `json:"limit"`
+	WithInfo bool   `json:"with_info"`
+}
+
+// ContractListResponse contains the requested page of contract addresses and the total number of contracts
+type ContractListResponse struct {
+	Contracts []string                  `json:"contracts"`
+	Infos     []ContractInfoWithAddress `json:"infos,omitempty"`
+	Total     uint64                    `json:"total"`
+}
+
+func queryContractList(ctx sdk.Context, req abci.RequestQuery, keeper Keeper) ([]byte, error) {
+	var pagination ListContractsRequest
+	if len(req.Data) != 0 {
+		if err := json.Unmarshal(req.Data, &pagination); err != nil {
+			return nil, sdkErrors.Wrap(sdkErrors.ErrJSONUnmarshal, err.Error())
+		}
+	}
+	skip, size, err := pageWindow(ctx, keeper, pagination.Page, pagination.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	res := ContractListResponse{
+		Contracts: make([]string, 0),
+		Total:     keeper.GetNextInstanceID(ctx) - 1,
+	}
+	var pos uint64
+	keeper.ListContractInfo(ctx, func(addr sdk.AccAddress, info types.ContractInfo) bool {
+		pos++
+		if pos > skip {
+			res.Contracts = append(res.Contracts, addr.String())
+			if pagination.WithInfo {
+				res.Infos = append(res.Infos, ContractInfoWithAddress{
+					Address:      addr,
+					ContractInfo: info,
+				})
+			}
+		}
+		return pos >= skip+size
+	})
+	bz, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrJSONMarshal, err.Error())
+	}
+	return bz, nil
+}
+
+// ContractInfoWithAddress adds the address (key) to the ContractInfo representation
+type ContractInfoWithAddress struct {
+	Address sdk.AccAddress `json:"address"`
+	types.ContractInfo
+}
+
+// ContractByLabelResponse is the contract found by creator and label. Labels are not unique, so
+// HasDuplicates is set when other contracts of the creator have the same label.
+type ContractByLabelResponse struct {
+	ContractInfoWithAddress
+	HasDuplicates bool `json:"has_duplicates"`
+}
+
+// queryContractByLabel returns the newest contract of the creator with the given label. Newest is the one
+// with the latest created position; contracts without or with the same position are ordered by address.
+func queryContractByLabel(ctx sdk.Context, creatorBech, label string, keeper Keeper) ([]byte, error) {
+	creator, err := sdk.AccAddressFromBech32(creatorBech)
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrInvalidAddress, creatorBech)
+	}
+
+	var (
+		found        *ContractByLabelResponse
+		matchCounter int
+	)
+	keeper.IterateContractsByLabel(ctx, creator, label, func(addr sdk.AccAddress, info types.ContractInfo) bool {
+		matchCounter++
+		if found == nil || !info.Created.LessThan(found.Created) {
+			found = &ContractByLabelResponse{
+				ContractInfoWithAddress: ContractInfoWithAddress{Address: addr, ContractInfo: info},
+			}
+		}
 		return false
 	})
-	bz, err := json.MarshalIndent(addrs, "", "  ")
+	if found == nil {
+		return nil, sdkErrors.Wrap(types.ErrNotFound, "contract")
+	}
+	found.HasDuplicates = matchCounter > 1
+
+	bz, err := json.MarshalIndent(found, "", "  ")
 	if err != nil {
 		return nil, sdkErrors.Wrap(sdkErrors.ErrJSONMarshal, err.Error())
 	}
 	return bz, nil
 }
 
-func queryContractState(ctx sdk.Context, bech, queryMethod string, req abci.RequestQuery, keeper Keeper) ([]byte, error) {
-	contractAddr, err := sdk.AccAddressFromBech32(bech)
+// ListContractsByCodeRequest is the optional pagination payload of the queries for the contracts of a code.
+// Pages start at 1.
+type ListContractsByCodeRequest struct {
+	Page  uint64 `json:"page"`
+	Limit uint64 `json:"limit"`
+}
+
+// ContractsByCodeResponse contains the requested page of the contracts of a code and the total number of them
+type ContractsByCodeResponse struct {
+	Contracts []ContractInfoWithAddress `json:"contracts"`
+	Total     uint64                    `json:"total"`
+}
+
+// queryContractListByCode returns a page of the contracts of a code in address order
+func queryContractListByCode(ctx sdk.Context, codeIDstr string, req abci.RequestQuery, keeper Keeper) ([]byte, error) {
+	codeID, err := strconv.ParseUint(codeIDstr, 10, 64)
 	if err != nil {
-		return nil, sdkErrors.Wrap(sdkErrors.ErrInvalidAddress, bech)
+		return nil, sdkErrors.Wrap(sdkErrors.ErrUnknownRequest, "invalid codeID: "+err.Error())
+	}
+	var pagination ListContractsByCodeRequest
+	if len(req.Data) != 0 {
+		if err := json.Unmarshal(req.Data, &pagination); err != nil {
+			return nil, sdkErrors.Wrap(sdkErrors.ErrJSONUnmarshal, err.Error())
+		}
+	}
+	skip, size, err := pageWindow(ctx, keeper, pagination.Page, pagination.Limit)
+	if err != nil {
+		return nil, err
 	}
 
-	var resultData []types.Model
-	switch queryMethod {
-	case QueryMethodContractStateAll:
-		for iter := keeper.GetContractState(ctx, contractAddr); iter.Valid(); iter.Next() {
-			resultData = append(resultData, types.Model{
-				Key:   string(iter.Key()),
-				Value: string(iter.Value()),
+	res := ContractsByCodeResponse{
+		Contracts: make([]ContractInfoWithAddress, 0),
+		Total:     keeper.GetContractCountByCode(ctx, codeID),
+	}
+	var pos uint64
+	keeper.IterateContractsByCode(ctx, codeID, func(addr sdk.AccAddress, info types.ContractInfo) bool {
+		pos++
+		if pos > skip {
+			res.Contracts = append(res.Contracts, ContractInfoWithAddress{
+				Address:      addr,
+				ContractInfo: info,
 			})
 		}
-		if resultData == nil {
-			resultData = make([]types.Model, 0)
+		return pos >= skip+size
+	})
+
+	bz, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrJSONMarshal, err.Error())
+	}
+	return bz, nil
+}
+
+// ContractSummary is the address, label and admin of a contract, enough to render a list of instances
+type ContractSummary struct {
+	Address sdk.AccAddress `json:"address"`
+	Label   string         `json:"label"`
+	Admin   sdk.AccAddress `json:"admin,omitempty"`
+}
+
+// ContractSummariesResponse contains the requested page of the contract summaries of a code and the total number
+// of contracts of the code
+type ContractSummariesResponse struct {
+	Contracts []ContractSummary `json:"contracts"`
+	Total     uint64            `json:"total"`
+}
+
+// queryContractsByCodeDetailed returns a page of the summaries of the contracts of a code. It reads the code to
+// contract index, so only the matching contracts are loaded. The pagination is the same as for the contracts by code.
+func queryContractsByCodeDetailed(ctx sdk.Context, codeIDstr string, req abci.RequestQuery, keeper Keeper) ([]byte, error) {
+	codeID, err := strconv.ParseUint(codeIDstr, 10, 64)
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrUnknownRequest, "invalid codeID: "+err.Error())
+	}
+	var pagination ListContractsByCodeRequest
+	if len(req.Data) != 0 {
+		if err := json.Unmarshal(req.Data, &pagination); err != nil {
+			return nil, sdkErrors.Wrap(sdkErrors.ErrJSONUnmarshal, err.Error())
+		}
+	}
+	skip, size, err := pageWindow(ctx, keeper, pagination.Page, pagination.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	res := ContractSummariesResponse{
+		Contracts: make([]ContractSummary, 0),
+		Total:     keeper.GetContractCountByCode(ctx, codeID),
+	}
+	var pos uint64
+	keeper.IterateContractsByCode(ctx, codeID, func(addr sdk.AccAddress, info types.ContractInfo) bool {
+		pos++
+		if pos > skip {
+			res.Contracts = append(res.Contracts, ContractSummary{
+				Address: addr,
+				Label:   info.Label,
+				Admin:   info.Admin,
+			})
 		}
+		return pos >= skip+size
+	})
+
+	bz, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrJSONMarshal, err.Error())
+	}
+	return bz, nil
+}
+
+// ContractsCreatedAfterRequest is the optional pagination payload of the contracts-created-after query. The page
+// starts after StartAfterKey, so the NextKey of the previous page can be passed on as is.
+type ContractsCreatedAfterRequest struct {
+	StartAfterKey []byte `json:"start_after_key"`
+	Limit         uint64 `json:"limit"`
+}
+
+// ContractsCreatedAfterResponse contains up to limit contracts in creation order. NextKey is the index key of the
+// last contract of the page and is only set when more contracts follow.
+type ContractsCreatedAfterResponse struct {
+	Contracts []ContractInfoWithAddress `json:"contracts"`
+	NextKey   []byte                    `json:"next_key,omitempty"`
+}
+
+// queryContractsCreatedAfter returns a page of the contracts created in a block higher than the given height,
+// ordered by their creation position. Contracts without a recorded creation position are not included.
+func queryContractsCreatedAfter(ctx sdk.Context, heightStr string, req abci.RequestQuery, keeper Keeper) ([]byte, error) {
+	height, err := strconv.ParseInt(heightStr, 10, 64)
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrUnknownRequest, "invalid height: "+err.Error())
+	}
+	var pagination ContractsCreatedAfterRequest
+	if len(req.Data) != 0 {
+		if err := json.Unmarshal(req.Data, &pagination); err != nil {
+			return nil, sdkErrors.Wrap(sdkErrors.ErrJSONUnmarshal, err.Error())
+		}
+	}
+	limit, err := pageLimit(ctx, keeper, pagination.Limit)
+	if err != nil {
+		return nil, err
+	}
+	pagination.Limit = limit
+
+	// the index keys start with the big endian height, no contract is created at a negative height
+	var start []byte
+	if height >= 0 {
+		start = sdk.Uint64ToBigEndian(uint64(height) + 1)
+	}
+	if len(pagination.StartAfterKey) != 0 {
+		if afterKey := append(append([]byte{}, pagination.StartAfterKey...), 0); bytes.Compare(afterKey, start) > 0 {
+			start = afterKey
+		}
+	}
+
+	res := ContractsCreatedAfterResponse{Contracts: make([]ContractInfoWithAddress, 0)}
+	var lastKey []byte
+	keeper.IterateContractsByCreated(ctx, start, func(key []byte, addr sdk.AccAddress, info types.ContractInfo) bool {
+		if uint64(len(res.Contracts)) == pagination.Limit {
+			res.NextKey = lastKey
+			return true
+		}
+		res.Contracts = append(res.Contracts, ContractInfoWithAddress{
+			Address:      addr,
+			ContractInfo: info,
+		})
+		lastKey = key
+		return false
+	})
+
+	bz, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrJSONMarshal, err.Error())
+	}
+	return bz, nil
+}
+
+func queryContractHistory(ctx sdk.Context, bech string, keeper Keeper) ([]byte, error) {
+	contractAddr, err := validateContractAddr(bech)
+	if err != nil {
+		return nil, err
+	}
+	entries := keeper.GetContractHistory(ctx, contractAddr)
+	if entries == nil {
+		// return an empty list for unknown contracts, same as the state queries
+		entries = make([]types.ContractCodeHistoryEntry, 0)
+	}
+	bz, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrJSONMarshal, err.Error())
+	}
+	return bz, nil
+}
+
+// ContractCodeIDsResponse is the code a contract was instantiated from and the code it runs now
+type ContractCodeIDsResponse struct {
+	OriginalCodeID uint64 `json:"original_code_id"`
+	CurrentCodeID  uint64 `json:"current_code_id"`
+}
+
+// queryContractCodeIDs reads the original code id from the first contract history entry. For contracts
+// imported from genesis this is the code of the genesis entry. Unknown contracts are rejected.
+func queryContractCodeIDs(ctx sdk.Context, bech string, keeper Keeper) ([]byte, error) {
+	contractAddr, err := validateContractAddr(bech)
+	if err != nil {
+		return nil, err
+	}
+	info := keeper.GetContractInfo(ctx, contractAddr)
+	if info == nil {
+		return nil, sdkErrors.Wrap(types.ErrNotFound, "contract")
+	}
+	res := ContractCodeIDsResponse{OriginalCodeID: info.CodeID, CurrentCodeID: info.CodeID}
+	if entries := keeper.GetContractHistory(ctx, contractAddr); len(entries) != 0 {
+		res.OriginalCodeID = entries[0].CodeID
+	}
+
+	bz, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrJSONMarshal, err.Error())
+	}
+	return bz, nil
+}
+
+// queryContractStateJSON returns the raw value stored under the key in req.Data as is. Values that are not
+// valid json are rejected so that clients can rely on the response being a json document.
+func queryContractStateJSON(ctx sdk.Context, bech string, req abci.RequestQuery, keeper Keeper) ([]byte, error) {
+	contractAddr, err := validateContractAddr(bech)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Data) == 0 {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrUnknownRequest, "key must not be empty")
+	}
+	if keeper.GetContractInfo(ctx, contractAddr) == nil {
+		return nil, sdkErrors.Wrap(types.ErrNotFound, "contract")
+	}
+	models := keeper.QueryRaw(ctx, contractAddr, req.Data)
+	if len(models) == 0 {
+		return nil, sdkErrors.Wrap(types.ErrNotFound, "key")
+	}
+	if !json.Valid(models[0].Value) {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrJSONUnmarshal, "stored value is not valid json")
+	}
+	return models[0].Value, nil
+}
+
+// ContractMigratableResponse tells whether the contract admin can migrate the contract. Governance can
+// migrate any contract with a MigrateContractProposal, so a contract without admin is frozen for everybody else.
+type ContractMigratableResponse struct {
+	Migratable bool           `json:"migratable"`
+	Admin      sdk.AccAddress `json:"admin,omitempty"`
+}
+
+// queryContractMigratable returns the admin of the contract. Unknown contracts are rejected.
+func queryContractMigratable(ctx sdk.Context, bech string, keeper Keeper) ([]byte, error) {
+	contractAddr, err := validateContractAddr(bech)
+	if err != nil {
+		return nil, err
+	}
+	info := keeper.GetContractInfo(ctx, contractAddr)
+	if info == nil {
+		return nil, sdkErrors.Wrap(types.ErrNotFound, "contract")
+	}
+	res := ContractMigratableResponse{Migratable: !info.Admin.Empty(), Admin: info.Admin}
+
+	bz, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrJSONMarshal, err.Error())
+	}
+	return bz, nil
+}
+
+// queryContractBalance returns the coins of the contract account. Non contract addresses are rejected.
+func queryContractBalance(ctx sdk.Context, bech string, keeper Keeper) ([]byte, error) {
+	contractAddr, err := validateContractAddr(bech)
+	if err != nil {
+		return nil, err
+	}
+	if keeper.GetContractInfo(ctx, contractAddr) == nil {
+		return nil, sdkErrors.Wrap(types.ErrNotFound, "contract")
+	}
+	balance := keeper.bankKeeper.GetCoins(ctx, contractAddr)
+	if balance == nil {
+		balance = make(sdk.Coins, 0)
+	}
+	bz, err := json.MarshalIndent(balance, "", "  ")
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrJSONMarshal, err.Error())
+	}
+	return bz, nil
+}
+
+// queryContractState returns the contract state. Baseapp builds the query context from the versioned store of
+// req.Height, so the all and raw methods read the state that was committed at that height.
+func queryContractState(ctx sdk.Context, bech, queryMethod string, req abci.RequestQuery, keeper Keeper) ([]byte, error) {
+	contractAddr, err := validateContractAddr(bech)
+	if err != nil {
+		return nil, err
+	}
+	var resultData []types.Model
+	switch queryMethod {
+	case QueryMethodContractStateAll:
+		resultData, err = collectModels(keeper.GetContractState(ctx, contractAddr), keeper.GetParams(ctx).MaxQueryResultEntries)
+	case QueryMethodContractStateAllPrefix:
+		resultData, err = collectModels(keeper.GetContractStateWithPrefix(ctx, contractAddr, req.Data), keeper.GetParams(ctx).MaxQueryResultEntries)
+	case QueryMethodContractStateAllPaginated:
+		return queryContractStatePage(ctx, contractAddr, req, keeper)
 	case QueryMethodContractStateRaw:
 		resultData = keeper.QueryRaw(ctx, contractAddr, req.Data)
+	case QueryMethodContractStateRawBatch:
+		var keys [][]byte
+		if err := json.Unmarshal(req.Data, &keys); err != nil {
+			return nil, sdkErrors.Wrap(sdkErrors.ErrJSONUnmarshal, err.Error())
+		}
+		resultData = keeper.QueryRawBatch(ctx, contractAddr, keys)
 	case QueryMethodContractStateSmart:
-		return keeper.QuerySmart(ctx, contractAddr, req.Data)
+		res, err := keeper.QuerySmart(ctx, contractAddr, req.Data)
+		if err != nil {
+			return nil, err
+		}
+		return smartQueryResultJSON(res)
 	default:
 		return nil, sdkErrors.Wrap(sdkErrors.ErrUnknownRequest, queryMethod)
 	}
+	if err != nil {
+		return nil, err
+	}
 	bz, err := json.MarshalIndent(resultData, "", "  ")
 	if err != nil {
 		return nil, sdkErrors.Wrap(sdkErrors.ErrJSONMarshal, err.Error())
@@ -128,8 +724,364 @@ func queryContractState(ctx sdk.Context, bech, queryMethod string, req abci.Requ
 	return bz, nil
 }
 
+// ContractStatePageRequest is the payload of the all-paginated state query. The page starts with the first key
+// after StartAfterKey, so the NextKey of the previous page can be passed on as is.
+type ContractStatePageRequest struct {
+	StartAfterKey []byte `json:"start_after_key"`
+	Limit         uint64 `json:"limit"`
+}
+
+// ContractStatePageResponse contains up to limit state entries in ascending key order. NextKey is the last key of
+// the page and is only set when more entries follow.
+type ContractStatePageResponse struct {
+	Models  []types.Model `json:"models"`
+	NextKey []byte        `json:"next_key,omitempty"`
+}
+
+func queryContractStatePage(ctx sdk.Context, contractAddr sdk.AccAddress, req abci.RequestQuery, keeper Keeper) ([]byte, error) {
+	var pagination ContractStatePageRequest
+	if len(req.Data) != 0 {
+		if err := json.Unmarshal(req.Data, &pagination); err != nil {
+			return nil, sdkErrors.Wrap(sdkErrors.ErrJSONUnmarshal, err.Error())
+		}
+	}
+	limit, err := pageLimit(ctx, keeper, pagination.Limit)
+	if err != nil {
+		return nil, err
+	}
+	pagination.Limit = limit
+
+	res := ContractStatePageResponse{Models: make([]types.Model, 0)}
+	iter := keeper.GetContractStateAfter(ctx, contractAddr, pagination.StartAfterKey)
+	defer iter.Close()
+	for ; iter.Valid(); iter.Next() {
+		if uint64(len(res.Models)) == pagination.Limit {
+			res.NextKey = res.Models[len(res.Models)-1].Key
+			break
+		}
+		res.Models = append(res.Models, types.Model{
+			Key:   iter.Key(),
+			Value: iter.Value(),
+		})
+	}
+
+	bz, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrJSONMarshal, err.Error())
+	}
+	return bz, nil
+}
+
+// collectModels reads all entries of the iterator and closes it. The models keep the iterator order,
+// which is ascending by key for the store iterators, so clients can diff state snapshots.
+// More than maxEntries entries fail with ErrQueryResultTooLarge before they are all held in memory.
+func collectModels(iter sdk.Iterator, maxEntries uint64) ([]types.Model, error) {
+	defer iter.Close()
+	resultData := make([]types.Model, 0)
+	for ; iter.Valid(); iter.Next() {
+		if uint64(len(resultData)) >= maxEntries {
+			return nil, sdkErrors.Wrapf(types.ErrQueryResultTooLarge, "more than %d entries, use pagination", maxEntries)
+		}
+		resultData = append(resultData, types.Model{
+			Key:   iter.Key(),
+			Value: iter.Value(),
+		})
+	}
+	return resultData, nil
+}
+
+// smartQueryResultJSON returns the contract's query result as indented json, like the other query responses.
+// Results that are not valid json themselves are encoded as a json string.
+func smartQueryResultJSON(res []byte) ([]byte, error) {
+	var bz []byte
+	var err error
+	if json.Valid(res) {
+		bz, err = json.MarshalIndent(json.RawMessage(res), "", "  ")
+	} else {
+		bz, err = json.MarshalIndent(string(res), "", "  ")
+	}
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrJSONMarshal, err.Error())
+	}
+	return bz, nil
+}
+
+// SimulateExecuteResponse is the outcome of an execution on a discarded cache context.
+// A failed execution is reported in Error, so that the gas used up to the failure is returned, too.
+// Events has all events of the execution including the ones of the dispatched messages. Contracts of the
+// api version in use can not emit own attributes, their output is returned in Log.
+type SimulateExecuteResponse struct {
+	GasUsed uint64           `json:"gas_used"`
+	Error   string           `json:"error,omitempty"`
+	Data    []byte           `json:"data,omitempty"`
+	Log     string           `json:"log,omitempty"`
+	Events  sdk.StringEvents `json:"events"`
+}
+
+// querySimulateExecute runs the MsgExecuteContract in the request data without committing any state.
+// The sender is not authenticated and the gas is limited by the smart query gas limit.
+func querySimulateExecute(ctx sdk.Context, req abci.RequestQuery, keeper Keeper) ([]byte, error) {
+	var msg types.MsgExecuteContract
+	if err := json.Unmarshal(req.Data, &msg); err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrJSONUnmarshal, err.Error())
+	}
+	// same checks and errors as for the tx
+	if err := msg.ValidateBasic(); err != nil {
+		return nil, err
+	}
+
+	bz, err := json.MarshalIndent(simulateExecute(ctx, keeper, msg), "", "  ")
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrJSONMarshal, err.Error())
+	}
+	return bz, nil
+}
+
+func simulateExecute(ctx sdk.Context, keeper Keeper, msg types.MsgExecuteContract) (res SimulateExecuteResponse) {
+	// writes go to the cache only, which is never committed
+	ctx, _ = ctx.CacheContext()
+	ctx = withSimulation(ctx).WithGasMeter(sdk.NewGasMeter(keeper.queryGasLimit)).WithEventManager(sdk.NewEventManager())
+	defer func() {
+		res.GasUsed = ctx.GasMeter().GasConsumed()
+		if r := recover(); r != nil {
+			oog, ok := r.(sdk.ErrorOutOfGas)
+			if !ok {
+				panic(r)
+			}
+			res.Error = sdkErrors.Wrap(types.ErrGasLimit, oog.Descriptor).Error()
+		}
+	}()
+
+	res.Events = make(sdk.StringEvents, 0)
+	result, err := keeper.Execute(ctx, msg.Contract, msg.Sender, msg.Msg, msg.SentFunds)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Data = result.Data
+	res.Log = result.Log
+	if events := append(result.Events, ctx.EventManager().Events()...); len(events) != 0 {
+		res.Events = sdk.StringifyEvents(events.ToABCIEvents())
+	}
+	return res
+}
+
+// queryContractMetrics returns the invocation counters of this node. They are not part of consensus and
+// differ between nodes.
+func queryContractMetrics(keeper Keeper) ([]byte, error) {
+	if keeper.metrics == nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrUnknownRequest, "contract metrics are not enabled in the node config")
+	}
+	bz, err := json.MarshalIndent(keeper.metrics.snapshot(), "", "  ")
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrJSONMarshal, err.Error())
+	}
+	return bz, nil
+}
+
+// queryExecutedThisBlock returns the contracts executed in the current block as indexed by this node. The
+// index is not part of consensus and differs between nodes.
+func queryExecutedThisBlock(keeper Keeper) ([]byte, error) {
+	if keeper.executed == nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrUnknownRequest, "executed contracts index is not enabled in the node config")
+	}
+	bz, err := json.MarshalIndent(keeper.executed.snapshot(), "", "  ")
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrJSONMarshal, err.Error())
+	}
+	return bz, nil
+}
+
+// CountResponse is returned by the count queries
+type CountResponse struct {
+	Count uint64 `json:"count"`
+}
+
+// queryCount returns the given total which is taken from the monotonic id counters so no store iteration is needed
+func queryCount(count uint64) ([]byte, error) {
+	bz, err := json.MarshalIndent(CountResponse{Count: count}, "", "  ")
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrJSONMarshal, err.Error())
+	}
+	return bz, nil
+}
+
+// queryCodeUsage returns the number of contracts of a code from the counter that the keeper maintains with the
+// code id -> contract address index
+func queryCodeUsage(ctx sdk.Context, codeIDstr string, keeper Keeper) ([]byte, error) {
+	codeID, err := strconv.ParseUint(codeIDstr, 10, 64)
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrUnknownRequest, "invalid codeID: "+err.Error())
+	}
+	if keeper.GetCodeInfo(ctx, codeID) == nil {
+		return nil, sdkErrors.Wrap(types.ErrNotFound, "code")
+	}
+	return queryCount(keeper.GetContractCountByCode(ctx, codeID))
+}
+
+// queryCodeStorageStats returns the totals that the keeper maintains when codes are stored
+func queryCodeStorageStats(ctx sdk.Context, keeper Keeper) ([]byte, error) {
+	bz, err := json.MarshalIndent(keeper.GetCodeStorageStats(ctx), "", "  ")
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrJSONMarshal, err.Error())
+	}
+	return bz, nil
+}
+
+// PinnedCodesResponse lists the pinned code IDs in ascending order
+type PinnedCodesResponse struct {
+	CodeIDs []uint64 `json:"code_ids"`
+}
+
+// queryPinnedCodes returns the requested range of the pinned code IDs
+func queryPinnedCodes(ctx sdk.Context, req abci.RequestQuery, keeper Keeper) ([]byte, error) {
+	pagination, err := codeListPage(ctx, req, keeper)
+	if err != nil {
+		return nil, err
+	}
+
+	res := PinnedCodesResponse{CodeIDs: make([]uint64, 0)}
+	var pos uint64
+	keeper.IteratePinnedCodes(ctx, func(codeID uint64) bool {
+		pos++
+		if pos <= pagination.Offset {
+			return false
+		}
+		res.CodeIDs = append(res.CodeIDs, codeID)
+		return uint64(len(res.CodeIDs)) >= pagination.Limit
+	})
+	bz, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrJSONMarshal, err.Error())
+	}
+	return bz, nil
+}
+
+// OrphanCodesResponse lists the IDs of the codes without any contract in ascending order
+type OrphanCodesResponse struct {
+	CodeIDs []uint64 `json:"code_ids"`
+}
+
+// queryOrphanCodes returns the requested range of the codes that no contract is instantiated from, according
+// to the contract count by code index. Contracts migrated away from a code count for their new code only.
+// The offset counts orphan codes, not all codes.
+func queryOrphanCodes(ctx sdk.Context, req abci.RequestQuery, keeper Keeper) ([]byte, error) {
+	pagination, err := codeListPage(ctx, req, keeper)
+	if err != nil {
+		return nil, err
+	}
+
+	res := OrphanCodesResponse{CodeIDs: make([]uint64, 0)}
+	var pos uint64
+	keeper.IterateCodeInfos(ctx, func(codeID uint64, _ types.CodeInfo) bool {
+		if keeper.GetContractCountByCode(ctx, codeID) != 0 {
+			return false
+		}
+		pos++
+		if pos <= pagination.Offset {
+			return false
+		}
+		res.CodeIDs = append(res.CodeIDs, codeID)
+		return uint64(len(res.CodeIDs)) >= pagination.Limit
+	})
+	bz, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrJSONMarshal, err.Error())
+	}
+	return bz, nil
+}
+
+// CodePinnedResponse is the pinned status of a single code
+type CodePinnedResponse struct {
+	Pinned bool `json:"pinned"`
+}
+
+// queryCodePinned returns whether the code is pinned. Unknown codes are rejected rather than reported as unpinned.
+func queryCodePinned(ctx sdk.Context, codeIDstr string, keeper Keeper) ([]byte, error) {
+	codeID, err := strconv.ParseUint(codeIDstr, 10, 64)
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrUnknownRequest, "invalid codeID: "+err.Error())
+	}
+	if keeper.GetCodeInfo(ctx, codeID) == nil {
+		return nil, sdkErrors.Wrap(types.ErrNotFound, "code")
+	}
+
+	bz, err := json.MarshalIndent(CodePinnedResponse{Pinned: keeper.IsPinnedCode(ctx, codeID)}, "", "  ")
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrJSONMarshal, err.Error())
+	}
+	return bz, nil
+}
+
+// CodeHasBytecodeResponse tells whether the wasm bytecode of a code can still be loaded
+type CodeHasBytecodeResponse struct {
+	HasBytecode bool `json:"has_bytecode"`
+}
+
+// queryCodeHasBytecode returns whether the bytecode of a known code is present. Unknown codes are rejected.
+func queryCodeHasBytecode(ctx sdk.Context, codeIDstr string, keeper Keeper) ([]byte, error) {
+	codeID, err := strconv.ParseUint(codeIDstr, 10, 64)
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrUnknownRequest, "invalid codeID: "+err.Error())
+	}
+	if keeper.GetCodeInfo(ctx, codeID) == nil {
+		return nil, sdkErrors.Wrap(types.ErrNotFound, "code")
+	}
+	_, err = keeper.GetByteCode(ctx, codeID)
+	if err != nil && !types.ErrBytecodePruned.Is(err) {
+		return nil, err
+	}
+	hasBytecode := err == nil
+
+	bz, err := json.MarshalIndent(CodeHasBytecodeResponse{HasBytecode: hasBytecode}, "", "  ")
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrJSONMarshal, err.Error())
+	}
+	return bz, nil
+}
+
+// CodeVerificationResponse has all data that is needed to verify a code against a reproducible build of its
+// source. The code hash is the sha256 hash of the uncompressed wasm bytecode.
+type CodeVerificationResponse struct {
+	CodeID   uint64       `json:"code_id"`
+	CodeHash cmn.HexBytes `json:"code_hash"`
+	Source   string       `json:"source"`
+	Builder  string       `json:"builder"`
+}
+
+// queryCodeVerification returns the code hash with the source and builder of a code. Unknown codes are rejected.
+func queryCodeVerification(ctx sdk.Context, codeIDstr string, keeper Keeper) ([]byte, error) {
+	codeID, err := strconv.ParseUint(codeIDstr, 10, 64)
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrUnknownRequest, "invalid codeID: "+err.Error())
+	}
+	info := keeper.GetCodeInfo(ctx, codeID)
+	if info == nil {
+		return nil, sdkErrors.Wrap(types.ErrNotFound, "code")
+	}
+
+	bz, err := json.MarshalIndent(CodeVerificationResponse{
+		CodeID:   codeID,
+		CodeHash: info.CodeHash,
+		Source:   info.Source,
+		Builder:  info.Builder,
+	}, "", "  ")
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrJSONMarshal, err.Error())
+	}
+	return bz, nil
+}
+
+// GetCodeRequest contains the optional settings of the code query
+type GetCodeRequest struct {
+	// Compressed requests the bytecode gzip compressed to save bandwidth
+	Compressed bool `json:"compressed"`
+}
+
 type GetCodeResponse struct {
 	Code []byte `json:"code" yaml:"code"`
+	// Compressed is true when Code is gzip compressed and must be uncompressed by the client
+	Compressed bool `json:"compressed,omitempty" yaml:"compressed,omitempty"`
 }
 
 func queryCode(ctx sdk.Context, codeIDstr string, req abci.RequestQuery, keeper Keeper) ([]byte, error) {
@@ -137,13 +1089,27 @@ func queryCode(ctx sdk.Context, codeIDstr string, req abci.RequestQuery, keeper
 	if err != nil {
 		return nil, sdkErrors.Wrap(sdkErrors.ErrUnknownRequest, "invalid codeID: "+err.Error())
 	}
+	var opts GetCodeRequest
+	if len(req.Data) != 0 {
+		if err := json.Unmarshal(req.Data, &opts); err != nil {
+			return nil, sdkErrors.Wrap(sdkErrors.ErrJSONUnmarshal, err.Error())
+		}
+	}
 
 	code, err := keeper.GetByteCode(ctx, codeID)
 	if err != nil {
 		return nil, sdkErrors.Wrap(err, "loading wasm code")
 	}
 
-	bz, err := json.MarshalIndent(GetCodeResponse{code}, "", "  ")
+	res := GetCodeResponse{Code: code}
+	if opts.Compressed {
+		if res.Code, err = compress(code); err != nil {
+			return nil, sdkErrors.Wrap(types.ErrQueryFailed, err.Error())
+		}
+		res.Compressed = true
+	}
+
+	bz, err := json.MarshalIndent(res, "", "  ")
 	if err != nil {
 		return nil, sdkErrors.Wrap(sdkErrors.ErrJSONMarshal, err.Error())
 	}
@@ -154,26 +1120,219 @@ type ListCodeResponse struct {
 	ID       uint64         `json:"id"`
 	Creator  sdk.AccAddress `json:"creator"`
 	CodeHash cmn.HexBytes   `json:"code_hash"`
+	Source   string         `json:"source"`
+	Builder  string         `json:"builder"`
+	// APIVersion is only set by the code-info and contract-code-info queries
+	APIVersion string `json:"api_version,omitempty"`
+	// Pinned is true when the code is in the pinned code index
+	Pinned bool `json:"pinned,omitempty"`
+	// Deprecated is set by the code creator or governance to discourage new instantiations
+	Deprecated bool `json:"deprecated,omitempty"`
 }
 
-func queryCodeList(ctx sdk.Context, req abci.RequestQuery, keeper Keeper) ([]byte, error) {
-	var info []ListCodeResponse
+// queryCodeInfo returns the metadata of a code without loading the wasm bytecode
+func queryCodeInfo(ctx sdk.Context, codeIDstr string, keeper Keeper) ([]byte, error) {
+	codeID, err := strconv.ParseUint(codeIDstr, 10, 64)
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrUnknownRequest, "invalid codeID: "+err.Error())
+	}
 
-	var i uint64
-	for true {
-		i++
-		res := keeper.GetCodeInfo(ctx, i)
-		if res == nil {
-			break
+	info := keeper.GetCodeInfo(ctx, codeID)
+	if info == nil {
+		return nil, sdkErrors.Wrap(types.ErrNotFound, "code")
+	}
+
+	bz, err := json.MarshalIndent(ListCodeResponse{
+		ID:         codeID,
+		Creator:    info.Creator,
+		CodeHash:   info.CodeHash,
+		Source:     info.Source,
+		Builder:    info.Builder,
+		APIVersion: info.APIVersion,
+		Pinned:     keeper.IsPinnedCode(ctx, codeID),
+		Deprecated: info.Deprecated,
+	}, "", "  ")
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrJSONMarshal, err.Error())
+	}
+	return bz, nil
+}
+
+// ContractCodeInfoResponse joins a contract with the metadata of the code it runs
+type ContractCodeInfoResponse struct {
+	Address      sdk.AccAddress     `json:"address"`
+	ContractInfo types.ContractInfo `json:"contract_info"`
+	CodeInfo     ListCodeResponse   `json:"code_info"`
+}
+
+// queryContractCodeInfo returns the contract info together with the info of its current code
+func queryContractCodeInfo(ctx sdk.Context, bech string, keeper Keeper) ([]byte, error) {
+	addr, err := validateContractAddr(bech)
+	if err != nil {
+		return nil, err
+	}
+	contractInfo := keeper.GetContractInfo(ctx, addr)
+	if contractInfo == nil {
+		return nil, sdkErrors.Wrap(types.ErrNotFound, "contract")
+	}
+	codeInfo := keeper.GetCodeInfo(ctx, contractInfo.CodeID)
+	if codeInfo == nil {
+		return nil, sdkErrors.Wrap(types.ErrNotFound, "code")
+	}
+
+	bz, err := json.MarshalIndent(ContractCodeInfoResponse{
+		Address:      addr,
+		ContractInfo: *contractInfo,
+		CodeInfo: ListCodeResponse{
+			ID:         contractInfo.CodeID,
+			Creator:    codeInfo.Creator,
+			CodeHash:   codeInfo.CodeHash,
+			Source:     codeInfo.Source,
+			Builder:    codeInfo.Builder,
+			APIVersion: codeInfo.APIVersion,
+			Pinned:     keeper.IsPinnedCode(ctx, contractInfo.CodeID),
+			Deprecated: codeInfo.Deprecated,
+		},
+	}, "", "  ")
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrJSONMarshal, err.Error())
+	}
+	return bz, nil
+}
+
+// queryCodeListByCreator returns the requested range of the codes uploaded by the creator in ascending code ID order
+func queryCodeListByCreator(ctx sdk.Context, bech string, req abci.RequestQuery, keeper Keeper) ([]byte, error) {
+	creator, err := sdk.AccAddressFromBech32(bech)
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrInvalidAddress, bech)
+	}
+	pagination, err := codeListPage(ctx, req, keeper)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]ListCodeResponse, 0)
+	var pos uint64
+	keeper.IterateCodesByCreator(ctx, creator, func(codeID uint64, info types.CodeInfo) bool {
+		pos++
+		if pos <= pagination.Offset {
+			return false
 		}
-		info = append(info, ListCodeResponse{
-			ID:       i,
-			Creator:  res.Creator,
-			CodeHash: res.CodeHash,
+		codes = append(codes, ListCodeResponse{
+			ID:         codeID,
+			Creator:    info.Creator,
+			CodeHash:   info.CodeHash,
+			Source:     info.Source,
+			Builder:    info.Builder,
+			Pinned:     keeper.IsPinnedCode(ctx, codeID),
+			Deprecated: info.Deprecated,
 		})
+		return uint64(len(codes)) >= pagination.Limit
+	})
+
+	bz, err := json.MarshalIndent(codes, "", "  ")
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrJSONMarshal, err.Error())
 	}
+	return bz, nil
+}
 
-	bz, err := json.MarshalIndent(info, "", "  ")
+// queryCodeByHash returns the requested range of the codes with the given hex encoded code hash in ascending
+// code ID order
+func queryCodeByHash(ctx sdk.Context, hexHash string, req abci.RequestQuery, keeper Keeper) ([]byte, error) {
+	codeHash, err := hex.DecodeString(hexHash)
+	if err != nil || len(codeHash) != sha256.Size {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrUnknownRequest, "invalid code hash: "+hexHash)
+	}
+	pagination, err := codeListPage(ctx, req, keeper)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]ListCodeResponse, 0)
+	var pos uint64
+	keeper.IterateCodesByHash(ctx, codeHash, func(codeID uint64, info types.CodeInfo) bool {
+		pos++
+		if pos <= pagination.Offset {
+			return false
+		}
+		codes = append(codes, ListCodeResponse{
+			ID:         codeID,
+			Creator:    info.Creator,
+			CodeHash:   info.CodeHash,
+			Source:     info.Source,
+			Builder:    info.Builder,
+			Pinned:     keeper.IsPinnedCode(ctx, codeID),
+			Deprecated: info.Deprecated,
+		})
+		return uint64(len(codes)) >= pagination.Limit
+	})
+
+	bz, err := json.MarshalIndent(codes, "", "  ")
+	if err != nil {
+		return nil, sdkErrors.Wrap(sdkErrors.ErrJSONMarshal, err.Error())
+	}
+	return bz, nil
+}
+
+// ListCodeRequest is the optional pagination payload of the code listing queries
+type ListCodeRequest struct {
+	Offset uint64 `json:"offset"`
+	Limit  uint64 `json:"limit"`
+}
+
+// codeListPage decodes the optional ListCodeRequest of a code listing query and replaces its limit with the
+// effective page size
+func codeListPage(ctx sdk.Context, req abci.RequestQuery, keeper Keeper) (ListCodeRequest, error) {
+	var pagination ListCodeRequest
+	if len(req.Data) != 0 {
+		if err := json.Unmarshal(req.Data, &pagination); err != nil {
+			return pagination, sdkErrors.Wrap(sdkErrors.ErrJSONUnmarshal, err.Error())
+		}
+	}
+	limit, err := pageLimit(ctx, keeper, pagination.Limit)
+	if err != nil {
+		return pagination, err
+	}
+	pagination.Limit = limit
+	return pagination, nil
+}
+
+// CodeListResponse contains the requested range of code infos and the total number of codes stored
+type CodeListResponse struct {
+	Codes []ListCodeResponse `json:"codes"`
+	Total uint64             `json:"total"`
+}
+
+func queryCodeList(ctx sdk.Context, req abci.RequestQuery, keeper Keeper) ([]byte, error) {
+	pagination, err := codeListPage(ctx, req, keeper)
+	if err != nil {
+		return nil, err
+	}
+
+	res := CodeListResponse{
+		Codes: make([]ListCodeResponse, 0),
+		Total: keeper.GetNextCodeID(ctx) - 1,
+	}
+	var pos uint64
+	keeper.IterateCodeInfos(ctx, func(i uint64, info types.CodeInfo) bool {
+		pos++
+		if pos <= pagination.Offset {
+			return false
+		}
+		res.Codes = append(res.Codes, ListCodeResponse{
+			ID:         i,
+			Creator:    info.Creator,
+			CodeHash:   info.CodeHash,
+			Source:     info.Source,
+			Builder:    info.Builder,
+			Pinned:     keeper.IsPinnedCode(ctx, i),
+			Deprecated: info.Deprecated,
+		})
+		return uint64(len(res.Codes)) >= pagination.Limit
+	})
+
+	bz, err := json.MarshalIndent(res, "", "  ")
 	if err != nil {
 		return nil, sdkErrors.Wrap(sdkErrors.ErrJSONMarshal, err.Error())
 	}
@@ -1,9 +1,15 @@
 package keeper
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"math"
 	"os"
+	"sort"
 	"testing"
 
 	sdk "github.com/cosmos/cosmos-sdk/types"
@@ -16,8 +22,8 @@ import (
 
 func TestQueryContractState(t *testing.T) {
 	type model struct {
-		Key   string `json:"key"`
-		Value string `json:"val"`
+		Key   []byte `json:"key"`
+		Value []byte `json:"val"`
 	}
 
 	tempDir, err := ioutil.TempDir("", "wasm")
@@ -33,7 +39,7 @@ func TestQueryContractState(t *testing.T) {
 	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
 	require.NoError(t, err)
 
-	contractID, err := keeper.Create(ctx, creator, wasmCode, "", "")
+	contractID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
 	require.NoError(t, err)
 
 	_, _, bob := keyPubAddr()
@@ -44,12 +50,13 @@ func TestQueryContractState(t *testing.T) {
 	initMsgBz, err := json.Marshal(initMsg)
 	require.NoError(t, err)
 
-	addr, err := keeper.Instantiate(ctx, contractID, creator, initMsgBz, deposit)
+	addr, err := keeper.Instantiate(ctx, contractID, creator, nil, initMsgBz, "demo contract", false, deposit)
 	require.NoError(t, err)
 
 	contractModel := []types.Model{
-		{Key: "foo", Value: "bar"},
-		{Key: string([]byte{0x0, 0x1}), Value: string([]byte{0x2, 0x3})},
+		{Key: []byte("foo"), Value: []byte("bar")},
+		{Key: []byte{0x0, 0x1}, Value: []byte{0x2, 0x3}},
+		{Key: []byte("binary"), Value: []byte{0xff, 0x0, 0xfe}},
 	}
 	keeper.setContractState(ctx, addr, contractModel)
 
@@ -64,32 +71,79 @@ func TestQueryContractState(t *testing.T) {
 		// if success and expSmartRes is not set, we parse into []model and compare
 		expModelLen      int
 		expModelContains []model
-		expErr           *sdkErrors.Error
+		// if set, the models must be returned in the order of expModelContains
+		expModelOrdered bool
+		expErr          *sdkErrors.Error
 	}{
 		"query all": {
 			srcPath:     []string{QueryGetContractState, addr.String(), QueryMethodContractStateAll},
-			expModelLen: 3,
+			expModelLen: 4,
 			expModelContains: []model{
-				{Key: "foo", Value: "bar"},
-				{Key: string([]byte{0x0, 0x1}), Value: string([]byte{0x2, 0x3})},
+				{Key: []byte("foo"), Value: []byte("bar")},
+				{Key: []byte{0x0, 0x1}, Value: []byte{0x2, 0x3}},
+				{Key: []byte("binary"), Value: []byte{0xff, 0x0, 0xfe}},
 			},
 		},
+		"query all with prefix": {
+			srcPath:          []string{QueryGetContractState, addr.String(), QueryMethodContractStateAllPrefix},
+			srcReq:           abci.RequestQuery{Data: []byte("bin")},
+			expModelLen:      1,
+			expModelContains: []model{{Key: []byte("binary"), Value: []byte{0xff, 0x0, 0xfe}}},
+		},
+		"query all with binary prefix": {
+			srcPath:          []string{QueryGetContractState, addr.String(), QueryMethodContractStateAllPrefix},
+			srcReq:           abci.RequestQuery{Data: []byte{0x0}},
+			expModelLen:      1,
+			expModelContains: []model{{Key: []byte{0x0, 0x1}, Value: []byte{0x2, 0x3}}},
+		},
+		"query all with empty prefix": {
+			srcPath:     []string{QueryGetContractState, addr.String(), QueryMethodContractStateAllPrefix},
+			expModelLen: 4,
+		},
+		"query all with unknown prefix": {
+			srcPath:     []string{QueryGetContractState, addr.String(), QueryMethodContractStateAllPrefix},
+			srcReq:      abci.RequestQuery{Data: []byte("unknown")},
+			expModelLen: 0,
+		},
 		"query raw key": {
 			srcPath:          []string{QueryGetContractState, addr.String(), QueryMethodContractStateRaw},
 			srcReq:           abci.RequestQuery{Data: []byte("foo")},
 			expModelLen:      1,
-			expModelContains: []model{{Key: "foo", Value: "bar"}},
+			expModelContains: []model{{Key: []byte("foo"), Value: []byte("bar")}},
 		},
 		"query raw binary key": {
 			srcPath:          []string{QueryGetContractState, addr.String(), QueryMethodContractStateRaw},
 			srcReq:           abci.RequestQuery{Data: []byte{0x0, 0x1}},
 			expModelLen:      1,
-			expModelContains: []model{{Key: string([]byte{0x0, 0x1}), Value: string([]byte{0x2, 0x3})}},
+			expModelContains: []model{{Key: []byte{0x0, 0x1}, Value: []byte{0x2, 0x3}}},
+		},
+		"query raw binary value": {
+			srcPath:          []string{QueryGetContractState, addr.String(), QueryMethodContractStateRaw},
+			srcReq:           abci.RequestQuery{Data: []byte("binary")},
+			expModelLen:      1,
+			expModelContains: []model{{Key: []byte("binary"), Value: []byte{0xff, 0x0, 0xfe}}},
+		},
+		"query raw batch": {
+			srcPath:         []string{QueryGetContractState, addr.String(), QueryMethodContractStateRawBatch},
+			srcReq:          abci.RequestQuery{Data: []byte(`["Zm9v","AAE=","dW5rbm93bg==","YmluYXJ5"]`)},
+			expModelLen:     4,
+			expModelOrdered: true,
+			expModelContains: []model{
+				{Key: []byte("foo"), Value: []byte("bar")},
+				{Key: []byte{0x0, 0x1}, Value: []byte{0x2, 0x3}},
+				{Key: []byte("unknown")},
+				{Key: []byte("binary"), Value: []byte{0xff, 0x0, 0xfe}},
+			},
+		},
+		"query raw batch with invalid json": {
+			srcPath: []string{QueryGetContractState, addr.String(), QueryMethodContractStateRawBatch},
+			srcReq:  abci.RequestQuery{Data: []byte("foo")},
+			expErr:  sdkErrors.ErrJSONUnmarshal,
 		},
 		"query smart": {
 			srcPath:     []string{QueryGetContractState, addr.String(), QueryMethodContractStateSmart},
 			srcReq:      abci.RequestQuery{Data: []byte(`{"verifier":{}}`)},
-			expSmartRes: anyAddr.String(),
+			expSmartRes: fmt.Sprintf("%q", anyAddr.String()),
 		},
 		"query smart invalid request": {
 			srcPath: []string{QueryGetContractState, addr.String(), QueryMethodContractStateSmart},
@@ -128,6 +182,7 @@ func TestQueryContractState(t *testing.T) {
 
 			// if smart query, check custom response
 			if spec.expSmartRes != "" {
+				require.True(t, json.Valid(binResult), string(binResult))
 				require.Equal(t, spec.expSmartRes, string(binResult))
 				return
 			}
@@ -143,6 +198,2168 @@ func TestQueryContractState(t *testing.T) {
 			for _, v := range spec.expModelContains {
 				assert.Contains(t, r, v)
 			}
+			if spec.expModelOrdered {
+				assert.Equal(t, spec.expModelContains, r)
+			}
+		})
+	}
+}
+
+func TestQueryContractStateSortedByKey(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	codeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: creator, Beneficiary: bob})
+	require.NoError(t, err)
+	addr, err := keeper.Instantiate(ctx, codeID, creator, nil, initMsgBz, "demo contract", false, nil)
+	require.NoError(t, err)
+
+	// stored out of order
+	keeper.setContractState(ctx, addr, []types.Model{
+		{Key: []byte("b:2"), Value: []byte("x")},
+		{Key: []byte{0xff}, Value: []byte("x")},
+		{Key: []byte("b:10"), Value: []byte("x")},
+		{Key: []byte("a"), Value: []byte("x")},
+		{Key: []byte{0x0}, Value: []byte("x")},
+		{Key: []byte("b:1"), Value: []byte("x")},
+	})
+
+	q := newQuerier(keeper)
+	specs := map[string]struct {
+		srcMethod string
+		srcData   []byte
+		expLen    int
+	}{
+		"all": {
+			srcMethod: QueryMethodContractStateAll,
+			// plus the contract's own config entry
+			expLen: 7,
+		},
+		"all with prefix": {
+			srcMethod: QueryMethodContractStateAllPrefix,
+			srcData:   []byte("b:"),
+			expLen:    3,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			bz, err := q(ctx, []string{QueryGetContractState, addr.String(), spec.srcMethod}, abci.RequestQuery{Data: spec.srcData})
+			require.NoError(t, err)
+			var res []types.Model
+			require.NoError(t, json.Unmarshal(bz, &res))
+			require.Len(t, res, spec.expLen)
+			assert.True(t, sort.SliceIsSorted(res, func(i, j int) bool {
+				return bytes.Compare(res[i].Key, res[j].Key) < 0
+			}), "not sorted: %v", res)
+		})
+	}
+}
+
+func TestQueryContractStateMaxResultEntries(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	codeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: creator, Beneficiary: bob})
+	require.NoError(t, err)
+	addr, err := keeper.Instantiate(ctx, codeID, creator, nil, initMsgBz, "demo contract", false, nil)
+	require.NoError(t, err)
+	// plus the contract's own config entry
+	keeper.setContractState(ctx, addr, []types.Model{
+		{Key: []byte("a:1"), Value: []byte("x")},
+		{Key: []byte("a:2"), Value: []byte("x")},
+		{Key: []byte("b:1"), Value: []byte("x")},
+	})
+
+	q := newQuerier(keeper)
+	specs := map[string]struct {
+		srcMethod  string
+		srcData    []byte
+		srcMaxSize uint64
+		expLen     int
+		expErr     *sdkErrors.Error
+	}{
+		"all within limit": {
+			srcMethod:  QueryMethodContractStateAll,
+			srcMaxSize: 4,
+			expLen:     4,
+		},
+		"all above limit": {
+			srcMethod:  QueryMethodContractStateAll,
+			srcMaxSize: 3,
+			expErr:     types.ErrQueryResultTooLarge,
+		},
+		"prefix within limit": {
+			srcMethod:  QueryMethodContractStateAllPrefix,
+			srcData:    []byte("a:"),
+			srcMaxSize: 2,
+			expLen:     2,
+		},
+		"prefix above limit": {
+			srcMethod:  QueryMethodContractStateAllPrefix,
+			srcData:    []byte("a:"),
+			srcMaxSize: 1,
+			expErr:     types.ErrQueryResultTooLarge,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			ctx, _ := ctx.CacheContext()
+			params := keeper.GetParams(ctx)
+			params.MaxQueryResultEntries = spec.srcMaxSize
+			keeper.SetParams(ctx, params)
+
+			bz, err := q(ctx, []string{QueryGetContractState, addr.String(), spec.srcMethod}, abci.RequestQuery{Data: spec.srcData})
+			if spec.expErr != nil {
+				require.True(t, spec.expErr.Is(err), "got %+v", err)
+				assert.Contains(t, err.Error(), "use pagination")
+				return
+			}
+			require.NoError(t, err)
+			var res []types.Model
+			require.NoError(t, json.Unmarshal(bz, &res))
+			assert.Len(t, res, spec.expLen)
+		})
+	}
+}
+
+func TestQueryContractStatePaginated(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	codeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: creator, Beneficiary: bob})
+	require.NoError(t, err)
+	addr, err := keeper.Instantiate(ctx, codeID, creator, nil, initMsgBz, "demo contract", false, nil)
+	require.NoError(t, err)
+
+	keeper.setContractState(ctx, addr, []types.Model{
+		{Key: []byte("z"), Value: []byte("1")},
+		{Key: []byte("z\x00"), Value: []byte("2")},
+		{Key: []byte("zb"), Value: []byte("3")},
+		{Key: []byte("zc"), Value: []byte("4")},
+	})
+	// plus the contract's own config entry, which is sorted before the keys above
+	const totalEntries = 5
+
+	q := newQuerier(keeper)
+	queryPage := func(req ContractStatePageRequest) ContractStatePageResponse {
+		reqBz, err := json.Marshal(req)
+		require.NoError(t, err)
+		bz, err := q(ctx, []string{QueryGetContractState, addr.String(), QueryMethodContractStateAllPaginated}, abci.RequestQuery{Data: reqBz})
+		require.NoError(t, err)
+		var res ContractStatePageResponse
+		require.NoError(t, json.Unmarshal(bz, &res))
+		return res
+	}
+
+	specs := map[string]struct {
+		srcReq     ContractStatePageRequest
+		expKeys    [][]byte
+		expNextKey []byte
+	}{
+		"page with next key": {
+			srcReq:     ContractStatePageRequest{StartAfterKey: []byte("y"), Limit: 2},
+			expKeys:    [][]byte{[]byte("z"), []byte("z\x00")},
+			expNextKey: []byte("z\x00"),
+		},
+		"start after key with zero byte suffix": {
+			srcReq:     ContractStatePageRequest{StartAfterKey: []byte("z"), Limit: 1},
+			expKeys:    [][]byte{[]byte("z\x00")},
+			expNextKey: []byte("z\x00"),
+		},
+		"start after unknown key": {
+			srcReq:     ContractStatePageRequest{StartAfterKey: []byte("za"), Limit: 1},
+			expKeys:    [][]byte{[]byte("zb")},
+			expNextKey: []byte("zb"),
+		},
+		"last page": {
+			srcReq:  ContractStatePageRequest{StartAfterKey: []byte("zb"), Limit: 1},
+			expKeys: [][]byte{[]byte("zc")},
+		},
+		"after last key": {
+			srcReq:  ContractStatePageRequest{StartAfterKey: []byte("zc"), Limit: 1},
+			expKeys: [][]byte{},
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			res := queryPage(spec.srcReq)
+			gotKeys := make([][]byte, len(res.Models))
+			for i, m := range res.Models {
+				gotKeys[i] = m.Key
+			}
+			assert.Equal(t, spec.expKeys, gotKeys)
+			assert.Equal(t, spec.expNextKey, res.NextKey)
+		})
+	}
+
+	t.Run("walk all pages", func(t *testing.T) {
+		var all []types.Model
+		req := ContractStatePageRequest{Limit: 2}
+		for {
+			res := queryPage(req)
+			all = append(all, res.Models...)
+			if res.NextKey == nil {
+				break
+			}
+			req.StartAfterKey = res.NextKey
+		}
+		assert.Len(t, all, totalEntries)
+		expModels, err := collectModels(keeper.GetContractState(ctx, addr), uint64(totalEntries))
+		require.NoError(t, err)
+		assert.Equal(t, expModels, all)
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		_, err := q(ctx, []string{QueryGetContractState, addr.String(), QueryMethodContractStateAllPaginated}, abci.RequestQuery{Data: []byte("foo")})
+		assert.True(t, sdkErrors.ErrJSONUnmarshal.Is(err), "got %+v", err)
+	})
+}
+
+func TestListCode(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+		require.NoError(t, err)
+	}
+	// remove code 2 to create a gap in the code ids
+	ctx.KVStore(keeper.storeKey).Delete(types.GetCodeKey(2))
+	require.NoError(t, keeper.PinCode(ctx, 3))
+	require.NoError(t, keeper.DeprecateCode(ctx, 1, creator, true))
+
+	q := newQuerier(keeper)
+	bz, err := q(ctx, []string{QueryListCode}, abci.RequestQuery{})
+	require.NoError(t, err)
+
+	var res CodeListResponse
+	require.NoError(t, json.Unmarshal(bz, &res))
+	require.Len(t, res.Codes, 2)
+	assert.Equal(t, uint64(1), res.Codes[0].ID)
+	assert.Equal(t, uint64(3), res.Codes[1].ID)
+	assert.Equal(t, creator, res.Codes[1].Creator)
+	assert.False(t, res.Codes[0].Pinned)
+	assert.True(t, res.Codes[1].Pinned)
+	assert.True(t, res.Codes[0].Deprecated)
+	assert.False(t, res.Codes[1].Deprecated)
+
+	bz, err = q(ctx, []string{QueryGetCodeInfo, "1"}, abci.RequestQuery{})
+	require.NoError(t, err)
+	var info ListCodeResponse
+	require.NoError(t, json.Unmarshal(bz, &info))
+	assert.True(t, info.Deprecated)
+
+	// and with pagination
+	bz, err = q(ctx, []string{QueryListCode}, abci.RequestQuery{Data: []byte(`{"offset":1,"limit":1}`)})
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(bz, &res))
+	require.Len(t, res.Codes, 1)
+	assert.Equal(t, uint64(3), res.Codes[0].ID)
+}
+
+func TestListContractsPagination(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+	anyAddr := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	codeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: anyAddr, Beneficiary: bob})
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		_, err := keeper.Instantiate(ctx, codeID, creator, nil, initMsgBz, "demo contract", false, nil)
+		require.NoError(t, err)
+	}
+
+	q := newQuerier(keeper)
+	query := func(req string) ContractListResponse {
+		bz, err := q(ctx, []string{QueryListContracts}, abci.RequestQuery{Data: []byte(req)})
+		require.NoError(t, err)
+		var res ContractListResponse
+		require.NoError(t, json.Unmarshal(bz, &res))
+		return res
+	}
+
+	all := query("")
+	require.Len(t, all.Contracts, 5)
+	assert.Equal(t, uint64(5), all.Total)
+
+	specs := map[string]struct {
+		req     string
+		expAddr []string
+	}{
+		"first page":   {req: `{"page":1,"limit":2}`, expAddr: all.Contracts[0:2]},
+		"second page":  {req: `{"page":2,"limit":2}`, expAddr: all.Contracts[2:4]},
+		"last page":    {req: `{"page":3,"limit":2}`, expAddr: all.Contracts[4:]},
+		"out of range": {req: `{"page":4,"limit":2}`, expAddr: []string{}},
+		"default page": {req: `{"limit":3}`, expAddr: all.Contracts[0:3]},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			res := query(spec.req)
+			assert.Equal(t, spec.expAddr, res.Contracts)
+			assert.Equal(t, uint64(5), res.Total)
+			assert.Nil(t, res.Infos)
+		})
+	}
+
+	// with full contract infos
+	res := query(`{"page":1,"limit":2,"with_info":true}`)
+	require.Len(t, res.Infos, 2)
+	for i, info := range res.Infos {
+		assert.Equal(t, res.Contracts[i], info.Address.String())
+		assert.Equal(t, codeID, info.CodeID)
+		assert.Equal(t, creator, info.Creator)
+	}
+}
+
+func TestQueryPageLimit(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	codeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: creator, Beneficiary: bob})
+	require.NoError(t, err)
+	addr, err := keeper.Instantiate(ctx, codeID, creator, nil, initMsgBz, "demo contract", false, nil)
+	require.NoError(t, err)
+	keeper.setContractState(ctx, addr, []types.Model{
+		{Key: []byte("a"), Value: []byte("1")},
+		{Key: []byte("b"), Value: []byte("2")},
+		{Key: []byte("c"), Value: []byte("3")},
+	})
+
+	params := keeper.GetParams(ctx)
+	params.MaxQueryResultEntries = 2
+	keeper.SetParams(ctx, params)
+
+	statePath := []string{QueryGetContractState, addr.String(), QueryMethodContractStateAllPaginated}
+	specs := map[string]struct {
+		srcPath []string
+		srcReq  string
+		expErr  *sdkErrors.Error
+	}{
+		"state page at max": {
+			srcPath: statePath,
+			srcReq:  `{"limit":2}`,
+		},
+		"state page above max": {
+			srcPath: statePath,
+			srcReq:  `{"limit":3}`,
+			expErr:  types.ErrQueryResultTooLarge,
+		},
+		"state page with max uint64 limit": {
+			srcPath: statePath,
+			srcReq:  `{"limit":18446744073709551615}`,
+			expErr:  types.ErrQueryResultTooLarge,
+		},
+		"code list above max": {
+			srcPath: []string{QueryListCode},
+			srcReq:  `{"limit":3}`,
+			expErr:  types.ErrQueryResultTooLarge,
+		},
+		"contract list above max": {
+			srcPath: []string{QueryListContracts},
+			srcReq:  `{"limit":3}`,
+			expErr:  types.ErrQueryResultTooLarge,
+		},
+		"contract list with overflowing page": {
+			srcPath: []string{QueryListContracts},
+			srcReq:  `{"page":18446744073709551615,"limit":2}`,
+			expErr:  sdkErrors.ErrUnknownRequest,
+		},
+	}
+	q := newQuerier(keeper)
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			_, err := q(ctx, spec.srcPath, abci.RequestQuery{Data: []byte(spec.srcReq)})
+			require.True(t, spec.expErr.Is(err), "got %+v", err)
+		})
+	}
+
+	// the default page size is clamped to the max
+	bz, err := q(ctx, statePath, abci.RequestQuery{})
+	require.NoError(t, err)
+	var res ContractStatePageResponse
+	require.NoError(t, json.Unmarshal(bz, &res))
+	assert.Len(t, res.Models, 2)
+	assert.NotNil(t, res.NextKey)
+}
+
+func TestQueryContractListByCode(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+	anyAddr := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	codeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+	otherCodeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: anyAddr, Beneficiary: bob})
+	require.NoError(t, err)
+
+	var expAddrs []sdk.AccAddress
+	for i := 0; i < 3; i++ {
+		addr, err := keeper.Instantiate(ctx, codeID, creator, nil, initMsgBz, "demo contract", false, nil)
+		require.NoError(t, err)
+		expAddrs = append(expAddrs, addr)
+	}
+	_, err = keeper.Instantiate(ctx, otherCodeID, creator, nil, initMsgBz, "demo contract", false, nil)
+	require.NoError(t, err)
+
+	q := newQuerier(keeper)
+	bz, err := q(ctx, []string{QueryListContractsByCode, fmt.Sprintf("%d", codeID)}, abci.RequestQuery{})
+	require.NoError(t, err)
+
+	var res ContractsByCodeResponse
+	require.NoError(t, json.Unmarshal(bz, &res))
+	require.Len(t, res.Contracts, 3)
+	assert.Equal(t, uint64(3), res.Total)
+	for _, r := range res.Contracts {
+		assert.Contains(t, expAddrs, r.Address)
+		assert.Equal(t, codeID, r.CodeID)
+		assert.Equal(t, creator, r.Creator)
+	}
+	allContracts := res.Contracts
+
+	// unknown code returns empty list
+	bz, err = q(ctx, []string{QueryListContractsByCode, "9999"}, abci.RequestQuery{})
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(bz, &res))
+	assert.Len(t, res.Contracts, 0)
+	assert.Equal(t, uint64(0), res.Total)
+
+	specs := map[string]struct {
+		srcReq       ListContractsByCodeRequest
+		expContracts []ContractInfoWithAddress
+		expErr       *sdkErrors.Error
+	}{
+		"first page": {
+			srcReq:       ListContractsByCodeRequest{Page: 1, Limit: 2},
+			expContracts: allContracts[:2],
+		},
+		"last page": {
+			srcReq:       ListContractsByCodeRequest{Page: 2, Limit: 2},
+			expContracts: allContracts[2:],
+		},
+		"page after last": {
+			srcReq:       ListContractsByCodeRequest{Page: 3, Limit: 2},
+			expContracts: []ContractInfoWithAddress{},
+		},
+		"limit above max entries": {
+			srcReq: ListContractsByCodeRequest{Limit: keeper.GetParams(ctx).MaxQueryResultEntries + 1},
+			expErr: types.ErrQueryResultTooLarge,
+		},
+		"page out of range": {
+			srcReq: ListContractsByCodeRequest{Page: math.MaxUint64, Limit: 2},
+			expErr: sdkErrors.ErrUnknownRequest,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			reqBz, err := json.Marshal(spec.srcReq)
+			require.NoError(t, err)
+			bz, err := q(ctx, []string{QueryListContractsByCode, fmt.Sprintf("%d", codeID)}, abci.RequestQuery{Data: reqBz})
+			require.True(t, spec.expErr.Is(err), "got %+v", err)
+			if spec.expErr != nil {
+				return
+			}
+			var res ContractsByCodeResponse
+			require.NoError(t, json.Unmarshal(bz, &res))
+			assert.Equal(t, spec.expContracts, res.Contracts)
+			assert.Equal(t, uint64(3), res.Total)
+		})
+	}
+}
+
+func TestQueryContractsByCodeDetailed(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+	anyAddr := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	codeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+	otherCodeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: anyAddr, Beneficiary: bob})
+	require.NoError(t, err)
+
+	withAdmin, err := keeper.Instantiate(ctx, codeID, creator, anyAddr, initMsgBz, "with admin", false, nil)
+	require.NoError(t, err)
+	withoutAdmin, err := keeper.Instantiate(ctx, codeID, creator, nil, initMsgBz, "without admin", false, nil)
+	require.NoError(t, err)
+	_, err = keeper.Instantiate(ctx, otherCodeID, creator, nil, initMsgBz, "other code", false, nil)
+	require.NoError(t, err)
+
+	summaries := []ContractSummary{
+		{Address: withAdmin, Label: "with admin", Admin: anyAddr},
+		{Address: withoutAdmin, Label: "without admin"},
+	}
+	// in address order
+	sort.Slice(summaries, func(i, j int) bool {
+		return bytes.Compare(summaries[i].Address, summaries[j].Address) < 0
+	})
+
+	q := newQuerier(keeper)
+	specs := map[string]struct {
+		srcPath  []string
+		srcReq   *ListContractsByCodeRequest
+		expRes   []ContractSummary
+		expTotal uint64
+		expErr   *sdkErrors.Error
+	}{
+		"contracts of code": {
+			srcPath:  []string{QueryContractsByCodeDetail, fmt.Sprintf("%d", codeID)},
+			expRes:   summaries,
+			expTotal: 2,
+		},
+		"first page": {
+			srcPath:  []string{QueryContractsByCodeDetail, fmt.Sprintf("%d", codeID)},
+			srcReq:   &ListContractsByCodeRequest{Page: 1, Limit: 1},
+			expRes:   summaries[:1],
+			expTotal: 2,
+		},
+		"second page": {
+			srcPath:  []string{QueryContractsByCodeDetail, fmt.Sprintf("%d", codeID)},
+			srcReq:   &ListContractsByCodeRequest{Page: 2, Limit: 1},
+			expRes:   summaries[1:],
+			expTotal: 2,
+		},
+		"limit above max entries": {
+			srcPath: []string{QueryContractsByCodeDetail, fmt.Sprintf("%d", codeID)},
+			srcReq:  &ListContractsByCodeRequest{Limit: keeper.GetParams(ctx).MaxQueryResultEntries + 1},
+			expErr:  types.ErrQueryResultTooLarge,
+		},
+		"unknown code": {
+			srcPath: []string{QueryContractsByCodeDetail, "9999"},
+			expRes:  []ContractSummary{},
+		},
+		"invalid code id": {
+			srcPath: []string{QueryContractsByCodeDetail, "foo"},
+			expErr:  sdkErrors.ErrUnknownRequest,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			var reqBz []byte
+			if spec.srcReq != nil {
+				var err error
+				reqBz, err = json.Marshal(spec.srcReq)
+				require.NoError(t, err)
+			}
+			bz, err := q(ctx, spec.srcPath, abci.RequestQuery{Data: reqBz})
+			require.True(t, spec.expErr.Is(err), "got %+v", err)
+			if spec.expErr != nil {
+				return
+			}
+			var res ContractSummariesResponse
+			require.NoError(t, json.Unmarshal(bz, &res))
+			assert.Equal(t, spec.expRes, res.Contracts)
+			assert.Equal(t, spec.expTotal, res.Total)
+		})
+	}
+}
+
+func TestQueryContractsCreatedAfter(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	codeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: creator, Beneficiary: bob})
+	require.NoError(t, err)
+
+	blockGasMeter := sdk.NewInfiniteGasMeter()
+	instantiateAt := func(height int64) sdk.AccAddress {
+		// the block gas consumed gives the position within the block
+		blockGasMeter.ConsumeGas(1, "testing")
+		addr, err := keeper.Instantiate(ctx.WithBlockHeight(height).WithBlockGasMeter(blockGasMeter), codeID, creator, nil, initMsgBz, "demo contract", false, nil)
+		require.NoError(t, err)
+		return addr
+	}
+	// instantiated out of address order
+	addrs := []sdk.AccAddress{instantiateAt(1), instantiateAt(3), instantiateAt(2), instantiateAt(2)}
+
+	q := newQuerier(keeper)
+	queryPage := func(height string, pagination ContractsCreatedAfterRequest) (ContractsCreatedAfterResponse, error) {
+		reqBz, err := json.Marshal(pagination)
+		require.NoError(t, err)
+		var res ContractsCreatedAfterResponse
+		bz, err := q(ctx, []string{QueryContractsCreatedAfter, height}, abci.RequestQuery{Data: reqBz})
+		if err != nil {
+			return res, err
+		}
+		require.NoError(t, json.Unmarshal(bz, &res))
+		return res, nil
+	}
+	addressesOf := func(res ContractsCreatedAfterResponse) []sdk.AccAddress {
+		addrs := make([]sdk.AccAddress, len(res.Contracts))
+		for i, c := range res.Contracts {
+			addrs[i] = c.Address
+		}
+		return addrs
+	}
+
+	specs := map[string]struct {
+		srcHeight    string
+		srcLimit     uint64
+		expAddresses []sdk.AccAddress
+		expNextKey   bool
+		expErr       *sdkErrors.Error
+	}{
+		"all": {
+			srcHeight:    "0",
+			expAddresses: []sdk.AccAddress{addrs[0], addrs[2], addrs[3], addrs[1]},
+		},
+		"negative height": {
+			srcHeight:    "-1",
+			expAddresses: []sdk.AccAddress{addrs[0], addrs[2], addrs[3], addrs[1]},
+		},
+		"after height": {
+			srcHeight:    "1",
+			expAddresses: []sdk.AccAddress{addrs[2], addrs[3], addrs[1]},
+		},
+		"after last height": {
+			srcHeight:    "3",
+			expAddresses: []sdk.AccAddress{},
+		},
+		"first page": {
+			srcHeight:    "0",
+			srcLimit:     2,
+			expAddresses: []sdk.AccAddress{addrs[0], addrs[2]},
+			expNextKey:   true,
+		},
+		"limit above max entries": {
+			srcHeight: "0",
+			srcLimit:  keeper.GetParams(ctx).MaxQueryResultEntries + 1,
+			expErr:    types.ErrQueryResultTooLarge,
+		},
+		"invalid height": {
+			srcHeight: "foo",
+			expErr:    sdkErrors.ErrUnknownRequest,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			res, err := queryPage(spec.srcHeight, ContractsCreatedAfterRequest{Limit: spec.srcLimit})
+			require.True(t, spec.expErr.Is(err), "got %+v", err)
+			if spec.expErr != nil {
+				return
+			}
+			assert.Equal(t, spec.expAddresses, addressesOf(res))
+			assert.Equal(t, spec.expNextKey, len(res.NextKey) != 0)
+		})
+	}
+
+	// page by page gives the same order
+	var paged []sdk.AccAddress
+	pagination := ContractsCreatedAfterRequest{Limit: 1}
+	for i := 0; i < len(addrs); i++ {
+		res, err := queryPage("1", pagination)
+		require.NoError(t, err)
+		paged = append(paged, addressesOf(res)...)
+		if len(res.NextKey) == 0 {
+			break
+		}
+		pagination.StartAfterKey = res.NextKey
+	}
+	assert.Equal(t, []sdk.AccAddress{addrs[2], addrs[3], addrs[1]}, paged)
+}
+
+func TestQueryCounts(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+	anyAddr := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	q := newQuerier(keeper)
+	queryCount := func(path string) uint64 {
+		bz, err := q(ctx, []string{path}, abci.RequestQuery{})
+		require.NoError(t, err)
+		var res CountResponse
+		require.NoError(t, json.Unmarshal(bz, &res))
+		return res.Count
+	}
+	assert.Equal(t, uint64(0), queryCount(QueryCodesCount))
+	assert.Equal(t, uint64(0), queryCount(QueryContractsCount))
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	var codeID uint64
+	for i := 0; i < 2; i++ {
+		codeID, err = keeper.Create(ctx, creator, wasmCode, "", "", nil)
+		require.NoError(t, err)
+	}
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: anyAddr, Beneficiary: bob})
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err := keeper.Instantiate(ctx, codeID, creator, nil, initMsgBz, "demo contract", false, nil)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, uint64(2), queryCount(QueryCodesCount))
+	assert.Equal(t, uint64(3), queryCount(QueryContractsCount))
+}
+
+func TestQueryCodeStorageStats(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	q := newQuerier(keeper)
+	queryStats := func() types.CodeStorageStats {
+		bz, err := q(ctx, []string{QueryCodeStorageStats}, abci.RequestQuery{})
+		require.NoError(t, err)
+		var res types.CodeStorageStats
+		require.NoError(t, json.Unmarshal(bz, &res))
+		return res
+	}
+	assert.Equal(t, types.CodeStorageStats{}, queryStats())
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	maskCode, err := ioutil.ReadFile("./testdata/mask.wasm")
+	require.NoError(t, err)
+	gzippedCode, err := ioutil.ReadFile("./testdata/contract.wasm.gzip")
+	require.NoError(t, err)
+
+	_, err = keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+	_, err = keeper.Create(ctx, creator, maskCode, "", "", nil)
+	require.NoError(t, err)
+	// gzipped uploads count with their uncompressed size
+	_, err = keeper.Create(ctx, creator, gzippedCode, "", "", nil)
+	require.NoError(t, err)
+	// reused codes are not stored again
+	_, created, err := keeper.CreateOrReuse(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+	require.False(t, created)
+
+	exp := types.CodeStorageStats{CodeCount: 3, TotalBytes: uint64(2*len(wasmCode) + len(maskCode))}
+	assert.Equal(t, exp, queryStats())
+	assert.Equal(t, exp, keeper.GetCodeStorageStats(ctx))
+}
+
+func TestQueryContractByLabel(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+	otherCreator := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	codeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: creator, Beneficiary: bob})
+	require.NoError(t, err)
+
+	_, err = keeper.Instantiate(ctx.WithBlockHeight(1), codeID, creator, nil, initMsgBz, "my/label", false, nil)
+	require.NoError(t, err)
+	newerAddr, err := keeper.Instantiate(ctx.WithBlockHeight(2), codeID, creator, nil, initMsgBz, "my/label", false, nil)
+	require.NoError(t, err)
+	uniqueAddr, err := keeper.Instantiate(ctx.WithBlockHeight(1), codeID, creator, nil, initMsgBz, "unique", false, nil)
+	require.NoError(t, err)
+	_, err = keeper.Instantiate(ctx.WithBlockHeight(3), codeID, otherCreator, nil, initMsgBz, "my/label", false, nil)
+	require.NoError(t, err)
+	// the label is the prefix of another label
+	_, err = keeper.Instantiate(ctx.WithBlockHeight(4), codeID, creator, nil, initMsgBz, "my/label/2", false, nil)
+	require.NoError(t, err)
+
+	// same height, the contract that is instantiated first has the later tx position
+	atTxIndex := func(index uint64) sdk.Context {
+		meter := sdk.NewGasMeter(1000)
+		meter.ConsumeGas(index, "testing")
+		return ctx.WithBlockHeight(5).WithBlockGasMeter(meter)
+	}
+	laterTxAddr, err := keeper.Instantiate(atTxIndex(100), codeID, creator, nil, initMsgBz, "same height", false, nil)
+	require.NoError(t, err)
+	_, err = keeper.Instantiate(atTxIndex(10), codeID, creator, nil, initMsgBz, "same height", false, nil)
+	require.NoError(t, err)
+
+	q := newQuerier(keeper)
+	specs := map[string]struct {
+		srcPath       []string
+		expAddr       sdk.AccAddress
+		expDuplicates bool
+		expErr        *sdkErrors.Error
+	}{
+		"newest with duplicates": {
+			// the label is split by the path separator
+			srcPath:       []string{QueryContractByLabel, creator.String(), "my", "label"},
+			expAddr:       newerAddr,
+			expDuplicates: true,
+		},
+		"newest by tx position": {
+			srcPath:       []string{QueryContractByLabel, creator.String(), "same height"},
+			expAddr:       laterTxAddr,
+			expDuplicates: true,
+		},
+		"unique label": {
+			srcPath: []string{QueryContractByLabel, creator.String(), "unique"},
+			expAddr: uniqueAddr,
+		},
+		"unknown label": {
+			srcPath: []string{QueryContractByLabel, creator.String(), "foo"},
+			expErr:  types.ErrNotFound,
+		},
+		"label of other creator": {
+			srcPath: []string{QueryContractByLabel, otherCreator.String(), "unique"},
+			expErr:  types.ErrNotFound,
+		},
+		"invalid creator": {
+			srcPath: []string{QueryContractByLabel, "foo", "unique"},
+			expErr:  sdkErrors.ErrInvalidAddress,
+		},
+		"without label": {
+			srcPath: []string{QueryContractByLabel, creator.String()},
+			expErr:  sdkErrors.ErrUnknownRequest,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			bz, err := q(ctx, spec.srcPath, abci.RequestQuery{})
+			require.True(t, spec.expErr.Is(err), "got %+v", err)
+			if spec.expErr != nil {
+				return
+			}
+			var res ContractByLabelResponse
+			require.NoError(t, json.Unmarshal(bz, &res))
+			assert.Equal(t, spec.expAddr, res.Address)
+			assert.Equal(t, creator, res.Creator)
+			assert.Equal(t, spec.expDuplicates, res.HasDuplicates)
+		})
+	}
+}
+
+func TestQueryCodeListByCreator(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+	otherCreator := createFakeFundedAccount(ctx, accKeeper, deposit)
+	_, _, noCodesAddr := keyPubAddr()
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	for _, c := range []sdk.AccAddress{creator, otherCreator, creator} {
+		_, err = keeper.Create(ctx, c, wasmCode, "", "", nil)
+		require.NoError(t, err)
+	}
+
+	q := newQuerier(keeper)
+	specs := map[string]struct {
+		srcPath  []string
+		srcReq   *ListCodeRequest
+		expCodes []uint64
+		expErr   *sdkErrors.Error
+	}{
+		"creator with codes": {
+			srcPath:  []string{QueryListCodeByCreator, creator.String()},
+			expCodes: []uint64{1, 3},
+		},
+		"first page": {
+			srcPath:  []string{QueryListCodeByCreator, creator.String()},
+			srcReq:   &ListCodeRequest{Limit: 1},
+			expCodes: []uint64{1},
+		},
+		"with offset": {
+			srcPath:  []string{QueryListCodeByCreator, creator.String()},
+			srcReq:   &ListCodeRequest{Offset: 1, Limit: 1},
+			expCodes: []uint64{3},
+		},
+		"offset after last": {
+			srcPath:  []string{QueryListCodeByCreator, creator.String()},
+			srcReq:   &ListCodeRequest{Offset: 2},
+			expCodes: []uint64{},
+		},
+		"limit above max entries": {
+			srcPath: []string{QueryListCodeByCreator, creator.String()},
+			srcReq:  &ListCodeRequest{Limit: keeper.GetParams(ctx).MaxQueryResultEntries + 1},
+			expErr:  types.ErrQueryResultTooLarge,
+		},
+		"other creator": {
+			srcPath:  []string{QueryListCodeByCreator, otherCreator.String()},
+			expCodes: []uint64{2},
+		},
+		"creator without codes": {
+			srcPath:  []string{QueryListCodeByCreator, noCodesAddr.String()},
+			expCodes: []uint64{},
+		},
+		"invalid address": {
+			srcPath: []string{QueryListCodeByCreator, "foo"},
+			expErr:  sdkErrors.ErrInvalidAddress,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			var reqBz []byte
+			if spec.srcReq != nil {
+				var err error
+				reqBz, err = json.Marshal(spec.srcReq)
+				require.NoError(t, err)
+			}
+			bz, err := q(ctx, spec.srcPath, abci.RequestQuery{Data: reqBz})
+			require.True(t, spec.expErr.Is(err), "got %+v", err)
+			if spec.expErr != nil {
+				return
+			}
+			var res []ListCodeResponse
+			require.NoError(t, json.Unmarshal(bz, &res))
+			gotCodes := make([]uint64, len(res))
+			for i, c := range res {
+				gotCodes[i] = c.ID
+			}
+			assert.Equal(t, spec.expCodes, gotCodes)
+		})
+	}
+}
+
+func TestQueryCodeByHash(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	gzippedCode, err := ioutil.ReadFile("./testdata/contract.wasm.gzip")
+	require.NoError(t, err)
+	maskCode, err := ioutil.ReadFile("./testdata/mask.wasm")
+	require.NoError(t, err)
+	// the gzipped upload is the same code
+	for _, c := range [][]byte{wasmCode, maskCode, gzippedCode} {
+		_, err = keeper.Create(ctx, creator, c, "", "", nil)
+		require.NoError(t, err)
+	}
+	codeHash := keeper.GetCodeInfo(ctx, 1).CodeHash
+
+	q := newQuerier(keeper)
+	specs := map[string]struct {
+		srcPath  []string
+		srcReq   *ListCodeRequest
+		expCodes []uint64
+		expErr   *sdkErrors.Error
+	}{
+		"duplicate uploads": {
+			srcPath:  []string{QueryCodeByHash, hex.EncodeToString(codeHash)},
+			expCodes: []uint64{1, 3},
+		},
+		"first page": {
+			srcPath:  []string{QueryCodeByHash, hex.EncodeToString(codeHash)},
+			srcReq:   &ListCodeRequest{Limit: 1},
+			expCodes: []uint64{1},
+		},
+		"with offset": {
+			srcPath:  []string{QueryCodeByHash, hex.EncodeToString(codeHash)},
+			srcReq:   &ListCodeRequest{Offset: 1, Limit: 1},
+			expCodes: []uint64{3},
+		},
+		"limit above max entries": {
+			srcPath: []string{QueryCodeByHash, hex.EncodeToString(codeHash)},
+			srcReq:  &ListCodeRequest{Limit: keeper.GetParams(ctx).MaxQueryResultEntries + 1},
+			expErr:  types.ErrQueryResultTooLarge,
+		},
+		"unknown hash": {
+			srcPath:  []string{QueryCodeByHash, hex.EncodeToString(make([]byte, sha256.Size))},
+			expCodes: []uint64{},
+		},
+		"hash prefix": {
+			srcPath: []string{QueryCodeByHash, hex.EncodeToString(codeHash[:4])},
+			expErr:  sdkErrors.ErrUnknownRequest,
+		},
+		"invalid hex": {
+			srcPath: []string{QueryCodeByHash, "foo"},
+			expErr:  sdkErrors.ErrUnknownRequest,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			var reqBz []byte
+			if spec.srcReq != nil {
+				var err error
+				reqBz, err = json.Marshal(spec.srcReq)
+				require.NoError(t, err)
+			}
+			bz, err := q(ctx, spec.srcPath, abci.RequestQuery{Data: reqBz})
+			require.True(t, spec.expErr.Is(err), "got %+v", err)
+			if spec.expErr != nil {
+				return
+			}
+			var res []ListCodeResponse
+			require.NoError(t, json.Unmarshal(bz, &res))
+			gotCodes := make([]uint64, len(res))
+			for i, c := range res {
+				gotCodes[i] = c.ID
+			}
+			assert.Equal(t, spec.expCodes, gotCodes)
+		})
+	}
+}
+
+func TestQueryPinnedCodes(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err = keeper.Create(ctx, creator, wasmCode, "", "", nil)
+		require.NoError(t, err)
+	}
+
+	q := newQuerier(keeper)
+	queryPinned := func() []uint64 {
+		bz, err := q(ctx, []string{QueryPinnedCodes}, abci.RequestQuery{})
+		require.NoError(t, err)
+		var res PinnedCodesResponse
+		require.NoError(t, json.Unmarshal(bz, &res))
+		return res.CodeIDs
+	}
+	assert.Equal(t, []uint64{}, queryPinned())
+
+	require.NoError(t, keeper.PinCode(ctx, 3))
+	require.NoError(t, keeper.PinCode(ctx, 1))
+	// pinning twice is fine
+	require.NoError(t, keeper.PinCode(ctx, 1))
+	assert.Equal(t, []uint64{1, 3}, queryPinned())
+	assert.True(t, keeper.IsPinnedCode(ctx, 1))
+	assert.False(t, keeper.IsPinnedCode(ctx, 2))
+
+	// paged
+	bz, err := q(ctx, []string{QueryPinnedCodes}, abci.RequestQuery{Data: []byte(`{"offset":1,"limit":1}`)})
+	require.NoError(t, err)
+	var page PinnedCodesResponse
+	require.NoError(t, json.Unmarshal(bz, &page))
+	assert.Equal(t, []uint64{3}, page.CodeIDs)
+	_, err = q(ctx, []string{QueryPinnedCodes}, abci.RequestQuery{Data: []byte(fmt.Sprintf(`{"limit":%d}`, keeper.GetParams(ctx).MaxQueryResultEntries+1))})
+	assert.True(t, types.ErrQueryResultTooLarge.Is(err), "got %+v", err)
+
+	require.NoError(t, keeper.UnpinCode(ctx, 1))
+	assert.Equal(t, []uint64{3}, queryPinned())
+
+	// unknown codes are rejected
+	assert.True(t, types.ErrNotFound.Is(keeper.PinCode(ctx, 99)))
+	assert.True(t, types.ErrNotFound.Is(keeper.UnpinCode(ctx, 99)))
+}
+
+func TestQueryOrphanCodes(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	q := newQuerier(keeper)
+	queryOrphanPage := func(pagination ListCodeRequest) []uint64 {
+		reqBz, err := json.Marshal(pagination)
+		require.NoError(t, err)
+		bz, err := q(ctx, []string{QueryOrphanCodes}, abci.RequestQuery{Data: reqBz})
+		require.NoError(t, err)
+		var res OrphanCodesResponse
+		require.NoError(t, json.Unmarshal(bz, &res))
+		return res.CodeIDs
+	}
+	queryOrphans := func() []uint64 {
+		return queryOrphanPage(ListCodeRequest{})
+	}
+	assert.Equal(t, []uint64{}, queryOrphans())
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err = keeper.Create(ctx, creator, wasmCode, "", "", nil)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []uint64{1, 2, 3}, queryOrphans())
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: creator, Beneficiary: bob})
+	require.NoError(t, err)
+	contractAddr, err := keeper.Instantiate(ctx, 2, creator, creator, initMsgBz, "demo contract", false, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{1, 3}, queryOrphans())
+	// the offset skips orphans only
+	assert.Equal(t, []uint64{1}, queryOrphanPage(ListCodeRequest{Limit: 1}))
+	assert.Equal(t, []uint64{3}, queryOrphanPage(ListCodeRequest{Offset: 1, Limit: 1}))
+
+	_, err = q(ctx, []string{QueryOrphanCodes}, abci.RequestQuery{Data: []byte(fmt.Sprintf(`{"limit":%d}`, keeper.GetParams(ctx).MaxQueryResultEntries+1))})
+	assert.True(t, types.ErrQueryResultTooLarge.Is(err), "got %+v", err)
+
+	// the old code becomes an orphan when its last contract is migrated away
+	require.NoError(t, keeper.Migrate(ctx, contractAddr, creator, 3, []byte(`{}`)))
+	assert.Equal(t, []uint64{1, 2}, queryOrphans())
+}
+
+func TestQueryCodePinned(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		_, err = keeper.Create(ctx, creator, wasmCode, "", "", nil)
+		require.NoError(t, err)
+	}
+	require.NoError(t, keeper.PinCode(ctx, 2))
+
+	q := newQuerier(keeper)
+	specs := map[string]struct {
+		srcPath   []string
+		expPinned bool
+		expErr    *sdkErrors.Error
+	}{
+		"pinned": {
+			srcPath:   []string{QueryCodePinned, "2"},
+			expPinned: true,
+		},
+		"not pinned": {
+			srcPath: []string{QueryCodePinned, "1"},
+		},
+		"unknown code": {
+			srcPath: []string{QueryCodePinned, "99"},
+			expErr:  types.ErrNotFound,
+		},
+		"invalid code id": {
+			srcPath: []string{QueryCodePinned, "foo"},
+			expErr:  sdkErrors.ErrUnknownRequest,
+		},
+		"missing code id": {
+			srcPath: []string{QueryCodePinned},
+			expErr:  sdkErrors.ErrUnknownRequest,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			bz, err := q(ctx, spec.srcPath, abci.RequestQuery{})
+			require.True(t, spec.expErr.Is(err), "got %+v", err)
+			if spec.expErr != nil {
+				return
+			}
+			var res CodePinnedResponse
+			require.NoError(t, json.Unmarshal(bz, &res))
+			assert.Equal(t, spec.expPinned, res.Pinned)
+		})
+	}
+}
+
+func TestQueryCodeUsage(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+	anyAddr := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	codeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+	otherCodeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+	unusedCodeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: anyAddr, Beneficiary: bob})
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err := keeper.Instantiate(ctx, codeID, creator, nil, initMsgBz, "demo contract", false, nil)
+		require.NoError(t, err)
+	}
+	migratedAddr, err := keeper.Instantiate(ctx, codeID, creator, creator, initMsgBz, "demo contract", false, nil)
+	require.NoError(t, err)
+	require.NoError(t, keeper.Migrate(ctx, migratedAddr, creator, otherCodeID, []byte(`{}`)))
+
+	q := newQuerier(keeper)
+	specs := map[string]struct {
+		srcPath  []string
+		expCount uint64
+		expErr   *sdkErrors.Error
+	}{
+		"with contracts": {
+			srcPath:  []string{QueryCodeUsage, fmt.Sprintf("%d", codeID)},
+			expCount: 3,
+		},
+		"migrated contract": {
+			srcPath:  []string{QueryCodeUsage, fmt.Sprintf("%d", otherCodeID)},
+			expCount: 1,
+		},
+		"no contracts": {
+			srcPath: []string{QueryCodeUsage, fmt.Sprintf("%d", unusedCodeID)},
+		},
+		"unknown code": {
+			srcPath: []string{QueryCodeUsage, "99"},
+			expErr:  types.ErrNotFound,
+		},
+		"invalid code id": {
+			srcPath: []string{QueryCodeUsage, "foo"},
+			expErr:  sdkErrors.ErrUnknownRequest,
+		},
+		"missing code id": {
+			srcPath: []string{QueryCodeUsage},
+			expErr:  sdkErrors.ErrUnknownRequest,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			bz, err := q(ctx, spec.srcPath, abci.RequestQuery{})
+			require.True(t, spec.expErr.Is(err), "got %+v", err)
+			if spec.expErr != nil {
+				return
+			}
+			var res CountResponse
+			require.NoError(t, json.Unmarshal(bz, &res))
+			assert.Equal(t, spec.expCount, res.Count)
+		})
+	}
+	// the counter matches the index
+	var indexed uint64
+	keeper.IterateContractsByCode(ctx, codeID, func(sdk.AccAddress, types.ContractInfo) bool {
+		indexed++
+		return false
+	})
+	assert.Equal(t, indexed, keeper.GetContractCountByCode(ctx, codeID))
+}
+
+func TestQueryCodeHasBytecode(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	_, err = keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+
+	// a code info with a hash the vm has never seen, as left behind when the bytecode was pruned
+	prunedInfo := types.NewCodeInfo(bytes.Repeat([]byte{1}, 32), creator, "", "", types.AllowEverybody, "")
+	ctx.KVStore(keeper.storeKey).Set(types.GetCodeKey(2), keeper.cdc.MustMarshalBinaryBare(prunedInfo))
+
+	q := newQuerier(keeper)
+	specs := map[string]struct {
+		srcPath        []string
+		expHasBytecode bool
+		expErr         *sdkErrors.Error
+	}{
+		"with bytecode": {
+			srcPath:        []string{QueryCodeHasBytecode, "1"},
+			expHasBytecode: true,
+		},
+		"bytecode pruned": {
+			srcPath: []string{QueryCodeHasBytecode, "2"},
+		},
+		"unknown code": {
+			srcPath: []string{QueryCodeHasBytecode, "99"},
+			expErr:  types.ErrNotFound,
+		},
+		"invalid code id": {
+			srcPath: []string{QueryCodeHasBytecode, "foo"},
+			expErr:  sdkErrors.ErrUnknownRequest,
+		},
+		"missing code id": {
+			srcPath: []string{QueryCodeHasBytecode},
+			expErr:  sdkErrors.ErrUnknownRequest,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			bz, err := q(ctx, spec.srcPath, abci.RequestQuery{})
+			require.True(t, spec.expErr.Is(err), "got %+v", err)
+			if spec.expErr != nil {
+				return
+			}
+			var res CodeHasBytecodeResponse
+			require.NoError(t, json.Unmarshal(bz, &res))
+			assert.Equal(t, spec.expHasBytecode, res.HasBytecode)
+		})
+	}
+
+	// the code query reports the missing bytecode distinctly
+	_, err = q(ctx, []string{QueryGetCode, "2"}, abci.RequestQuery{})
+	assert.True(t, types.ErrBytecodePruned.Is(err), "got %+v", err)
+}
+
+func TestQueryCodeCompressed(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	codeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+
+	q := newQuerier(keeper)
+	specs := map[string]struct {
+		srcReq        []byte
+		expCompressed bool
+		expErr        *sdkErrors.Error
+	}{
+		"default": {},
+		"compressed": {
+			srcReq:        []byte(`{"compressed":true}`),
+			expCompressed: true,
+		},
+		"explicitly uncompressed": {
+			srcReq: []byte(`{"compressed":false}`),
+		},
+		"invalid request": {
+			srcReq: []byte(`not json`),
+			expErr: sdkErrors.ErrJSONUnmarshal,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			bz, err := q(ctx, []string{QueryGetCode, fmt.Sprintf("%d", codeID)}, abci.RequestQuery{Data: spec.srcReq})
+			require.True(t, spec.expErr.Is(err), "got %+v", err)
+			if spec.expErr != nil {
+				return
+			}
+			var res GetCodeResponse
+			require.NoError(t, json.Unmarshal(bz, &res))
+			assert.Equal(t, spec.expCompressed, res.Compressed)
+			if !spec.expCompressed {
+				assert.Equal(t, wasmCode, res.Code)
+				return
+			}
+			assert.True(t, len(res.Code) < len(wasmCode))
+			code, err := uncompress(res.Code, types.DefaultMaxWasmCodeSize)
+			require.NoError(t, err)
+			assert.Equal(t, wasmCode, code)
+		})
+	}
+}
+
+func TestQueryCodeVerification(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	codeID, err := keeper.Create(ctx, creator, wasmCode, "https://example.com/source", "cosmwasm-opt:0.6.2", nil)
+	require.NoError(t, err)
+
+	// the code hash can be compared with the hash of a locally built artifact
+	expHash := sha256.Sum256(wasmCode)
+	assert.Equal(t, expHash[:], keeper.GetCodeHash(ctx, codeID))
+	assert.Nil(t, keeper.GetCodeHash(ctx, 99))
+
+	q := newQuerier(keeper)
+	specs := map[string]struct {
+		srcPath []string
+		expErr  *sdkErrors.Error
+	}{
+		"known code": {
+			srcPath: []string{QueryCodeVerification, fmt.Sprintf("%d", codeID)},
+		},
+		"unknown code": {
+			srcPath: []string{QueryCodeVerification, "99"},
+			expErr:  types.ErrNotFound,
+		},
+		"invalid code id": {
+			srcPath: []string{QueryCodeVerification, "foo"},
+			expErr:  sdkErrors.ErrUnknownRequest,
+		},
+		"missing code id": {
+			srcPath: []string{QueryCodeVerification},
+			expErr:  sdkErrors.ErrUnknownRequest,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			bz, err := q(ctx, spec.srcPath, abci.RequestQuery{})
+			require.True(t, spec.expErr.Is(err), "got %+v", err)
+			if spec.expErr != nil {
+				return
+			}
+			var res CodeVerificationResponse
+			require.NoError(t, json.Unmarshal(bz, &res))
+			assert.Equal(t, CodeVerificationResponse{
+				CodeID:   codeID,
+				CodeHash: expHash[:],
+				Source:   "https://example.com/source",
+				Builder:  "cosmwasm-opt:0.6.2",
+			}, res)
+		})
+	}
+}
+
+func TestQueryCodeInfo(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	codeID, err := keeper.Create(ctx, creator, wasmCode, "https://example.com/source", "cosmwasm-opt:0.6.2", nil)
+	require.NoError(t, err)
+
+	q := newQuerier(keeper)
+	specs := map[string]struct {
+		srcPath []string
+		expErr  *sdkErrors.Error
+	}{
+		"existing code": {
+			srcPath: []string{QueryGetCodeInfo, fmt.Sprintf("%d", codeID)},
+		},
+		"unknown code": {
+			srcPath: []string{QueryGetCodeInfo, "999"},
+			expErr:  types.ErrNotFound,
+		},
+		"invalid code id": {
+			srcPath: []string{QueryGetCodeInfo, "foo"},
+			expErr:  sdkErrors.ErrUnknownRequest,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			bz, err := q(ctx, spec.srcPath, abci.RequestQuery{})
+			require.True(t, spec.expErr.Is(err), err)
+			if spec.expErr != nil {
+				return
+			}
+			var res ListCodeResponse
+			require.NoError(t, json.Unmarshal(bz, &res))
+			info := keeper.GetCodeInfo(ctx, codeID)
+			assert.Equal(t, ListCodeResponse{
+				ID:         codeID,
+				Creator:    creator,
+				CodeHash:   info.CodeHash,
+				Source:     "https://example.com/source",
+				Builder:    "cosmwasm-opt:0.6.2",
+				APIVersion: "0.6",
+			}, res)
+			assert.NotContains(t, string(bz), "wasm_byte_code")
+		})
+	}
+}
+
+func TestQueryContractCodeInfo(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+	anyAddr := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	codeID, err := keeper.Create(ctx, creator, wasmCode, "https://example.com/source", "cosmwasm-opt:0.6.2", nil)
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: anyAddr, Beneficiary: bob})
+	require.NoError(t, err)
+	contractAddr, err := keeper.Instantiate(ctx, codeID, anyAddr, creator, initMsgBz, "demo contract", false, nil)
+	require.NoError(t, err)
+	_, _, nonContract := keyPubAddr()
+
+	q := newQuerier(keeper)
+	specs := map[string]struct {
+		srcAddr sdk.AccAddress
+		expErr  *sdkErrors.Error
+	}{
+		"contract with code": {
+			srcAddr: contractAddr,
+		},
+		"unknown contract": {
+			srcAddr: nonContract,
+			expErr:  types.ErrNotFound,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			bz, err := q(ctx, []string{QueryContractCodeInfo, spec.srcAddr.String()}, abci.RequestQuery{})
+			if spec.expErr != nil {
+				require.True(t, spec.expErr.Is(err), "got %+v", err)
+				return
+			}
+			require.NoError(t, err)
+			var res ContractCodeInfoResponse
+			require.NoError(t, json.Unmarshal(bz, &res))
+			assert.Equal(t, contractAddr, res.Address)
+			assert.Equal(t, *keeper.GetContractInfo(ctx, contractAddr), res.ContractInfo)
+			assert.Equal(t, ListCodeResponse{
+				ID:         codeID,
+				Creator:    creator,
+				CodeHash:   keeper.GetCodeInfo(ctx, codeID).CodeHash,
+				Source:     "https://example.com/source",
+				Builder:    "cosmwasm-opt:0.6.2",
+				APIVersion: "0.6",
+			}, res.CodeInfo)
+		})
+	}
+}
+
+func TestQueryContractHistory(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+	anyAddr := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	originalCodeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+	newCodeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: anyAddr, Beneficiary: bob})
+	require.NoError(t, err)
+
+	ctx = ctx.WithBlockHeight(1)
+	contractAddr, err := keeper.Instantiate(ctx, originalCodeID, creator, creator, initMsgBz, "demo contract", false, nil)
+	require.NoError(t, err)
+
+	ctx = ctx.WithBlockHeight(2)
+	require.NoError(t, keeper.Migrate(ctx, contractAddr, creator, newCodeID, []byte(`{"foo":"bar"}`)))
+
+	q := newQuerier(keeper)
+	specs := map[string]struct {
+		srcAddr    sdk.AccAddress
+		expEntries []types.ContractCodeHistoryEntry
+	}{
+		"init and migrate": {
+			srcAddr: contractAddr,
+			expEntries: []types.ContractCodeHistoryEntry{
+				{Operation: types.InitContractCodeHistoryType, CodeID: originalCodeID, Updated: 1, Msg: initMsgBz},
+				{Operation: types.MigrateContractCodeHistoryType, CodeID: newCodeID, Updated: 2, Msg: []byte(`{"foo":"bar"}`)},
+			},
+		},
+		"unknown contract": {
+			srcAddr:    anyAddr,
+			expEntries: []types.ContractCodeHistoryEntry{},
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			bz, err := q(ctx, []string{QueryContractHistory, spec.srcAddr.String()}, abci.RequestQuery{})
+			require.NoError(t, err)
+
+			var res []types.ContractCodeHistoryEntry
+			require.NoError(t, json.Unmarshal(bz, &res))
+			require.Len(t, res, len(spec.expEntries))
+			for i, exp := range spec.expEntries {
+				assert.Equal(t, exp.Operation, res[i].Operation)
+				assert.Equal(t, exp.CodeID, res[i].CodeID)
+				assert.Equal(t, exp.Updated, res[i].Updated)
+				// messages are re-indented by the querier
+				assert.JSONEq(t, string(exp.Msg), string(res[i].Msg))
+			}
+		})
+	}
+}
+
+func TestQueryContractInfos(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+	anyAddr := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	codeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: anyAddr, Beneficiary: bob})
+	require.NoError(t, err)
+	firstAddr, err := keeper.Instantiate(ctx, codeID, creator, nil, initMsgBz, "first contract", false, nil)
+	require.NoError(t, err)
+	secondAddr, err := keeper.Instantiate(ctx, codeID, creator, nil, initMsgBz, "second contract", false, nil)
+	require.NoError(t, err)
+
+	params := keeper.GetParams(ctx)
+	params.MaxContractInfosBatch = 3
+	keeper.SetParams(ctx, params)
+
+	q := newQuerier(keeper)
+	specs := map[string]struct {
+		srcAddrs []string
+		exp      []*types.ContractInfo
+		expErr   *sdkErrors.Error
+	}{
+		"in request order": {
+			srcAddrs: []string{secondAddr.String(), firstAddr.String()},
+			exp:      []*types.ContractInfo{keeper.GetContractInfo(ctx, secondAddr), keeper.GetContractInfo(ctx, firstAddr)},
+		},
+		"unknown contract": {
+			srcAddrs: []string{firstAddr.String(), anyAddr.String()},
+			exp:      []*types.ContractInfo{keeper.GetContractInfo(ctx, firstAddr), nil},
+		},
+		"empty": {
+			srcAddrs: []string{},
+			exp:      []*types.ContractInfo{},
+		},
+		"max addresses": {
+			srcAddrs: []string{firstAddr.String(), firstAddr.String(), firstAddr.String()},
+			exp:      []*types.ContractInfo{keeper.GetContractInfo(ctx, firstAddr), keeper.GetContractInfo(ctx, firstAddr), keeper.GetContractInfo(ctx, firstAddr)},
+		},
+		"too many addresses": {
+			srcAddrs: []string{firstAddr.String(), firstAddr.String(), firstAddr.String(), firstAddr.String()},
+			expErr:   sdkErrors.ErrUnknownRequest,
+		},
+		"invalid address": {
+			srcAddrs: []string{firstAddr.String(), "cosmos1notavalidaddress"},
+			expErr:   sdkErrors.ErrInvalidAddress,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			data, err := json.Marshal(spec.srcAddrs)
+			require.NoError(t, err)
+			bz, err := q(ctx, []string{QueryGetContractInfos}, abci.RequestQuery{Data: data})
+			require.True(t, spec.expErr.Is(err), "got %+v", err)
+			if spec.expErr != nil {
+				return
+			}
+			var res []*types.ContractInfo
+			require.NoError(t, json.Unmarshal(bz, &res))
+			assert.Equal(t, spec.exp, res)
+		})
+	}
+
+	t.Run("not a json array", func(t *testing.T) {
+		_, err := q(ctx, []string{QueryGetContractInfos}, abci.RequestQuery{Data: []byte(firstAddr.String())})
+		require.True(t, sdkErrors.ErrJSONUnmarshal.Is(err), "got %+v", err)
+	})
+}
+
+func TestQueryContractCodeIDs(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+	anyAddr := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	originalCodeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+	newCodeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: anyAddr, Beneficiary: bob})
+	require.NoError(t, err)
+
+	migratedAddr, err := keeper.Instantiate(ctx, originalCodeID, creator, creator, initMsgBz, "demo contract", false, nil)
+	require.NoError(t, err)
+	require.NoError(t, keeper.Migrate(ctx, migratedAddr, creator, newCodeID, []byte(`{"foo":"bar"}`)))
+	notMigratedAddr, err := keeper.Instantiate(ctx, originalCodeID, creator, creator, initMsgBz, "demo contract", false, nil)
+	require.NoError(t, err)
+
+	q := newQuerier(keeper)
+	specs := map[string]struct {
+		srcAddr sdk.AccAddress
+		exp     ContractCodeIDsResponse
+		expErr  *sdkErrors.Error
+	}{
+		"migrated": {
+			srcAddr: migratedAddr,
+			exp:     ContractCodeIDsResponse{OriginalCodeID: originalCodeID, CurrentCodeID: newCodeID},
+		},
+		"not migrated": {
+			srcAddr: notMigratedAddr,
+			exp:     ContractCodeIDsResponse{OriginalCodeID: originalCodeID, CurrentCodeID: originalCodeID},
+		},
+		"unknown contract": {
+			srcAddr: anyAddr,
+			expErr:  types.ErrNotFound,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			bz, err := q(ctx, []string{QueryContractCodeIDs, spec.srcAddr.String()}, abci.RequestQuery{})
+			require.True(t, spec.expErr.Is(err), "got %+v", err)
+			if spec.expErr != nil {
+				return
+			}
+			var res ContractCodeIDsResponse
+			require.NoError(t, json.Unmarshal(bz, &res))
+			assert.Equal(t, spec.exp, res)
+		})
+	}
+}
+
+func TestQueryContractMigratable(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+	anyAddr := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	codeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: anyAddr, Beneficiary: bob})
+	require.NoError(t, err)
+	withAdminAddr, err := keeper.Instantiate(ctx, codeID, creator, anyAddr, initMsgBz, "demo contract", false, nil)
+	require.NoError(t, err)
+	noAdminAddr, err := keeper.Instantiate(ctx, codeID, creator, nil, initMsgBz, "demo contract", false, nil)
+	require.NoError(t, err)
+	clearedAdminAddr, err := keeper.Instantiate(ctx, codeID, creator, creator, initMsgBz, "demo contract", false, nil)
+	require.NoError(t, err)
+	require.NoError(t, keeper.ClearContractAdmin(ctx, clearedAdminAddr, creator))
+
+	q := newQuerier(keeper)
+	specs := map[string]struct {
+		srcAddr sdk.AccAddress
+		exp     ContractMigratableResponse
+		expErr  *sdkErrors.Error
+	}{
+		"with admin": {
+			srcAddr: withAdminAddr,
+			exp:     ContractMigratableResponse{Migratable: true, Admin: anyAddr},
+		},
+		"without admin": {
+			srcAddr: noAdminAddr,
+			exp:     ContractMigratableResponse{},
+		},
+		"admin cleared": {
+			srcAddr: clearedAdminAddr,
+			exp:     ContractMigratableResponse{},
+		},
+		"unknown contract": {
+			srcAddr: anyAddr,
+			expErr:  types.ErrNotFound,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			bz, err := q(ctx, []string{QueryContractMigratable, spec.srcAddr.String()}, abci.RequestQuery{})
+			require.True(t, spec.expErr.Is(err), "got %+v", err)
+			if spec.expErr != nil {
+				return
+			}
+			var res ContractMigratableResponse
+			require.NoError(t, json.Unmarshal(bz, &res))
+			assert.Equal(t, spec.exp, res)
+		})
+	}
+}
+
+func TestQueryContractStateJSON(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+	anyAddr := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	codeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: anyAddr, Beneficiary: bob})
+	require.NoError(t, err)
+	contractAddr, err := keeper.Instantiate(ctx, codeID, creator, nil, initMsgBz, "demo contract", false, nil)
+	require.NoError(t, err)
+
+	keeper.setContractState(ctx, contractAddr, []types.Model{
+		{Key: []byte("json"), Value: []byte(`{"count":8}`)},
+		{Key: []byte("binary"), Value: []byte{0x1, 0x2}},
+	})
+
+	q := newQuerier(keeper)
+	specs := map[string]struct {
+		srcAddr sdk.AccAddress
+		srcKey  []byte
+		exp     []byte
+		expErr  *sdkErrors.Error
+	}{
+		"json value": {
+			srcAddr: contractAddr,
+			srcKey:  []byte("json"),
+			exp:     []byte(`{"count":8}`),
+		},
+		"non json value": {
+			srcAddr: contractAddr,
+			srcKey:  []byte("binary"),
+			expErr:  sdkErrors.ErrJSONUnmarshal,
+		},
+		"unknown key": {
+			srcAddr: contractAddr,
+			srcKey:  []byte("unknown"),
+			expErr:  types.ErrNotFound,
+		},
+		"empty key": {
+			srcAddr: contractAddr,
+			expErr:  sdkErrors.ErrUnknownRequest,
+		},
+		"unknown contract": {
+			srcAddr: anyAddr,
+			srcKey:  []byte("json"),
+			expErr:  types.ErrNotFound,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			bz, err := q(ctx, []string{QueryContractStateJSON, spec.srcAddr.String()}, abci.RequestQuery{Data: spec.srcKey})
+			require.True(t, spec.expErr.Is(err), "got %+v", err)
+			if spec.expErr != nil {
+				return
+			}
+			assert.Equal(t, spec.exp, bz)
+		})
+	}
+}
+
+func TestQueryContractBalance(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	codeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: creator, Beneficiary: bob})
+	require.NoError(t, err)
+	contractStart := sdk.NewCoins(sdk.NewInt64Coin("denom", 40000))
+	fundedAddr, err := keeper.Instantiate(ctx, codeID, creator, nil, initMsgBz, "funded", false, contractStart)
+	require.NoError(t, err)
+	emptyAddr, err := keeper.Instantiate(ctx, codeID, creator, nil, initMsgBz, "empty", false, nil)
+	require.NoError(t, err)
+
+	q := newQuerier(keeper)
+	specs := map[string]struct {
+		srcPath    []string
+		expBalance sdk.Coins
+		expErr     *sdkErrors.Error
+	}{
+		"funded contract": {
+			srcPath:    []string{QueryContractBalance, fundedAddr.String()},
+			expBalance: contractStart,
+		},
+		"contract without funds": {
+			srcPath:    []string{QueryContractBalance, emptyAddr.String()},
+			expBalance: sdk.Coins{},
+		},
+		"not a contract": {
+			srcPath: []string{QueryContractBalance, creator.String()},
+			expErr:  types.ErrNotFound,
+		},
+		"invalid address": {
+			srcPath: []string{QueryContractBalance, "foo"},
+			expErr:  sdkErrors.ErrInvalidAddress,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			bz, err := q(ctx, spec.srcPath, abci.RequestQuery{})
+			require.True(t, spec.expErr.Is(err), "got %+v", err)
+			if spec.expErr != nil {
+				return
+			}
+			var res sdk.Coins
+			require.NoError(t, json.Unmarshal(bz, &res))
+			assert.Equal(t, spec.expBalance, res)
+		})
+	}
+}
+
+func TestQuerySimulateExecute(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, accKeeper, keeper := CreateTestInput(t, false, tempDir)
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(ctx, accKeeper, deposit.Add(deposit))
+	fred := createFakeFundedAccount(ctx, accKeeper, deposit)
+
+	wasmCode, err := ioutil.ReadFile("./testdata/contract.wasm")
+	require.NoError(t, err)
+	codeID, err := keeper.Create(ctx, creator, wasmCode, "", "", nil)
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(InitMsg{Verifier: fred, Beneficiary: bob})
+	require.NoError(t, err)
+	contractAddr, err := keeper.Instantiate(ctx, codeID, creator, nil, initMsgBz, "demo contract", false, deposit)
+	require.NoError(t, err)
+
+	execPayload := func(sender sdk.AccAddress) []byte {
+		bz, err := json.Marshal(types.MsgExecuteContract{Sender: sender, Contract: contractAddr, Msg: []byte(`{}`)})
+		require.NoError(t, err)
+		return bz
+	}
+
+	q := newQuerier(keeper)
+	specs := map[string]struct {
+		srcReq      []byte
+		expExecErr  bool
+		expQueryErr *sdkErrors.Error
+	}{
+		"verifier releases funds": {
+			srcReq: execPayload(fred),
+		},
+		"execution fails": {
+			srcReq:     execPayload(creator),
+			expExecErr: true,
+		},
+		"invalid json": {
+			srcReq:      []byte("not json"),
+			expQueryErr: sdkErrors.ErrJSONUnmarshal,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			bz, err := q(ctx, []string{QuerySimulateExecute}, abci.RequestQuery{Data: spec.srcReq})
+			require.True(t, spec.expQueryErr.Is(err), "got %+v", err)
+			if spec.expQueryErr != nil {
+				return
+			}
+			var res SimulateExecuteResponse
+			require.NoError(t, json.Unmarshal(bz, &res))
+			assert.NotZero(t, res.GasUsed)
+			if spec.expExecErr {
+				assert.NotEmpty(t, res.Error)
+				return
+			}
+			assert.Empty(t, res.Error)
+			assert.NotEmpty(t, res.Events)
+			// nothing was committed
+			assert.Nil(t, accKeeper.GetAccount(ctx, bob))
+			assert.Equal(t, deposit, accKeeper.GetAccount(ctx, contractAddr).GetCoins())
+
+			// the preview matches a real execution
+			var msg types.MsgExecuteContract
+			require.NoError(t, json.Unmarshal(spec.srcReq, &msg))
+			execCtx, _ := ctx.CacheContext()
+			execCtx = execCtx.WithEventManager(sdk.NewEventManager())
+			execRes, err := keeper.Execute(execCtx, msg.Contract, msg.Sender, msg.Msg, msg.SentFunds)
+			require.NoError(t, err)
+			assert.Equal(t, execRes.Log, res.Log)
+			assert.Equal(t, sdk.StringifyEvents(execCtx.EventManager().Events().ToABCIEvents()), res.Events)
+		})
+	}
+}
+
+func TestQuerySimulateExecuteValidatesMsg(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, _, keeper := CreateTestInput(t, false, tempDir)
+
+	_, _, anyAddr := keyPubAddr()
+	q := NewQuerier(keeper)
+	specs := map[string]types.MsgExecuteContract{
+		"missing sender":   {Contract: anyAddr, Msg: []byte(`{}`)},
+		"missing contract": {Sender: anyAddr, Msg: []byte(`{}`)},
+		"invalid funds": {Sender: anyAddr, Contract: anyAddr, Msg: []byte(`{}`),
+			SentFunds: sdk.Coins{sdk.Coin{Denom: "denom", Amount: sdk.NewInt(-1)}}},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			bz, err := json.Marshal(spec)
+			require.NoError(t, err)
+			_, err = q(ctx, []string{QuerySimulateExecute}, abci.RequestQuery{Data: bz})
+			require.Error(t, err)
+			// same error as for the tx
+			expErr := spec.ValidateBasic()
+			require.Error(t, expErr)
+			assert.Equal(t, expErr.Codespace(), err.Codespace())
+			assert.Equal(t, expErr.Code(), err.Code())
+		})
+	}
+}
+
+func TestQueryInvalidContractAddress(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ctx, _, keeper := CreateTestInput(t, false, tempDir)
+
+	const badAddr = "cosmos1notavalidaddress"
+	q := newQuerier(keeper)
+	specs := map[string][]string{
+		"contract info":       {QueryGetContract, badAddr},
+		"contract history":    {QueryContractHistory, badAddr},
+		"contract balance":    {QueryContractBalance, badAddr},
+		"contract code":       {QueryContractCodeInfo, badAddr},
+		"contract code ids":   {QueryContractCodeIDs, badAddr},
+		"contract migratable": {QueryContractMigratable, badAddr},
+		"state all":           {QueryGetContractState, badAddr, QueryMethodContractStateAll},
+		"state raw":           {QueryGetContractState, badAddr, QueryMethodContractStateRaw},
+		"state smart":         {QueryGetContractState, badAddr, QueryMethodContractStateSmart},
+		"state json":          {QueryContractStateJSON, badAddr},
+	}
+	for msg, path := range specs {
+		t.Run(msg, func(t *testing.T) {
+			_, err := q(ctx, path, abci.RequestQuery{})
+			require.True(t, sdkErrors.ErrInvalidAddress.Is(err), "got %+v", err)
+			assert.Equal(t, badAddr+": invalid address", err.Error())
 		})
 	}
 }
@@ -30,13 +30,16 @@ func MakeTestCodec() *codec.Codec {
 	// cdc.RegisterConcrete(&auth.BaseAccount{}, "test/wasm/BaseAccount", nil)
 	auth.AppModuleBasic{}.RegisterCodec(cdc)
 	bank.AppModuleBasic{}.RegisterCodec(cdc)
+	// staking msgs can be dispatched by contracts
+	types.RegisterCodec(cdc)
+	wasmTypes.RegisterCodec(cdc)
 	sdk.RegisterCodec(cdc)
 	codec.RegisterCrypto(cdc)
 
 	return cdc
 }
 
-func CreateTestInput(t *testing.T, isCheckTx bool, tempDir string) (sdk.Context, auth.AccountKeeper, Keeper) {
+func CreateTestInput(t testing.TB, isCheckTx bool, tempDir string) (sdk.Context, auth.AccountKeeper, Keeper) {
 	keyContract := sdk.NewKVStoreKey(types.StoreKey)
 	keyAcc := sdk.NewKVStoreKey(auth.StoreKey)
 	keyParams := sdk.NewKVStoreKey(params.StoreKey)
@@ -79,7 +82,8 @@ func CreateTestInput(t *testing.T, isCheckTx bool, tempDir string) (sdk.Context,
 	// Load default wasm config
 	wasmConfig := wasmTypes.DefaultWasmConfig()
 
-	keeper := NewKeeper(cdc, keyContract, accountKeeper, bk, router, tempDir, wasmConfig)
+	keeper := NewKeeper(cdc, keyContract, pk.Subspace(wasmTypes.DefaultParamspace), accountKeeper, bk, router, tempDir, wasmConfig)
+	keeper.SetParams(ctx, wasmTypes.DefaultParams())
 
 	return ctx, accountKeeper, keeper
 }
@@ -9,7 +9,23 @@ import (
 func RegisterCodec(cdc *codec.Codec) {
 	cdc.RegisterConcrete(&MsgStoreCode{}, "wasm/store-code", nil)
 	cdc.RegisterConcrete(&MsgInstantiateContract{}, "wasm/instantiate", nil)
+	cdc.RegisterConcrete(&MsgStoreCodeAndInstantiate{}, "wasm/store-code-and-instantiate", nil)
+	cdc.RegisterConcrete(&MsgInstantiateContract2{}, "wasm/instantiate2", nil)
 	cdc.RegisterConcrete(&MsgExecuteContract{}, "wasm/execute", nil)
+	cdc.RegisterConcrete(&MsgMigrateContract{}, "wasm/migrate", nil)
+	cdc.RegisterConcrete(&MsgUpdateAdmin{}, "wasm/update-contract-admin", nil)
+	cdc.RegisterConcrete(&MsgClearAdmin{}, "wasm/clear-contract-admin", nil)
+	cdc.RegisterConcrete(&MsgUpdateInstantiateConfig{}, "wasm/update-instantiate-config", nil)
+	cdc.RegisterConcrete(&MsgDeprecateCode{}, "wasm/deprecate-code", nil)
+
+	cdc.RegisterConcrete(StoreCodeProposal{}, "wasm/store-proposal", nil)
+	cdc.RegisterConcrete(MigrateContractProposal{}, "wasm/migrate-proposal", nil)
+	cdc.RegisterConcrete(PinCodesProposal{}, "wasm/pin-codes-proposal", nil)
+	cdc.RegisterConcrete(UnpinCodesProposal{}, "wasm/unpin-codes-proposal", nil)
+	cdc.RegisterConcrete(ImportContractStateProposal{}, "wasm/import-contract-state-proposal", nil)
+	cdc.RegisterConcrete(UpdateInstantiateConfigProposal{}, "wasm/update-instantiate-config-proposal", nil)
+	cdc.RegisterConcrete(DeprecateCodeProposal{}, "wasm/deprecate-code-proposal", nil)
+	cdc.RegisterConcrete(MigrateAllContractsProposal{}, "wasm/migrate-all-contracts-proposal", nil)
 }
 
 // ModuleCdc generic sealed codec to be used throughout module
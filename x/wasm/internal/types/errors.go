@@ -1,6 +1,7 @@
 package types
 
 import (
+	sdk "github.com/cosmos/cosmos-sdk/types"
 	sdkErrors "github.com/cosmos/cosmos-sdk/types/errors"
 )
 
@@ -31,4 +32,50 @@ var (
 
 	// ErrQueryFailed error for rust smart query contract failure
 	ErrQueryFailed = sdkErrors.Register(DefaultCodespace, 8, "query wasm contract failed")
+
+	// ErrMigrationFailed error for a contract migration that was rejected
+	ErrMigrationFailed = sdkErrors.Register(DefaultCodespace, 9, "migrate wasm contract failed")
+
+	// ErrInvalidWasmCode error for wasm code that is empty or can not be uncompressed
+	ErrInvalidWasmCode = sdkErrors.Register(DefaultCodespace, 10, "invalid wasm code")
+
+	// ErrCodeTooLarge error for wasm code above the max wasm code size
+	ErrCodeTooLarge = sdkErrors.Register(DefaultCodespace, 11, "wasm code too large")
+
+	// ErrInvalidSource error for a source that is not an absolute url
+	ErrInvalidSource = sdkErrors.Register(DefaultCodespace, 12, "invalid source")
+
+	// ErrInvalidBuilder error for a builder that is not a cosmwasm-opt image reference
+	ErrInvalidBuilder = sdkErrors.Register(DefaultCodespace, 13, "invalid builder")
+
+	// ErrInvalidLabel error for a contract label that is empty or too long
+	ErrInvalidLabel = sdkErrors.Register(DefaultCodespace, 14, "invalid label")
+
+	// ErrInvalidMsg error for a contract msg or message field that fails validation
+	ErrInvalidMsg = sdkErrors.Register(DefaultCodespace, 15, "invalid msg")
+
+	// ErrMaxContractGas error for a single contract call that ran out of the MaxContractGas
+	ErrMaxContractGas = sdkErrors.Register(DefaultCodespace, 16, "max contract gas exceeded")
+
+	// ErrQueryResultTooLarge error for a query result with more entries than MaxQueryResultEntries
+	ErrQueryResultTooLarge = sdkErrors.Register(DefaultCodespace, 17, "query result too large")
+
+	// ErrBytecodePruned error for a code info without the wasm bytecode in the wasm cache dir
+	ErrBytecodePruned = sdkErrors.Register(DefaultCodespace, 18, "wasm bytecode pruned")
+
+	// ErrReentrancy error for a contract call into a contract that is already on the call stack
+	ErrReentrancy = sdkErrors.Register(DefaultCodespace, 19, "contract reentrancy")
 )
+
+// ToSDKError converts the registered errors to the sdk.Error type that ValidateBasic and the gov
+// module expect. Codespace and code are preserved, so clients can key on them.
+func ToSDKError(err error) sdk.Error {
+	if err == nil {
+		return nil
+	}
+	if sdkErr, ok := err.(sdk.Error); ok {
+		return sdkErr
+	}
+	space, code, log := sdkErrors.ABCIInfo(err, false)
+	return sdk.NewError(sdk.CodespaceType(space), sdk.CodeType(code), log)
+}
@@ -0,0 +1,36 @@
+package types
+
+const (
+	// EventTypeStoreCode is emitted when new wasm code was uploaded
+	EventTypeStoreCode = "store_code"
+	// EventTypeInstantiate is emitted when a new contract was instantiated
+	EventTypeInstantiate = "instantiate"
+	// EventTypeExecute is emitted when a contract was executed
+	EventTypeExecute = "execute"
+	// EventTypeMigrate is emitted when a contract was migrated to a new code
+	EventTypeMigrate = "migrate"
+	// EventTypePinCode is emitted when a code was pinned
+	EventTypePinCode = "pin_code"
+	// EventTypeUnpinCode is emitted when a code was unpinned
+	EventTypeUnpinCode = "unpin_code"
+	// EventTypeImportContractState is emitted when state was written into a contract store by governance
+	EventTypeImportContractState = "import_contract_state"
+	// EventTypeUpdateInstantiateConfig is emitted when the instantiate permission of a code was changed by governance
+	EventTypeUpdateInstantiateConfig = "update_instantiate_config"
+	// EventTypeDeprecateCode is emitted when the deprecated flag of a code was changed by governance
+	EventTypeDeprecateCode = "deprecate_code"
+	// EventTypeInstantiateDeprecated is emitted as a warning when a deprecated code was instantiated
+	EventTypeInstantiateDeprecated = "instantiate_deprecated_code"
+	// EventTypeContractReentrancy is emitted when a contract is called while it is already on the call stack
+	EventTypeContractReentrancy = "contract_reentrancy"
+
+	AttributeKeyContract = "contract_address"
+	AttributeKeyCodeID   = "code_id"
+	AttributeKeyCreator  = "creator"
+	AttributeKeySender   = "sender"
+	// AttributeKeyCodeReused is "true" when a store code or store code and instantiate with DeduplicateByHash
+	// returned an existing code
+	AttributeKeyCodeReused = "code_reused"
+	// AttributeKeyFunds are the coins sent to the contract with an instantiate or execute, empty for none
+	AttributeKeyFunds = "funds"
+)
@@ -1,28 +1,162 @@
 package types
 
-import sdk "github.com/cosmos/cosmos-sdk/types"
+import (
+	"bytes"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkErrors "github.com/cosmos/cosmos-sdk/types/errors"
+)
 
 // GenesisState is the struct representation of the export genesis
 type GenesisState struct {
+	Params    Params     `json:"params"`
 	Codes     []Code     `json:"codes"`
 	Contracts []Contract `json:"contracts"`
+	Sequences []Sequence `json:"sequences"`
 }
 
 // Code struct encompasses CodeInfo and CodeBytes
 type Code struct {
+	CodeID     uint64   `json:"code_id"`
 	CodeInfo   CodeInfo `json:"code_info"`
 	CodesBytes []byte   `json:"code_bytes"`
+	// Pinned is true when the code is in the pinned code index
+	Pinned bool `json:"pinned,omitempty"`
 }
 
-// Contract struct encompasses ContractAddress, ContractInfo, and ContractState
+// Contract struct encompasses ContractAddress, ContractInfo, ContractState and ContractCodeHistory.
+// A contract without history gets a single genesis entry on import.
 type Contract struct {
-	ContractAddress sdk.AccAddress `json:"contract_address"`
-	ContractInfo    ContractInfo   `json:"contract_info"`
-	ContractState   []Model        `json:"contract_state"`
+	ContractAddress     sdk.AccAddress             `json:"contract_address"`
+	ContractInfo        ContractInfo               `json:"contract_info"`
+	ContractState       []Model                    `json:"contract_state"`
+	ContractCodeHistory []ContractCodeHistoryEntry `json:"contract_code_history,omitempty"`
+}
+
+// Sequence is the value of an ID counter. The IDKey is KeyLastCodeID or KeyLastInstanceID.
+type Sequence struct {
+	IDKey []byte `json:"id_key"`
+	Value uint64 `json:"value"`
+}
+
+// ValidateBasic checks the code entry without the bytecode being compiled
+func (c Code) ValidateBasic() error {
+	if c.CodeID == 0 {
+		return sdkErrors.Wrap(ErrInvalidGenesis, "code id must not be 0")
+	}
+	if len(c.CodeInfo.CodeHash) == 0 {
+		return sdkErrors.Wrapf(ErrInvalidGenesis, "empty code hash for code id: %d", c.CodeID)
+	}
+	if err := validateGenesisAddress(c.CodeInfo.Creator); err != nil {
+		return sdkErrors.Wrapf(err, "creator of code id: %d", c.CodeID)
+	}
+	// codes stored before access configs existed have none
+	if c.CodeInfo.InstantiateConfig.Type != "" {
+		if err := c.CodeInfo.InstantiateConfig.ValidateBasic(); err != nil {
+			return sdkErrors.Wrapf(ErrInvalidGenesis, "instantiate config of code id %d: %s", c.CodeID, err.Error())
+		}
+	}
+	if len(c.CodesBytes) == 0 {
+		return sdkErrors.Wrapf(ErrInvalidGenesis, "empty code bytes for code id: %d", c.CodeID)
+	}
+	return nil
+}
+
+// ValidateBasic checks the contract entry
+func (c Contract) ValidateBasic() error {
+	if err := validateGenesisAddress(c.ContractAddress); err != nil {
+		return sdkErrors.Wrap(err, "contract address")
+	}
+	if c.ContractInfo.CodeID == 0 {
+		return sdkErrors.Wrapf(ErrInvalidGenesis, "code id must not be 0 for contract: %s", c.ContractAddress)
+	}
+	if err := validateGenesisAddress(c.ContractInfo.Creator); err != nil {
+		return sdkErrors.Wrapf(err, "creator of contract: %s", c.ContractAddress)
+	}
+	if len(c.ContractInfo.Admin) != 0 {
+		if err := validateGenesisAddress(c.ContractInfo.Admin); err != nil {
+			return sdkErrors.Wrapf(err, "admin of contract: %s", c.ContractAddress)
+		}
+	}
+	for _, m := range c.ContractState {
+		if len(m.Key) == 0 {
+			return sdkErrors.Wrapf(ErrInvalidGenesis, "empty state key for contract: %s", c.ContractAddress)
+		}
+	}
+	if n := len(c.ContractCodeHistory); n != 0 && c.ContractCodeHistory[n-1].CodeID != c.ContractInfo.CodeID {
+		return sdkErrors.Wrapf(ErrInvalidGenesis, "code history does not end with the code id of contract: %s", c.ContractAddress)
+	}
+	return nil
+}
+
+func validateGenesisAddress(addr sdk.AccAddress) error {
+	if len(addr) != sdk.AddrLen {
+		return sdkErrors.Wrapf(ErrInvalidGenesis, "invalid address length: %d", len(addr))
+	}
+	return nil
 }
 
 // ValidateGenesis performs basic validation of supply genesis data returning an
 // error for any failed validation criteria.
+// Besides the entries themselves it checks that contracts reference codes of the genesis, that there are
+// no duplicates and that the ID counters are beyond the IDs in use, so that the chain can not panic or
+// overwrite an entry later on.
 func ValidateGenesis(data GenesisState) error {
+	if err := data.Params.ValidateBasic(); err != nil {
+		return sdkErrors.Wrap(ErrInvalidGenesis, err.Error())
+	}
+
+	codeIDs := make(map[uint64]struct{}, len(data.Codes))
+	var maxCodeID uint64
+	for _, c := range data.Codes {
+		if err := c.ValidateBasic(); err != nil {
+			return err
+		}
+		if _, ok := codeIDs[c.CodeID]; ok {
+			return sdkErrors.Wrapf(ErrInvalidGenesis, "duplicate code id: %d", c.CodeID)
+		}
+		codeIDs[c.CodeID] = struct{}{}
+		if c.CodeID > maxCodeID {
+			maxCodeID = c.CodeID
+		}
+	}
+
+	contractAddrs := make(map[string]struct{}, len(data.Contracts))
+	for _, c := range data.Contracts {
+		if err := c.ValidateBasic(); err != nil {
+			return err
+		}
+		if _, ok := codeIDs[c.ContractInfo.CodeID]; !ok {
+			return sdkErrors.Wrapf(ErrInvalidGenesis, "unknown code id %d for contract: %s", c.ContractInfo.CodeID, c.ContractAddress)
+		}
+		if _, ok := contractAddrs[string(c.ContractAddress)]; ok {
+			return sdkErrors.Wrapf(ErrInvalidGenesis, "duplicate contract: %s", c.ContractAddress)
+		}
+		contractAddrs[string(c.ContractAddress)] = struct{}{}
+	}
+
+	var seenCodeSeq, seenInstanceSeq bool
+	for _, seq := range data.Sequences {
+		switch {
+		case bytes.Equal(seq.IDKey, KeyLastCodeID):
+			if seenCodeSeq {
+				return sdkErrors.Wrap(ErrInvalidGenesis, "duplicate code id sequence")
+			}
+			seenCodeSeq = true
+			if seq.Value <= maxCodeID {
+				return sdkErrors.Wrapf(ErrInvalidGenesis, "code id sequence %d must be greater than max code id %d", seq.Value, maxCodeID)
+			}
+		case bytes.Equal(seq.IDKey, KeyLastInstanceID):
+			if seenInstanceSeq {
+				return sdkErrors.Wrap(ErrInvalidGenesis, "duplicate instance id sequence")
+			}
+			seenInstanceSeq = true
+			if seq.Value <= uint64(len(data.Contracts)) {
+				return sdkErrors.Wrapf(ErrInvalidGenesis, "instance id sequence %d must be greater than number of contracts %d", seq.Value, len(data.Contracts))
+			}
+		default:
+			return sdkErrors.Wrapf(ErrInvalidGenesis, "unknown sequence: %q", string(seq.IDKey))
+		}
+	}
 	return nil
 }
@@ -0,0 +1,53 @@
+package types
+
+import (
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// WasmHooks are called by the wasm keeper after a contract lifecycle event so that other modules can react on it.
+// The hooks run within the same transaction, a panic reverts the message. They are not called for the executions
+// of the simulate execute query, which never commit their state.
+type WasmHooks interface {
+	// AfterStoreCode is called when new code was uploaded
+	AfterStoreCode(ctx sdk.Context, codeID uint64, creator sdk.AccAddress)
+	// AfterInstantiate is called when a new contract was instantiated
+	AfterInstantiate(ctx sdk.Context, codeID uint64, contractAddr sdk.AccAddress, creator sdk.AccAddress)
+	// AfterExecute is called when a contract was executed successfully
+	AfterExecute(ctx sdk.Context, contractAddr sdk.AccAddress, caller sdk.AccAddress)
+	// AfterMigrate is called when a contract was migrated to a new code
+	AfterMigrate(ctx sdk.Context, contractAddr sdk.AccAddress, oldCodeID uint64, newCodeID uint64)
+}
+
+var _ WasmHooks = MultiWasmHooks{}
+
+// MultiWasmHooks combines multiple wasm hooks, all hook functions are run in array sequence
+type MultiWasmHooks []WasmHooks
+
+// NewMultiWasmHooks creates a new MultiWasmHooks
+func NewMultiWasmHooks(hooks ...WasmHooks) MultiWasmHooks {
+	return hooks
+}
+
+func (h MultiWasmHooks) AfterStoreCode(ctx sdk.Context, codeID uint64, creator sdk.AccAddress) {
+	for i := range h {
+		h[i].AfterStoreCode(ctx, codeID, creator)
+	}
+}
+
+func (h MultiWasmHooks) AfterInstantiate(ctx sdk.Context, codeID uint64, contractAddr sdk.AccAddress, creator sdk.AccAddress) {
+	for i := range h {
+		h[i].AfterInstantiate(ctx, codeID, contractAddr, creator)
+	}
+}
+
+func (h MultiWasmHooks) AfterExecute(ctx sdk.Context, contractAddr sdk.AccAddress, caller sdk.AccAddress) {
+	for i := range h {
+		h[i].AfterExecute(ctx, contractAddr, caller)
+	}
+}
+
+func (h MultiWasmHooks) AfterMigrate(ctx sdk.Context, contractAddr sdk.AccAddress, oldCodeID uint64, newCodeID uint64) {
+	for i := range h {
+		h[i].AfterMigrate(ctx, contractAddr, oldCodeID, newCodeID)
+	}
+}
@@ -1,6 +1,8 @@
 package types
 
 import (
+	"crypto/sha256"
+
 	sdk "github.com/cosmos/cosmos-sdk/types"
 )
 
@@ -25,10 +27,21 @@ const (
 var (
 	KeyLastCodeID     = []byte("lastCodeId")
 	KeyLastInstanceID = []byte("lastContractId")
+	// KeyCodeStorageStats holds the running CodeStorageStats
+	KeyCodeStorageStats = []byte("codeStorageStats")
 
 	CodeKeyPrefix       = []byte{0x01}
 	ContractKeyPrefix   = []byte{0x02}
 	ContractStorePrefix = []byte{0x03}
+
+	ContractByCodeIDSecondaryIndexPrefix = []byte{0x04}
+	ContractHistoryStorePrefix           = []byte{0x05}
+	PinnedCodeIndexPrefix                = []byte{0x06}
+	CodeByCreatorSecondaryIndexPrefix    = []byte{0x07}
+	CodeByHashSecondaryIndexPrefix       = []byte{0x08}
+	ContractCountByCodeIDPrefix          = []byte{0x09}
+	ContractByLabelSecondaryIndexPrefix  = []byte{0x0a}
+	ContractCreatedSecondaryIndexPrefix  = []byte{0x0b}
 )
 
 // GetCodeKey constructs the key for retreiving the ID for the WASM code
@@ -46,3 +59,68 @@ func GetContractAddressKey(addr sdk.AccAddress) []byte {
 func GetContractStorePrefixKey(addr sdk.AccAddress) []byte {
 	return append(ContractStorePrefix, addr...)
 }
+
+// GetContractByCodeIDSecondaryIndexPrefix returns the prefix of the code id -> contract address index entries for a code
+func GetContractByCodeIDSecondaryIndexPrefix(codeID uint64) []byte {
+	return append(ContractByCodeIDSecondaryIndexPrefix, sdk.Uint64ToBigEndian(codeID)...)
+}
+
+// GetContractByCodeIDSecondaryIndexKey returns the key of the code id -> contract address index entry
+func GetContractByCodeIDSecondaryIndexKey(codeID uint64, contractAddr sdk.AccAddress) []byte {
+	return append(GetContractByCodeIDSecondaryIndexPrefix(codeID), contractAddr...)
+}
+
+// GetContractCountByCodeIDKey returns the key of the number of contracts in the code id -> contract address index
+func GetContractCountByCodeIDKey(codeID uint64) []byte {
+	return append(ContractCountByCodeIDPrefix, sdk.Uint64ToBigEndian(codeID)...)
+}
+
+// GetContractHistoryStoreKey returns the key of the code history of a contract
+func GetContractHistoryStoreKey(contractAddr sdk.AccAddress) []byte {
+	return append(ContractHistoryStorePrefix, contractAddr...)
+}
+
+// GetPinnedCodeIndexPrefix returns the key of the pinned code index entry
+func GetPinnedCodeIndexPrefix(codeID uint64) []byte {
+	return append(PinnedCodeIndexPrefix, sdk.Uint64ToBigEndian(codeID)...)
+}
+
+// GetCodeByCreatorSecondaryIndexPrefix returns the prefix of the creator -> code id index entries for a creator
+func GetCodeByCreatorSecondaryIndexPrefix(creator sdk.AccAddress) []byte {
+	return append(CodeByCreatorSecondaryIndexPrefix, creator...)
+}
+
+// GetCodeByCreatorSecondaryIndexKey returns the key of the creator -> code id index entry
+func GetCodeByCreatorSecondaryIndexKey(creator sdk.AccAddress, codeID uint64) []byte {
+	return append(GetCodeByCreatorSecondaryIndexPrefix(creator), sdk.Uint64ToBigEndian(codeID)...)
+}
+
+// GetCodeByHashSecondaryIndexPrefix returns the prefix of the code hash -> code id index entries for a hash
+func GetCodeByHashSecondaryIndexPrefix(codeHash []byte) []byte {
+	return append(CodeByHashSecondaryIndexPrefix, codeHash...)
+}
+
+// GetCodeByHashSecondaryIndexKey returns the key of the code hash -> code id index entry
+func GetCodeByHashSecondaryIndexKey(codeHash []byte, codeID uint64) []byte {
+	return append(GetCodeByHashSecondaryIndexPrefix(codeHash), sdk.Uint64ToBigEndian(codeID)...)
+}
+
+// GetContractByLabelSecondaryIndexPrefix returns the prefix of the creator and label -> contract address index
+// entries. The label is hashed so that the prefix has a fixed length and no label is the prefix of another.
+func GetContractByLabelSecondaryIndexPrefix(creator sdk.AccAddress, label string) []byte {
+	labelHash := sha256.Sum256([]byte(label))
+	return append(append(ContractByLabelSecondaryIndexPrefix, creator...), labelHash[:]...)
+}
+
+// GetContractByLabelSecondaryIndexKey returns the key of the creator and label -> contract address index entry
+func GetContractByLabelSecondaryIndexKey(creator sdk.AccAddress, label string, contractAddr sdk.AccAddress) []byte {
+	return append(GetContractByLabelSecondaryIndexPrefix(creator, label), contractAddr...)
+}
+
+// GetContractCreatedSecondaryIndexKey returns the key of the created position -> contract address index entry.
+// Height and tx index are big endian encoded, so that the entries are in creation order.
+func GetContractCreatedSecondaryIndexKey(created AbsoluteTxPosition, contractAddr sdk.AccAddress) []byte {
+	key := append(ContractCreatedSecondaryIndexPrefix, sdk.Uint64ToBigEndian(uint64(created.BlockHeight))...)
+	key = append(key, sdk.Uint64ToBigEndian(created.TxIndex)...)
+	return append(key, contractAddr...)
+}
@@ -2,16 +2,30 @@ package types
 
 import (
 	"encoding/json"
-	"net/http"
+	"fmt"
 	"net/url"
 	"regexp"
+	"strings"
 
 	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkErrors "github.com/cosmos/cosmos-sdk/types/errors"
 )
 
 const (
-	MaxWasmSize   = 500 * 1024
-	BuildTagRegex = "^cosmwasm-opt:"
+	// BuildTagRegex matches a full cosmwasm-opt image reference: a semver version with an optional
+	// pre-release and an optional sha256 digest, e.g. cosmwasm-opt:0.7.0@sha256:<64 hex chars>
+	BuildTagRegex = `^cosmwasm-opt:[0-9]+\.[0-9]+\.[0-9]+(-[0-9A-Za-z.-]+)?(@sha256:[0-9a-f]{64})?$`
+	// BuildImageName is the docker image that the builder tag must reference
+	BuildImageName = "cosmwasm-opt"
+	// MaxLabelSize is the longest label that can be used when instantiating a contract
+	MaxLabelSize = 128
+	// MaxSaltSize is the longest salt that can be used with MsgInstantiateContract2
+	MaxSaltSize = 64
+	// MaxFundsCoins is the highest number of distinct denoms that can be sent to a contract with a message
+	MaxFundsCoins = 32
+	// MaxFundsAmountBits is the max bit length of a coin amount that can be sent to a contract with a message.
+	// Larger amounts are rejected early so that downstream math stays far from the sdk.Int overflow bound.
+	MaxFundsAmountBits = 128
 )
 
 type MsgStoreCode struct {
@@ -22,6 +36,11 @@ type MsgStoreCode struct {
 	Source string `json:"source" yaml:"source"`
 	// Builder is a docker tag, optional
 	Builder string `json:"builder" yaml:"builder"`
+	// InstantiatePermission restricts who may instantiate the code, optional. Defaults to everybody.
+	InstantiatePermission *AccessConfig `json:"instantiate_permission,omitempty" yaml:"instantiate_permission"`
+	// DeduplicateByHash returns the existing code ID when the same code was stored before, optional.
+	// The metadata of this message is not applied to the existing code then.
+	DeduplicateByHash bool `json:"deduplicate_by_hash,omitempty" yaml:"deduplicate_by_hash"`
 }
 
 func (msg MsgStoreCode) Route() string {
@@ -33,41 +52,75 @@ func (msg MsgStoreCode) Type() string {
 }
 
 func (msg MsgStoreCode) ValidateBasic() sdk.Error {
-	if len(msg.WASMByteCode) == 0 {
-		return sdk.ErrInternal("empty wasm code")
+	if msg.Sender.Empty() {
+		return sdk.ErrInvalidAddress("missing sender")
 	}
 
-	if len(msg.WASMByteCode) > MaxWasmSize {
-		return sdk.ErrInternal("wasm code too large")
+	if len(msg.WASMByteCode) == 0 {
+		return ToSDKError(sdkErrors.Wrap(ErrInvalidWasmCode, "empty wasm code"))
 	}
 
 	if msg.Source != "" {
 		u, err := url.Parse(msg.Source)
 		if err != nil {
-			return sdk.ErrInternal("source should be a valid url")
+			return ToSDKError(sdkErrors.Wrap(ErrInvalidSource, "source should be a valid url"))
 		}
 
 		if !u.IsAbs() {
-			return sdk.ErrInternal("source should be an absolute url")
+			return ToSDKError(sdkErrors.Wrap(ErrInvalidSource, "source should be an absolute url"))
 		}
+	}
 
-		// check if the source is reachable
-		resp, err := http.Get(msg.Source)
-		if err != nil || resp.StatusCode != 200 {
-			return sdk.ErrInternal("source url is not reachable")
+	if msg.Builder != "" {
+		if err := ValidateBuilder(msg.Builder); err != nil {
+			return err
+		}
+		// the build can not be reproduced without the source
+		if msg.Source == "" {
+			return ToSDKError(sdkErrors.Wrap(ErrInvalidBuilder, "builder requires a source"))
 		}
 	}
 
-	if msg.Builder != "" {
-		ok, err := regexp.MatchString(BuildTagRegex, msg.Builder)
-		if err != nil || !ok {
-			return sdk.ErrInternal("invalid tag supplied for builder")
+	if msg.InstantiatePermission != nil {
+		if err := msg.InstantiatePermission.ValidateBasic(); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
+var (
+	buildTagRegex     = regexp.MustCompile(BuildTagRegex)
+	buildVersionRegex = regexp.MustCompile(`^[0-9]+\.[0-9]+\.[0-9]+(-[0-9A-Za-z.-]+)?$`)
+	buildDigestRegex  = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+)
+
+// ValidateBuilder ensures the builder is a reproducible build image reference matching BuildTagRegex.
+// The error names the part that is wrong so that build tooling can report it to the user.
+func ValidateBuilder(builder string) sdk.Error {
+	if buildTagRegex.MatchString(builder) {
+		return nil
+	}
+	if !strings.HasPrefix(builder, BuildImageName+":") {
+		return ToSDKError(sdkErrors.Wrapf(ErrInvalidBuilder, "must be a %s image like %s:0.7.0", BuildImageName, BuildImageName))
+	}
+	tag := strings.TrimPrefix(builder, BuildImageName+":")
+	version, digest := tag, ""
+	if pos := strings.Index(tag, "@"); pos >= 0 {
+		version, digest = tag[:pos], tag[pos+1:]
+	}
+	switch {
+	case version == "":
+		return ToSDKError(sdkErrors.Wrap(ErrInvalidBuilder, "version missing"))
+	case !buildVersionRegex.MatchString(version):
+		return ToSDKError(sdkErrors.Wrapf(ErrInvalidBuilder, "version %q is not in the format x.y.z", version))
+	case !buildDigestRegex.MatchString(digest):
+		return ToSDKError(sdkErrors.Wrap(ErrInvalidBuilder, "digest must be sha256 followed by 64 lowercase hex characters"))
+	}
+	return ToSDKError(ErrInvalidBuilder)
+}
+
 func (msg MsgStoreCode) GetSignBytes() []byte {
 	return sdk.MustSortJSON(ModuleCdc.MustMarshalJSON(msg))
 }
@@ -76,11 +129,87 @@ func (msg MsgStoreCode) GetSigners() []sdk.AccAddress {
 	return []sdk.AccAddress{msg.Sender}
 }
 
+// MsgStoreCodeAndInstantiate uploads code and instantiates a contract from it within the same message
+type MsgStoreCodeAndInstantiate struct {
+	Sender sdk.AccAddress `json:"sender" yaml:"sender"`
+	// WASMByteCode can be raw or gzip compressed
+	WASMByteCode []byte `json:"wasm_byte_code" yaml:"wasm_byte_code"`
+	// Source is a valid URI reference to the contract's source code, optional
+	Source string `json:"source" yaml:"source"`
+	// Builder is a docker tag, optional
+	Builder string `json:"builder" yaml:"builder"`
+	// Admin is an optional address that can execute migrations
+	Admin     sdk.AccAddress  `json:"admin,omitempty" yaml:"admin"`
+	Label     string          `json:"label" yaml:"label"`
+	InitMsg   json.RawMessage `json:"init_msg" yaml:"init_msg"`
+	InitFunds sdk.Coins       `json:"init_funds" yaml:"init_funds"`
+	// AdminOnlyExecute restricts execution of the contract to the admin, optional
+	AdminOnlyExecute bool `json:"admin_only_execute,omitempty" yaml:"admin_only_execute"`
+	// DeduplicateByHash instantiates the existing code when the same code was stored before, optional.
+	DeduplicateByHash bool `json:"deduplicate_by_hash,omitempty" yaml:"deduplicate_by_hash"`
+}
+
+func (msg MsgStoreCodeAndInstantiate) Route() string {
+	return RouterKey
+}
+
+func (msg MsgStoreCodeAndInstantiate) Type() string {
+	return "store-code-and-instantiate"
+}
+
+// ValidateBasic runs the checks of MsgStoreCode and MsgInstantiateContract
+func (msg MsgStoreCodeAndInstantiate) ValidateBasic() sdk.Error {
+	if err := msg.StoreCodeMsg().ValidateBasic(); err != nil {
+		return err
+	}
+	// the code id is not known before the code is stored
+	return msg.InstantiateMsg(0).ValidateBasic()
+}
+
+func (msg MsgStoreCodeAndInstantiate) GetSignBytes() []byte {
+	return sdk.MustSortJSON(ModuleCdc.MustMarshalJSON(msg))
+}
+
+func (msg MsgStoreCodeAndInstantiate) GetSigners() []sdk.AccAddress {
+	return []sdk.AccAddress{msg.Sender}
+}
+
+// StoreCodeMsg returns the store code part of the message
+func (msg MsgStoreCodeAndInstantiate) StoreCodeMsg() MsgStoreCode {
+	return MsgStoreCode{
+		Sender:       msg.Sender,
+		WASMByteCode: msg.WASMByteCode,
+		Source:       msg.Source,
+		Builder:      msg.Builder,
+
+		DeduplicateByHash: msg.DeduplicateByHash,
+	}
+}
+
+// InstantiateMsg returns the instantiate part of the message for the given code id
+func (msg MsgStoreCodeAndInstantiate) InstantiateMsg(codeID uint64) MsgInstantiateContract {
+	return MsgInstantiateContract{
+		Sender:    msg.Sender,
+		Admin:     msg.Admin,
+		Code:      codeID,
+		Label:     msg.Label,
+		InitMsg:   msg.InitMsg,
+		InitFunds: msg.InitFunds,
+
+		AdminOnlyExecute: msg.AdminOnlyExecute,
+	}
+}
+
 type MsgInstantiateContract struct {
-	Sender    sdk.AccAddress  `json:"sender" yaml:"sender"`
+	Sender sdk.AccAddress `json:"sender" yaml:"sender"`
+	// Admin is an optional address that can execute migrations
+	Admin     sdk.AccAddress  `json:"admin,omitempty" yaml:"admin"`
 	Code      uint64          `json:"code_id" yaml:"code_id"`
+	Label     string          `json:"label" yaml:"label"`
 	InitMsg   json.RawMessage `json:"init_msg" yaml:"init_msg"`
 	InitFunds sdk.Coins       `json:"init_funds" yaml:"init_funds"`
+	// AdminOnlyExecute restricts execution of the contract to the admin, optional
+	AdminOnlyExecute bool `json:"admin_only_execute,omitempty" yaml:"admin_only_execute"`
 }
 
 func (msg MsgInstantiateContract) Route() string {
@@ -92,8 +221,23 @@ func (msg MsgInstantiateContract) Type() string {
 }
 
 func (msg MsgInstantiateContract) ValidateBasic() sdk.Error {
-	if msg.InitFunds.IsAnyNegative() {
-		return sdk.ErrInvalidCoins("negative InitFunds")
+	if msg.Sender.Empty() {
+		return sdk.ErrInvalidAddress("missing sender")
+	}
+	if msg.Label == "" {
+		return ToSDKError(sdkErrors.Wrap(ErrInvalidLabel, "label is required"))
+	}
+	if len(msg.Label) > MaxLabelSize {
+		return ToSDKError(sdkErrors.Wrap(ErrInvalidLabel, "label too long"))
+	}
+	if err := validateFunds("InitFunds", msg.InitFunds); err != nil {
+		return err
+	}
+	if !json.Valid(msg.InitMsg) {
+		return ToSDKError(sdkErrors.Wrap(ErrInvalidMsg, "init msg must be valid json"))
+	}
+	if msg.AdminOnlyExecute && msg.Admin.Empty() {
+		return sdk.ErrInvalidAddress("admin only execute requires an admin")
 	}
 	return nil
 }
@@ -106,6 +250,55 @@ func (msg MsgInstantiateContract) GetSigners() []sdk.AccAddress {
 	return []sdk.AccAddress{msg.Sender}
 }
 
+// MsgInstantiateContract2 instantiates a contract at an address derived from the code id, sender and salt
+type MsgInstantiateContract2 struct {
+	Sender sdk.AccAddress `json:"sender" yaml:"sender"`
+	// Admin is an optional address that can execute migrations
+	Admin     sdk.AccAddress  `json:"admin,omitempty" yaml:"admin"`
+	Code      uint64          `json:"code_id" yaml:"code_id"`
+	Label     string          `json:"label" yaml:"label"`
+	InitMsg   json.RawMessage `json:"init_msg" yaml:"init_msg"`
+	InitFunds sdk.Coins       `json:"init_funds" yaml:"init_funds"`
+	Salt      []byte          `json:"salt" yaml:"salt"`
+	// AdminOnlyExecute restricts execution of the contract to the admin, optional
+	AdminOnlyExecute bool `json:"admin_only_execute,omitempty" yaml:"admin_only_execute"`
+}
+
+func (msg MsgInstantiateContract2) Route() string {
+	return RouterKey
+}
+
+func (msg MsgInstantiateContract2) Type() string {
+	return "instantiate2"
+}
+
+func (msg MsgInstantiateContract2) ValidateBasic() sdk.Error {
+	if len(msg.Salt) == 0 {
+		return ToSDKError(sdkErrors.Wrap(ErrInvalidMsg, "salt is required"))
+	}
+	if len(msg.Salt) > MaxSaltSize {
+		return ToSDKError(sdkErrors.Wrap(ErrInvalidMsg, "salt too long"))
+	}
+	return MsgInstantiateContract{
+		Sender:    msg.Sender,
+		Admin:     msg.Admin,
+		Code:      msg.Code,
+		Label:     msg.Label,
+		InitMsg:   msg.InitMsg,
+		InitFunds: msg.InitFunds,
+
+		AdminOnlyExecute: msg.AdminOnlyExecute,
+	}.ValidateBasic()
+}
+
+func (msg MsgInstantiateContract2) GetSignBytes() []byte {
+	return sdk.MustSortJSON(ModuleCdc.MustMarshalJSON(msg))
+}
+
+func (msg MsgInstantiateContract2) GetSigners() []sdk.AccAddress {
+	return []sdk.AccAddress{msg.Sender}
+}
+
 type MsgExecuteContract struct {
 	Sender    sdk.AccAddress  `json:"sender" yaml:"sender"`
 	Contract  sdk.AccAddress  `json:"contract" yaml:"contract"`
@@ -122,8 +315,17 @@ func (msg MsgExecuteContract) Type() string {
 }
 
 func (msg MsgExecuteContract) ValidateBasic() sdk.Error {
-	if msg.SentFunds.IsAnyNegative() {
-		return sdk.ErrInvalidCoins("negative SentFunds")
+	if msg.Sender.Empty() {
+		return sdk.ErrInvalidAddress("missing sender")
+	}
+	if msg.Contract.Empty() {
+		return sdk.ErrInvalidAddress("missing contract")
+	}
+	if err := validateFunds("SentFunds", msg.SentFunds); err != nil {
+		return err
+	}
+	if !json.Valid(msg.Msg) {
+		return ToSDKError(sdkErrors.Wrap(ErrInvalidMsg, "msg must be valid json"))
 	}
 	return nil
 }
@@ -135,3 +337,197 @@ func (msg MsgExecuteContract) GetSignBytes() []byte {
 func (msg MsgExecuteContract) GetSigners() []sdk.AccAddress {
 	return []sdk.AccAddress{msg.Sender}
 }
+
+type MsgMigrateContract struct {
+	Sender     sdk.AccAddress  `json:"sender" yaml:"sender"`
+	Contract   sdk.AccAddress  `json:"contract" yaml:"contract"`
+	Code       uint64          `json:"code_id" yaml:"code_id"`
+	MigrateMsg json.RawMessage `json:"msg" yaml:"msg"`
+}
+
+func (msg MsgMigrateContract) Route() string {
+	return RouterKey
+}
+
+func (msg MsgMigrateContract) Type() string {
+	return "migrate"
+}
+
+func (msg MsgMigrateContract) ValidateBasic() sdk.Error {
+	if msg.Sender.Empty() {
+		return sdk.ErrInvalidAddress("missing sender")
+	}
+	if msg.Contract.Empty() {
+		return sdk.ErrInvalidAddress("missing contract")
+	}
+	if msg.Code == 0 {
+		return ToSDKError(sdkErrors.Wrap(ErrInvalidMsg, "code_id is required"))
+	}
+	if !json.Valid(msg.MigrateMsg) {
+		return ToSDKError(sdkErrors.Wrap(ErrInvalidMsg, "migrate msg must be valid json"))
+	}
+	return nil
+}
+
+func (msg MsgMigrateContract) GetSignBytes() []byte {
+	return sdk.MustSortJSON(ModuleCdc.MustMarshalJSON(msg))
+}
+
+func (msg MsgMigrateContract) GetSigners() []sdk.AccAddress {
+	return []sdk.AccAddress{msg.Sender}
+}
+
+type MsgUpdateAdmin struct {
+	Sender   sdk.AccAddress `json:"sender" yaml:"sender"`
+	NewAdmin sdk.AccAddress `json:"new_admin" yaml:"new_admin"`
+	Contract sdk.AccAddress `json:"contract" yaml:"contract"`
+}
+
+func (msg MsgUpdateAdmin) Route() string {
+	return RouterKey
+}
+
+func (msg MsgUpdateAdmin) Type() string {
+	return "update-contract-admin"
+}
+
+func (msg MsgUpdateAdmin) ValidateBasic() sdk.Error {
+	if msg.Sender.Empty() {
+		return sdk.ErrInvalidAddress("missing sender")
+	}
+	if msg.Contract.Empty() {
+		return sdk.ErrInvalidAddress("missing contract")
+	}
+	if msg.NewAdmin.Empty() {
+		return sdk.ErrInvalidAddress("missing new admin")
+	}
+	return nil
+}
+
+func (msg MsgUpdateAdmin) GetSignBytes() []byte {
+	return sdk.MustSortJSON(ModuleCdc.MustMarshalJSON(msg))
+}
+
+func (msg MsgUpdateAdmin) GetSigners() []sdk.AccAddress {
+	return []sdk.AccAddress{msg.Sender}
+}
+
+// MsgUpdateInstantiateConfig sets a new instantiate permission on a code. Only the code creator may send it.
+type MsgUpdateInstantiateConfig struct {
+	Sender        sdk.AccAddress `json:"sender" yaml:"sender"`
+	CodeID        uint64         `json:"code_id" yaml:"code_id"`
+	NewPermission AccessConfig   `json:"new_permission" yaml:"new_permission"`
+	// AllowCreatorLockout confirms a new permission that no longer allows the creator to instantiate the code
+	AllowCreatorLockout bool `json:"allow_creator_lockout,omitempty" yaml:"allow_creator_lockout"`
+}
+
+func (msg MsgUpdateInstantiateConfig) Route() string {
+	return RouterKey
+}
+
+func (msg MsgUpdateInstantiateConfig) Type() string {
+	return "update-instantiate-config"
+}
+
+func (msg MsgUpdateInstantiateConfig) ValidateBasic() sdk.Error {
+	if msg.Sender.Empty() {
+		return sdk.ErrInvalidAddress("missing sender")
+	}
+	if msg.CodeID == 0 {
+		return ToSDKError(sdkErrors.Wrap(ErrInvalidMsg, "code_id is required"))
+	}
+	return msg.NewPermission.ValidateBasic()
+}
+
+func (msg MsgUpdateInstantiateConfig) GetSignBytes() []byte {
+	return sdk.MustSortJSON(ModuleCdc.MustMarshalJSON(msg))
+}
+
+func (msg MsgUpdateInstantiateConfig) GetSigners() []sdk.AccAddress {
+	return []sdk.AccAddress{msg.Sender}
+}
+
+// MsgDeprecateCode sets or clears the deprecated flag of a code. Only the code creator may send it.
+type MsgDeprecateCode struct {
+	Sender sdk.AccAddress `json:"sender" yaml:"sender"`
+	CodeID uint64         `json:"code_id" yaml:"code_id"`
+	// Deprecated is the new flag, false reverts an earlier deprecation
+	Deprecated bool `json:"deprecated" yaml:"deprecated"`
+}
+
+func (msg MsgDeprecateCode) Route() string {
+	return RouterKey
+}
+
+func (msg MsgDeprecateCode) Type() string {
+	return "deprecate-code"
+}
+
+func (msg MsgDeprecateCode) ValidateBasic() sdk.Error {
+	if msg.Sender.Empty() {
+		return sdk.ErrInvalidAddress("missing sender")
+	}
+	if msg.CodeID == 0 {
+		return ToSDKError(sdkErrors.Wrap(ErrInvalidMsg, "code_id is required"))
+	}
+	return nil
+}
+
+func (msg MsgDeprecateCode) GetSignBytes() []byte {
+	return sdk.MustSortJSON(ModuleCdc.MustMarshalJSON(msg))
+}
+
+func (msg MsgDeprecateCode) GetSigners() []sdk.AccAddress {
+	return []sdk.AccAddress{msg.Sender}
+}
+
+type MsgClearAdmin struct {
+	Sender   sdk.AccAddress `json:"sender" yaml:"sender"`
+	Contract sdk.AccAddress `json:"contract" yaml:"contract"`
+}
+
+func (msg MsgClearAdmin) Route() string {
+	return RouterKey
+}
+
+func (msg MsgClearAdmin) Type() string {
+	return "clear-contract-admin"
+}
+
+func (msg MsgClearAdmin) ValidateBasic() sdk.Error {
+	if msg.Sender.Empty() {
+		return sdk.ErrInvalidAddress("missing sender")
+	}
+	if msg.Contract.Empty() {
+		return sdk.ErrInvalidAddress("missing contract")
+	}
+	return nil
+}
+
+func (msg MsgClearAdmin) GetSignBytes() []byte {
+	return sdk.MustSortJSON(ModuleCdc.MustMarshalJSON(msg))
+}
+
+func (msg MsgClearAdmin) GetSigners() []sdk.AccAddress {
+	return []sdk.AccAddress{msg.Sender}
+}
+
+// validateFunds ensures the coins sent to a contract are sorted, valid and within the MaxFundsCoins and
+// MaxFundsAmountBits bounds. The name is used in the error messages.
+func validateFunds(name string, funds sdk.Coins) sdk.Error {
+	if funds.IsAnyNegative() {
+		return sdk.ErrInvalidCoins("negative " + name)
+	}
+	if len(funds) > MaxFundsCoins {
+		return sdk.ErrInvalidCoins("too many " + name + " denoms")
+	}
+	if !funds.IsValid() {
+		return sdk.ErrInvalidCoins(name + " must have valid denoms in sorted order without duplicates or zero amounts: " + funds.String())
+	}
+	for _, c := range funds {
+		if c.Amount.BigInt().BitLen() > MaxFundsAmountBits {
+			return sdk.ErrInvalidCoins(fmt.Sprintf("%s amount of %s exceeds %d bits", name, c.Denom, MaxFundsAmountBits))
+		}
+	}
+	return nil
+}
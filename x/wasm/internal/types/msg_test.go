@@ -0,0 +1,53 @@
+package types
+
+import (
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMsgMigrateContractValidation(t *testing.T) {
+	sender := sdk.AccAddress(make([]byte, sdk.AddrLen))
+	contract := sdk.AccAddress(append(make([]byte, sdk.AddrLen-1), 1))
+
+	specs := map[string]struct {
+		src    MsgMigrateContract
+		expErr bool
+	}{
+		"all good": {
+			src: MsgMigrateContract{Sender: sender, Contract: contract, Code: 1, MigrateMsg: []byte(`{"foo":"bar"}`)},
+		},
+		"missing sender": {
+			src:    MsgMigrateContract{Contract: contract, Code: 1, MigrateMsg: []byte(`{}`)},
+			expErr: true,
+		},
+		"missing contract": {
+			src:    MsgMigrateContract{Sender: sender, Code: 1, MigrateMsg: []byte(`{}`)},
+			expErr: true,
+		},
+		"missing code id": {
+			src:    MsgMigrateContract{Sender: sender, Contract: contract, MigrateMsg: []byte(`{}`)},
+			expErr: true,
+		},
+		"missing migrate msg": {
+			src:    MsgMigrateContract{Sender: sender, Contract: contract, Code: 1},
+			expErr: true,
+		},
+		"invalid migrate msg": {
+			src:    MsgMigrateContract{Sender: sender, Contract: contract, Code: 1, MigrateMsg: []byte("not json")},
+			expErr: true,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			err := spec.src.ValidateBasic()
+			if !spec.expErr {
+				require.Nil(t, err)
+				return
+			}
+			assert.NotNil(t, err)
+		})
+	}
+}
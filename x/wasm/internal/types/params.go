@@ -0,0 +1,261 @@
+package types
+
+import (
+	"fmt"
+
+	"github.com/cosmos/cosmos-sdk/x/params"
+)
+
+const (
+	// DefaultParamspace for params keeper
+	DefaultParamspace = ModuleName
+	// DefaultMaxWasmCodeSize limit max bytes read to prevent gzip bombs
+	DefaultMaxWasmCodeSize = 512 * 1024
+	// DefaultMaxInitMsgSize limit max bytes of the init msg of a new contract
+	DefaultMaxInitMsgSize = 100 * 1024
+	// DefaultMaxExecuteMsgSize limit max bytes of the msg of a contract execution
+	DefaultMaxExecuteMsgSize = 100 * 1024
+	// DefaultUploadGasPerByte is the gas charged for every byte of uploaded wasm code
+	DefaultUploadGasPerByte = 3
+	// DefaultMaxLabelSize limit max bytes of a contract label
+	DefaultMaxLabelSize = MaxLabelSize
+	// DefaultMaxFundsCoins limit max number of distinct denoms sent to a contract with a message
+	DefaultMaxFundsCoins = MaxFundsCoins
+	// DefaultContractStoreReadGasPerByte is the extra gas charged for every byte a contract reads from its storage
+	DefaultContractStoreReadGasPerByte = 0
+	// DefaultContractStoreWriteGasPerByte is the extra gas charged for every byte a contract writes to its storage
+	DefaultContractStoreWriteGasPerByte = 0
+	// DefaultMaxContractGas does not limit the gas of a single contract call
+	DefaultMaxContractGas = 0
+	// DefaultMaxQueryResultEntries limit max number of state entries returned by a query without pagination
+	DefaultMaxQueryResultEntries = 10_000
+	// DefaultMaxContractInfosBatch limit max number of addresses of a contract-infos query
+	DefaultMaxContractInfosBatch = 100
+	// DefaultMaxMigrateAllContracts limit max number of contracts migrated by a single migrate all contracts proposal
+	DefaultMaxMigrateAllContracts = 100
+	// DefaultAllowGzipUpload accepts gzip compressed wasm code
+	DefaultAllowGzipUpload = true
+)
+
+// Types of the staking messages that contracts can dispatch
+const (
+	StakingMsgTypeDelegate   = "delegate"
+	StakingMsgTypeUndelegate = "begin_unbonding"
+	StakingMsgTypeRedelegate = "begin_redelegate"
+)
+
+// Parameter store keys
+var (
+	ParamStoreKeyMaxWasmCodeSize     = []byte("MaxWasmCodeSize")
+	ParamStoreKeyMaxInitMsgSize      = []byte("MaxInitMsgSize")
+	ParamStoreKeyMaxExecuteMsgSize   = []byte("MaxExecuteMsgSize")
+	ParamStoreKeyUploadGasPerByte    = []byte("UploadGasPerByte")
+	ParamStoreKeyMaxLabelSize        = []byte("MaxLabelSize")
+	ParamStoreKeyMaxFundsCoins       = []byte("MaxFundsCoins")
+	ParamStoreKeyContractStakingMsgs = []byte("ContractStakingMsgs")
+
+	ParamStoreKeyContractStoreReadGasPerByte  = []byte("ContractStoreReadGasPerByte")
+	ParamStoreKeyContractStoreWriteGasPerByte = []byte("ContractStoreWriteGasPerByte")
+	ParamStoreKeyMaxContractGas               = []byte("MaxContractGas")
+	ParamStoreKeyMaxQueryResultEntries        = []byte("MaxQueryResultEntries")
+	ParamStoreKeyAcceptedFundDenoms           = []byte("AcceptedFundDenoms")
+	ParamStoreKeyRejectContractReentrancy     = []byte("RejectContractReentrancy")
+	ParamStoreKeyAllowGzipUpload              = []byte("AllowGzipUpload")
+	ParamStoreKeyMaxContractInfosBatch        = []byte("MaxContractInfosBatch")
+	ParamStoreKeyRejectDeprecatedInstantiate  = []byte("RejectDeprecatedInstantiate")
+	ParamStoreKeyMaxMigrateAllContracts       = []byte("MaxMigrateAllContracts")
+)
+
+// Params defines the set of wasm parameters.
+type Params struct {
+	MaxWasmCodeSize   uint64 `json:"max_wasm_code_size" yaml:"max_wasm_code_size"`
+	MaxInitMsgSize    uint64 `json:"max_init_msg_size" yaml:"max_init_msg_size"`
+	MaxExecuteMsgSize uint64 `json:"max_execute_msg_size" yaml:"max_execute_msg_size"`
+	// UploadGasPerByte is charged on store code for every byte of the wasm code as submitted, so the total
+	// upload gas is UploadGasPerByte * len(WASMByteCode). Zero disables the charge.
+	UploadGasPerByte uint64 `json:"upload_gas_per_byte" yaml:"upload_gas_per_byte"`
+	// MaxLabelSize and MaxFundsCoins can only tighten the MaxLabelSize and MaxFundsCoins bounds
+	// that are enforced by ValidateBasic of the messages.
+	MaxLabelSize  uint64 `json:"max_label_size" yaml:"max_label_size"`
+	MaxFundsCoins uint64 `json:"max_funds_coins" yaml:"max_funds_coins"`
+	// ContractStakingMsgs are the staking message types that contracts can dispatch with themselves as
+	// delegator. Empty disables staking from contracts.
+	ContractStakingMsgs []string `json:"contract_staking_msgs" yaml:"contract_staking_msgs"`
+	// ContractStoreReadGasPerByte and ContractStoreWriteGasPerByte are charged for the key and value bytes of
+	// every get, set and remove of a contract on its storage. They add to the per byte costs of the sdk gas
+	// kv store. Zero disables the charge.
+	ContractStoreReadGasPerByte  uint64 `json:"contract_store_read_gas_per_byte" yaml:"contract_store_read_gas_per_byte"`
+	ContractStoreWriteGasPerByte uint64 `json:"contract_store_write_gas_per_byte" yaml:"contract_store_write_gas_per_byte"`
+	// MaxContractGas is the sdk gas a single instantiate or execute call of a contract can consume, within the
+	// gas limit of the transaction. Zero means no limit besides the transaction gas limit.
+	MaxContractGas uint64 `json:"max_contract_gas" yaml:"max_contract_gas"`
+	// MaxQueryResultEntries is the max number of state entries the all state queries collect in memory.
+	// Larger results are rejected and have to be read with the paginated query.
+	MaxQueryResultEntries uint64 `json:"max_query_result_entries" yaml:"max_query_result_entries"`
+	// AcceptedFundDenoms are the only denoms that can be sent to a contract with an instantiate or execute
+	// message. Empty accepts all denoms.
+	AcceptedFundDenoms []string `json:"accepted_fund_denoms" yaml:"accepted_fund_denoms"`
+	// RejectContractReentrancy fails a contract call when the contract is already on the call stack of the
+	// messages dispatched from contracts. When false such calls run and are flagged with an event.
+	RejectContractReentrancy bool `json:"reject_contract_reentrancy" yaml:"reject_contract_reentrancy"`
+	// AllowGzipUpload accepts gzip compressed wasm code on store code. When false only raw wasm is accepted.
+	AllowGzipUpload bool `json:"allow_gzip_upload" yaml:"allow_gzip_upload"`
+	// MaxContractInfosBatch is the max number of addresses a single contract-infos query can look up
+	MaxContractInfosBatch uint64 `json:"max_contract_infos_batch" yaml:"max_contract_infos_batch"`
+	// RejectDeprecatedInstantiate fails the instantiation of deprecated codes. When false they are
+	// instantiated with a warning event.
+	RejectDeprecatedInstantiate bool `json:"reject_deprecated_instantiate" yaml:"reject_deprecated_instantiate"`
+	// MaxMigrateAllContracts is the max number of contracts a single migrate all contracts proposal migrates.
+	// Contracts beyond this bound stay on the old code and are left for a follow-up proposal.
+	MaxMigrateAllContracts uint64 `json:"max_migrate_all_contracts" yaml:"max_migrate_all_contracts"`
+}
+
+// ParamKeyTable returns the parameter key table.
+func ParamKeyTable() params.KeyTable {
+	return params.NewKeyTable().RegisterParamSet(&Params{})
+}
+
+// DefaultParams returns default wasm parameters
+func DefaultParams() Params {
+	return Params{
+		MaxWasmCodeSize:   DefaultMaxWasmCodeSize,
+		MaxInitMsgSize:    DefaultMaxInitMsgSize,
+		MaxExecuteMsgSize: DefaultMaxExecuteMsgSize,
+		UploadGasPerByte:  DefaultUploadGasPerByte,
+		MaxLabelSize:      DefaultMaxLabelSize,
+		MaxFundsCoins:     DefaultMaxFundsCoins,
+		ContractStakingMsgs: []string{
+			StakingMsgTypeDelegate,
+			StakingMsgTypeUndelegate,
+			StakingMsgTypeRedelegate,
+		},
+		ContractStoreReadGasPerByte:  DefaultContractStoreReadGasPerByte,
+		ContractStoreWriteGasPerByte: DefaultContractStoreWriteGasPerByte,
+		MaxContractGas:               DefaultMaxContractGas,
+		MaxQueryResultEntries:        DefaultMaxQueryResultEntries,
+		AllowGzipUpload:              DefaultAllowGzipUpload,
+		MaxContractInfosBatch:        DefaultMaxContractInfosBatch,
+		MaxMigrateAllContracts:       DefaultMaxMigrateAllContracts,
+	}
+}
+
+func (p Params) String() string {
+	return fmt.Sprintf(`Wasm Params:
+  Max Wasm Code Size:   %d
+  Max Init Msg Size:    %d
+  Max Execute Msg Size: %d
+  Upload Gas Per Byte:  %d
+  Max Label Size:       %d
+  Max Funds Coins:      %d
+  Contract Staking Msgs: %v
+  Contract Store Read Gas Per Byte:  %d
+  Contract Store Write Gas Per Byte: %d
+  Max Contract Gas:     %d
+  Max Query Result Entries: %d
+  Accepted Fund Denoms: %v
+  Reject Contract Reentrancy: %t
+  Allow Gzip Upload:    %t
+  Max Contract Infos Batch: %d
+  Reject Deprecated Instantiate: %t
+  Max Migrate All Contracts: %d`, p.MaxWasmCodeSize, p.MaxInitMsgSize, p.MaxExecuteMsgSize, p.UploadGasPerByte,
+		p.MaxLabelSize, p.MaxFundsCoins, p.ContractStakingMsgs, p.ContractStoreReadGasPerByte, p.ContractStoreWriteGasPerByte,
+		p.MaxContractGas, p.MaxQueryResultEntries, p.AcceptedFundDenoms, p.RejectContractReentrancy, p.AllowGzipUpload, p.MaxContractInfosBatch, p.RejectDeprecatedInstantiate,
+		p.MaxMigrateAllContracts)
+}
+
+// ParamSetPairs returns the parameter set pairs.
+func (p *Params) ParamSetPairs() params.ParamSetPairs {
+	return params.ParamSetPairs{
+		{Key: ParamStoreKeyMaxWasmCodeSize, Value: &p.MaxWasmCodeSize},
+		{Key: ParamStoreKeyMaxInitMsgSize, Value: &p.MaxInitMsgSize},
+		{Key: ParamStoreKeyMaxExecuteMsgSize, Value: &p.MaxExecuteMsgSize},
+		{Key: ParamStoreKeyUploadGasPerByte, Value: &p.UploadGasPerByte},
+		{Key: ParamStoreKeyMaxLabelSize, Value: &p.MaxLabelSize},
+		{Key: ParamStoreKeyMaxFundsCoins, Value: &p.MaxFundsCoins},
+		{Key: ParamStoreKeyContractStakingMsgs, Value: &p.ContractStakingMsgs},
+		{Key: ParamStoreKeyContractStoreReadGasPerByte, Value: &p.ContractStoreReadGasPerByte},
+		{Key: ParamStoreKeyContractStoreWriteGasPerByte, Value: &p.ContractStoreWriteGasPerByte},
+		{Key: ParamStoreKeyMaxContractGas, Value: &p.MaxContractGas},
+		{Key: ParamStoreKeyMaxQueryResultEntries, Value: &p.MaxQueryResultEntries},
+		{Key: ParamStoreKeyAcceptedFundDenoms, Value: &p.AcceptedFundDenoms},
+		{Key: ParamStoreKeyRejectContractReentrancy, Value: &p.RejectContractReentrancy},
+		{Key: ParamStoreKeyAllowGzipUpload, Value: &p.AllowGzipUpload},
+		{Key: ParamStoreKeyMaxContractInfosBatch, Value: &p.MaxContractInfosBatch},
+		{Key: ParamStoreKeyRejectDeprecatedInstantiate, Value: &p.RejectDeprecatedInstantiate},
+		{Key: ParamStoreKeyMaxMigrateAllContracts, Value: &p.MaxMigrateAllContracts},
+	}
+}
+
+// ValidateBasic performs basic validation on wasm parameters
+func (p Params) ValidateBasic() error {
+	if p.MaxWasmCodeSize == 0 {
+		return fmt.Errorf("max wasm code size must be positive: %d", p.MaxWasmCodeSize)
+	}
+	if p.MaxInitMsgSize == 0 {
+		return fmt.Errorf("max init msg size must be positive: %d", p.MaxInitMsgSize)
+	}
+	if p.MaxExecuteMsgSize == 0 {
+		return fmt.Errorf("max execute msg size must be positive: %d", p.MaxExecuteMsgSize)
+	}
+	if p.MaxLabelSize == 0 || p.MaxLabelSize > MaxLabelSize {
+		return fmt.Errorf("max label size must be between 1 and %d: %d", MaxLabelSize, p.MaxLabelSize)
+	}
+	if p.MaxFundsCoins == 0 || p.MaxFundsCoins > MaxFundsCoins {
+		return fmt.Errorf("max funds coins must be between 1 and %d: %d", MaxFundsCoins, p.MaxFundsCoins)
+	}
+	if p.MaxQueryResultEntries == 0 {
+		return fmt.Errorf("max query result entries must be positive: %d", p.MaxQueryResultEntries)
+	}
+	if p.MaxContractInfosBatch == 0 {
+		return fmt.Errorf("max contract infos batch must be positive: %d", p.MaxContractInfosBatch)
+	}
+	if p.MaxMigrateAllContracts == 0 {
+		return fmt.Errorf("max migrate all contracts must be positive: %d", p.MaxMigrateAllContracts)
+	}
+	seen := make(map[string]bool, len(p.ContractStakingMsgs))
+	for _, msgType := range p.ContractStakingMsgs {
+		switch msgType {
+		case StakingMsgTypeDelegate, StakingMsgTypeUndelegate, StakingMsgTypeRedelegate:
+		default:
+			return fmt.Errorf("unknown contract staking msg type: %q", msgType)
+		}
+		if seen[msgType] {
+			return fmt.Errorf("duplicate contract staking msg type: %q", msgType)
+		}
+		seen[msgType] = true
+	}
+	seenDenoms := make(map[string]bool, len(p.AcceptedFundDenoms))
+	for _, denom := range p.AcceptedFundDenoms {
+		if denom == "" {
+			return fmt.Errorf("empty accepted fund denom")
+		}
+		if seenDenoms[denom] {
+			return fmt.Errorf("duplicate accepted fund denom: %q", denom)
+		}
+		seenDenoms[denom] = true
+	}
+	return nil
+}
+
+// ContractStakingMsgAllowed returns true when contracts can dispatch staking messages of the given type
+func (p Params) ContractStakingMsgAllowed(msgType string) bool {
+	for _, t := range p.ContractStakingMsgs {
+		if t == msgType {
+			return true
+		}
+	}
+	return false
+}
+
+// FundDenomAccepted returns true when contracts can receive coins of the given denom with a message
+func (p Params) FundDenomAccepted(denom string) bool {
+	if len(p.AcceptedFundDenoms) == 0 {
+		return true
+	}
+	for _, d := range p.AcceptedFundDenoms {
+		if d == denom {
+			return true
+		}
+	}
+	return false
+}
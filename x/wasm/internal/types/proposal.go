@@ -0,0 +1,448 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkErrors "github.com/cosmos/cosmos-sdk/types/errors"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+)
+
+const (
+	ProposalTypeStoreCode       = "StoreCode"
+	ProposalTypeMigrateContract = "MigrateContract"
+	ProposalTypePinCodes        = "PinCodes"
+	ProposalTypeUnpinCodes      = "UnpinCodes"
+
+	ProposalTypeImportContractState     = "ImportContractState"
+	ProposalTypeUpdateInstantiateConfig = "UpdateInstantiateConfig"
+	ProposalTypeDeprecateCode           = "DeprecateCode"
+	ProposalTypeMigrateAllContracts     = "MigrateAllContracts"
+)
+
+func init() { // register new content types with the sdk
+	govtypes.RegisterProposalType(ProposalTypeStoreCode)
+	govtypes.RegisterProposalType(ProposalTypeMigrateContract)
+	govtypes.RegisterProposalType(ProposalTypePinCodes)
+	govtypes.RegisterProposalType(ProposalTypeUnpinCodes)
+	govtypes.RegisterProposalType(ProposalTypeImportContractState)
+	govtypes.RegisterProposalType(ProposalTypeUpdateInstantiateConfig)
+	govtypes.RegisterProposalType(ProposalTypeDeprecateCode)
+	govtypes.RegisterProposalType(ProposalTypeMigrateAllContracts)
+	govtypes.RegisterProposalTypeCodec(StoreCodeProposal{}, "wasm/store-proposal")
+	govtypes.RegisterProposalTypeCodec(MigrateContractProposal{}, "wasm/migrate-proposal")
+	govtypes.RegisterProposalTypeCodec(PinCodesProposal{}, "wasm/pin-codes-proposal")
+	govtypes.RegisterProposalTypeCodec(UnpinCodesProposal{}, "wasm/unpin-codes-proposal")
+	govtypes.RegisterProposalTypeCodec(ImportContractStateProposal{}, "wasm/import-contract-state-proposal")
+	govtypes.RegisterProposalTypeCodec(UpdateInstantiateConfigProposal{}, "wasm/update-instantiate-config-proposal")
+	govtypes.RegisterProposalTypeCodec(DeprecateCodeProposal{}, "wasm/deprecate-code-proposal")
+	govtypes.RegisterProposalTypeCodec(MigrateAllContractsProposal{}, "wasm/migrate-all-contracts-proposal")
+}
+
+// StoreCodeProposal uploads wasm code on behalf of governance
+type StoreCodeProposal struct {
+	Title       string `json:"title" yaml:"title"`
+	Description string `json:"description" yaml:"description"`
+	// RunAs is the address that is set as code creator
+	RunAs sdk.AccAddress `json:"run_as" yaml:"run_as"`
+	// WASMByteCode can be raw or gzip compressed
+	WASMByteCode []byte `json:"wasm_byte_code" yaml:"wasm_byte_code"`
+	// Source is a valid URI reference to the contract's source code, optional
+	Source string `json:"source" yaml:"source"`
+	// Builder is a docker tag, optional
+	Builder string `json:"builder" yaml:"builder"`
+	// InstantiatePermission restricts who may instantiate the code, optional. Defaults to everybody.
+	InstantiatePermission *AccessConfig `json:"instantiate_permission,omitempty" yaml:"instantiate_permission"`
+}
+
+// GetTitle returns the title of the proposal
+func (p StoreCodeProposal) GetTitle() string { return p.Title }
+
+// GetDescription returns the human readable description of the proposal
+func (p StoreCodeProposal) GetDescription() string { return p.Description }
+
+// ProposalRoute returns the routing key of the proposal
+func (p StoreCodeProposal) ProposalRoute() string { return RouterKey }
+
+// ProposalType returns the type of the proposal
+func (p StoreCodeProposal) ProposalType() string { return ProposalTypeStoreCode }
+
+// ValidateBasic validates the proposal with the same rules as MsgStoreCode
+func (p StoreCodeProposal) ValidateBasic() sdk.Error {
+	if err := govtypes.ValidateAbstract(sdk.CodespaceType(DefaultCodespace), p); err != nil {
+		return err
+	}
+	return MsgStoreCode{
+		Sender:       p.RunAs,
+		WASMByteCode: p.WASMByteCode,
+		Source:       p.Source,
+		Builder:      p.Builder,
+
+		InstantiatePermission: p.InstantiatePermission,
+	}.ValidateBasic()
+}
+
+// String implements the Stringer interface.
+func (p StoreCodeProposal) String() string {
+	return fmt.Sprintf(`Store Code Proposal:
+  Title:       %s
+  Description: %s
+  Run as:      %s
+  WasmCode:    %d bytes
+  Source:      %s
+  Builder:     %s
+`, p.Title, p.Description, p.RunAs, len(p.WASMByteCode), p.Source, p.Builder)
+}
+
+// MigrateContractProposal migrates a contract to a new code on behalf of governance. There is no admin check,
+// so this works for contracts without an admin, too.
+type MigrateContractProposal struct {
+	Title       string `json:"title" yaml:"title"`
+	Description string `json:"description" yaml:"description"`
+	// Contract is the address of the contract to migrate
+	Contract sdk.AccAddress `json:"contract" yaml:"contract"`
+	// CodeID references the new code
+	CodeID uint64 `json:"code_id" yaml:"code_id"`
+	// MigrateMsg is the json encoded message passed to the contract
+	MigrateMsg json.RawMessage `json:"msg" yaml:"msg"`
+	// RunAs is the address that is reported as sender of the migration
+	RunAs sdk.AccAddress `json:"run_as" yaml:"run_as"`
+}
+
+// GetTitle returns the title of the proposal
+func (p MigrateContractProposal) GetTitle() string { return p.Title }
+
+// GetDescription returns the human readable description of the proposal
+func (p MigrateContractProposal) GetDescription() string { return p.Description }
+
+// ProposalRoute returns the routing key of the proposal
+func (p MigrateContractProposal) ProposalRoute() string { return RouterKey }
+
+// ProposalType returns the type of the proposal
+func (p MigrateContractProposal) ProposalType() string { return ProposalTypeMigrateContract }
+
+// ValidateBasic validates the proposal
+func (p MigrateContractProposal) ValidateBasic() sdk.Error {
+	if err := govtypes.ValidateAbstract(sdk.CodespaceType(DefaultCodespace), p); err != nil {
+		return err
+	}
+	if p.Contract.Empty() {
+		return sdk.ErrInvalidAddress("missing contract")
+	}
+	if p.CodeID == 0 {
+		return ToSDKError(sdkErrors.Wrap(ErrInvalidMsg, "code_id is required"))
+	}
+	if p.RunAs.Empty() {
+		return sdk.ErrInvalidAddress("missing run as address")
+	}
+	if !json.Valid(p.MigrateMsg) {
+		return ToSDKError(sdkErrors.Wrap(ErrInvalidMsg, "migrate msg must be valid json"))
+	}
+	return nil
+}
+
+// String implements the Stringer interface.
+func (p MigrateContractProposal) String() string {
+	return fmt.Sprintf(`Migrate Contract Proposal:
+  Title:       %s
+  Description: %s
+  Contract:    %s
+  Code id:     %d
+  Run as:      %s
+  Msg:         %s
+`, p.Title, p.Description, p.Contract, p.CodeID, p.RunAs, p.MigrateMsg)
+}
+
+// PinCodesProposal adds codes to the pinned code index on behalf of governance
+type PinCodesProposal struct {
+	Title       string `json:"title" yaml:"title"`
+	Description string `json:"description" yaml:"description"`
+	// CodeIDs references the codes to be pinned
+	CodeIDs []uint64 `json:"code_ids" yaml:"code_ids"`
+}
+
+// GetTitle returns the title of the proposal
+func (p PinCodesProposal) GetTitle() string { return p.Title }
+
+// GetDescription returns the human readable description of the proposal
+func (p PinCodesProposal) GetDescription() string { return p.Description }
+
+// ProposalRoute returns the routing key of the proposal
+func (p PinCodesProposal) ProposalRoute() string { return RouterKey }
+
+// ProposalType returns the type of the proposal
+func (p PinCodesProposal) ProposalType() string { return ProposalTypePinCodes }
+
+// ValidateBasic validates the proposal
+func (p PinCodesProposal) ValidateBasic() sdk.Error {
+	if err := govtypes.ValidateAbstract(sdk.CodespaceType(DefaultCodespace), p); err != nil {
+		return err
+	}
+	return validateCodeIDs(p.CodeIDs)
+}
+
+// String implements the Stringer interface.
+func (p PinCodesProposal) String() string {
+	return fmt.Sprintf(`Pin Wasm Codes Proposal:
+  Title:       %s
+  Description: %s
+  Codes:       %v
+`, p.Title, p.Description, p.CodeIDs)
+}
+
+// UnpinCodesProposal removes codes from the pinned code index on behalf of governance
+type UnpinCodesProposal struct {
+	Title       string `json:"title" yaml:"title"`
+	Description string `json:"description" yaml:"description"`
+	// CodeIDs references the codes to be unpinned
+	CodeIDs []uint64 `json:"code_ids" yaml:"code_ids"`
+}
+
+// GetTitle returns the title of the proposal
+func (p UnpinCodesProposal) GetTitle() string { return p.Title }
+
+// GetDescription returns the human readable description of the proposal
+func (p UnpinCodesProposal) GetDescription() string { return p.Description }
+
+// ProposalRoute returns the routing key of the proposal
+func (p UnpinCodesProposal) ProposalRoute() string { return RouterKey }
+
+// ProposalType returns the type of the proposal
+func (p UnpinCodesProposal) ProposalType() string { return ProposalTypeUnpinCodes }
+
+// ValidateBasic validates the proposal
+func (p UnpinCodesProposal) ValidateBasic() sdk.Error {
+	if err := govtypes.ValidateAbstract(sdk.CodespaceType(DefaultCodespace), p); err != nil {
+		return err
+	}
+	return validateCodeIDs(p.CodeIDs)
+}
+
+// String implements the Stringer interface.
+func (p UnpinCodesProposal) String() string {
+	return fmt.Sprintf(`Unpin Wasm Codes Proposal:
+  Title:       %s
+  Description: %s
+  Codes:       %v
+`, p.Title, p.Description, p.CodeIDs)
+}
+
+// ImportContractStateProposal writes state entries into the store of a contract on behalf of governance.
+// Entries with existing keys are overwritten, all other state of the contract is kept.
+type ImportContractStateProposal struct {
+	Title       string `json:"title" yaml:"title"`
+	Description string `json:"description" yaml:"description"`
+	// Contract is the address of the contract that gets the state
+	Contract sdk.AccAddress `json:"contract" yaml:"contract"`
+	// Models are the raw key value entries to write
+	Models []Model `json:"models" yaml:"models"`
+}
+
+// GetTitle returns the title of the proposal
+func (p ImportContractStateProposal) GetTitle() string { return p.Title }
+
+// GetDescription returns the human readable description of the proposal
+func (p ImportContractStateProposal) GetDescription() string { return p.Description }
+
+// ProposalRoute returns the routing key of the proposal
+func (p ImportContractStateProposal) ProposalRoute() string { return RouterKey }
+
+// ProposalType returns the type of the proposal
+func (p ImportContractStateProposal) ProposalType() string { return ProposalTypeImportContractState }
+
+// ValidateBasic validates the proposal
+func (p ImportContractStateProposal) ValidateBasic() sdk.Error {
+	if err := govtypes.ValidateAbstract(sdk.CodespaceType(DefaultCodespace), p); err != nil {
+		return err
+	}
+	if p.Contract.Empty() {
+		return sdk.ErrInvalidAddress("missing contract")
+	}
+	if len(p.Models) == 0 {
+		return ToSDKError(sdkErrors.Wrap(ErrInvalidMsg, "models must not be empty"))
+	}
+	return ToSDKError(ValidateModels(p.Models))
+}
+
+// String implements the Stringer interface.
+func (p ImportContractStateProposal) String() string {
+	return fmt.Sprintf(`Import Contract State Proposal:
+  Title:       %s
+  Description: %s
+  Contract:    %s
+  Models:      %d
+`, p.Title, p.Description, p.Contract, len(p.Models))
+}
+
+// UpdateInstantiateConfigProposal sets a new instantiate permission on a code on behalf of governance.
+// There is no creator check, so this works for any code.
+type UpdateInstantiateConfigProposal struct {
+	Title       string `json:"title" yaml:"title"`
+	Description string `json:"description" yaml:"description"`
+	// CodeID references the code to update
+	CodeID uint64 `json:"code_id" yaml:"code_id"`
+	// NewPermission replaces the instantiate permission of the code
+	NewPermission AccessConfig `json:"new_permission" yaml:"new_permission"`
+}
+
+// GetTitle returns the title of the proposal
+func (p UpdateInstantiateConfigProposal) GetTitle() string { return p.Title }
+
+// GetDescription returns the human readable description of the proposal
+func (p UpdateInstantiateConfigProposal) GetDescription() string { return p.Description }
+
+// ProposalRoute returns the routing key of the proposal
+func (p UpdateInstantiateConfigProposal) ProposalRoute() string { return RouterKey }
+
+// ProposalType returns the type of the proposal
+func (p UpdateInstantiateConfigProposal) ProposalType() string {
+	return ProposalTypeUpdateInstantiateConfig
+}
+
+// ValidateBasic validates the proposal
+func (p UpdateInstantiateConfigProposal) ValidateBasic() sdk.Error {
+	if err := govtypes.ValidateAbstract(sdk.CodespaceType(DefaultCodespace), p); err != nil {
+		return err
+	}
+	if p.CodeID == 0 {
+		return ToSDKError(sdkErrors.Wrap(ErrInvalidMsg, "code_id is required"))
+	}
+	return p.NewPermission.ValidateBasic()
+}
+
+// String implements the Stringer interface.
+func (p UpdateInstantiateConfigProposal) String() string {
+	return fmt.Sprintf(`Update Instantiate Config Proposal:
+  Title:       %s
+  Description: %s
+  Code id:     %d
+  Permission:  %s
+`, p.Title, p.Description, p.CodeID, p.NewPermission.Type)
+}
+
+// DeprecateCodeProposal sets or clears the deprecated flag of a code on behalf of governance.
+type DeprecateCodeProposal struct {
+	Title       string `json:"title" yaml:"title"`
+	Description string `json:"description" yaml:"description"`
+	// CodeID references the code to update
+	CodeID uint64 `json:"code_id" yaml:"code_id"`
+	// Deprecated is the new flag, false reverts an earlier deprecation
+	Deprecated bool `json:"deprecated" yaml:"deprecated"`
+}
+
+// GetTitle returns the title of the proposal
+func (p DeprecateCodeProposal) GetTitle() string { return p.Title }
+
+// GetDescription returns the human readable description of the proposal
+func (p DeprecateCodeProposal) GetDescription() string { return p.Description }
+
+// ProposalRoute returns the routing key of the proposal
+func (p DeprecateCodeProposal) ProposalRoute() string { return RouterKey }
+
+// ProposalType returns the type of the proposal
+func (p DeprecateCodeProposal) ProposalType() string {
+	return ProposalTypeDeprecateCode
+}
+
+// ValidateBasic validates the proposal
+func (p DeprecateCodeProposal) ValidateBasic() sdk.Error {
+	if err := govtypes.ValidateAbstract(sdk.CodespaceType(DefaultCodespace), p); err != nil {
+		return err
+	}
+	if p.CodeID == 0 {
+		return ToSDKError(sdkErrors.Wrap(ErrInvalidMsg, "code_id is required"))
+	}
+	return nil
+}
+
+// String implements the Stringer interface.
+func (p DeprecateCodeProposal) String() string {
+	return fmt.Sprintf(`Deprecate Code Proposal:
+  Title:       %s
+  Description: %s
+  Code id:     %d
+  Deprecated:  %t
+`, p.Title, p.Description, p.CodeID, p.Deprecated)
+}
+
+// MigrateAllContractsProposal migrates the contracts of a code to a new code on behalf of governance, all with
+// the same migrate msg. A single proposal migrates up to MaxMigrateAllContracts contracts, the remaining ones
+// need another proposal.
+type MigrateAllContractsProposal struct {
+	Title       string `json:"title" yaml:"title"`
+	Description string `json:"description" yaml:"description"`
+	// OldCodeID references the code of the contracts to migrate
+	OldCodeID uint64 `json:"old_code_id" yaml:"old_code_id"`
+	// NewCodeID references the code the contracts are migrated to
+	NewCodeID uint64 `json:"new_code_id" yaml:"new_code_id"`
+	// MigrateMsg is the json encoded message passed to every contract
+	MigrateMsg json.RawMessage `json:"msg" yaml:"msg"`
+	// RunAs is the address that is reported as sender of the migrations
+	RunAs sdk.AccAddress `json:"run_as" yaml:"run_as"`
+}
+
+// GetTitle returns the title of the proposal
+func (p MigrateAllContractsProposal) GetTitle() string { return p.Title }
+
+// GetDescription returns the human readable description of the proposal
+func (p MigrateAllContractsProposal) GetDescription() string { return p.Description }
+
+// ProposalRoute returns the routing key of the proposal
+func (p MigrateAllContractsProposal) ProposalRoute() string { return RouterKey }
+
+// ProposalType returns the type of the proposal
+func (p MigrateAllContractsProposal) ProposalType() string { return ProposalTypeMigrateAllContracts }
+
+// ValidateBasic validates the proposal
+func (p MigrateAllContractsProposal) ValidateBasic() sdk.Error {
+	if err := govtypes.ValidateAbstract(sdk.CodespaceType(DefaultCodespace), p); err != nil {
+		return err
+	}
+	if p.OldCodeID == 0 {
+		return ToSDKError(sdkErrors.Wrap(ErrInvalidMsg, "old_code_id is required"))
+	}
+	if p.NewCodeID == 0 {
+		return ToSDKError(sdkErrors.Wrap(ErrInvalidMsg, "new_code_id is required"))
+	}
+	if p.OldCodeID == p.NewCodeID {
+		return ToSDKError(sdkErrors.Wrap(ErrInvalidMsg, "old and new code id must differ"))
+	}
+	if p.RunAs.Empty() {
+		return sdk.ErrInvalidAddress("missing run as address")
+	}
+	if !json.Valid(p.MigrateMsg) {
+		return ToSDKError(sdkErrors.Wrap(ErrInvalidMsg, "migrate msg must be valid json"))
+	}
+	return nil
+}
+
+// String implements the Stringer interface.
+func (p MigrateAllContractsProposal) String() string {
+	return fmt.Sprintf(`Migrate All Contracts Proposal:
+  Title:       %s
+  Description: %s
+  Old code id: %d
+  New code id: %d
+  Run as:      %s
+  Msg:         %s
+`, p.Title, p.Description, p.OldCodeID, p.NewCodeID, p.RunAs, p.MigrateMsg)
+}
+
+// validateCodeIDs ensures the list is not empty and contains neither 0 nor duplicates
+func validateCodeIDs(codeIDs []uint64) sdk.Error {
+	if len(codeIDs) == 0 {
+		return sdk.ErrUnknownRequest("code ids must not be empty")
+	}
+	seen := make(map[uint64]struct{}, len(codeIDs))
+	for _, c := range codeIDs {
+		if c == 0 {
+			return sdk.ErrUnknownRequest("code id must not be 0")
+		}
+		if _, ok := seen[c]; ok {
+			return sdk.ErrUnknownRequest(fmt.Sprintf("duplicate code id: %d", c))
+		}
+		seen[c] = struct{}{}
+	}
+	return nil
+}
@@ -1,18 +1,38 @@
 package types
 
 import (
+	"encoding/json"
+	"fmt"
+
 	wasmTypes "github.com/confio/go-cosmwasm/types"
 	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkErrors "github.com/cosmos/cosmos-sdk/types/errors"
 	auth "github.com/cosmos/cosmos-sdk/x/auth/exported"
 )
 
 const defaultLRUCacheSize = uint64(0)
 const defaultQueryGasLimit = uint64(3000000)
 
-// Model is a struct that holds a KV pair
+// Model is a struct that holds a KV pair.
+// Key and value are stored as bytes and thus base64 encoded in json, so that binary state is preserved.
 type Model struct {
-	Key   string `json:"key"`
-	Value string `json:"val"`
+	Key   []byte `json:"key"`
+	Value []byte `json:"val"`
+}
+
+// ValidateModels ensures that no model has an empty key and that the keys are unique
+func ValidateModels(models []Model) error {
+	seen := make(map[string]struct{}, len(models))
+	for _, m := range models {
+		if len(m.Key) == 0 {
+			return sdkErrors.Wrap(ErrInvalidMsg, "empty model key")
+		}
+		if _, ok := seen[string(m.Key)]; ok {
+			return sdkErrors.Wrapf(ErrInvalidMsg, "duplicate model key: %X", m.Key)
+		}
+		seen[string(m.Key)] = struct{}{}
+	}
+	return nil
 }
 
 // CodeInfo is data for the uploaded contract WASM code
@@ -21,15 +41,86 @@ type CodeInfo struct {
 	Creator  sdk.AccAddress `json:"creator"`
 	Source   string         `json:"source"`
 	Builder  string         `json:"builder"`
+	// InstantiateConfig defines who is allowed to create instances of this code
+	InstantiateConfig AccessConfig `json:"instantiate_config"`
+	// APIVersion is the contract API version the code was built against, like "0.6". Empty when the code
+	// does not declare it.
+	APIVersion string `json:"api_version,omitempty"`
+	// Deprecated discourages new instances of this code. Instantiation emits a warning event or fails,
+	// depending on the RejectDeprecatedInstantiate param.
+	Deprecated bool `json:"deprecated,omitempty"`
 }
 
 // NewCodeInfo fills a new Contract struct
-func NewCodeInfo(codeHash []byte, creator sdk.AccAddress, source string, builder string) CodeInfo {
+func NewCodeInfo(codeHash []byte, creator sdk.AccAddress, source string, builder string, instantiatePermission AccessConfig, apiVersion string) CodeInfo {
 	return CodeInfo{
-		CodeHash: codeHash,
-		Creator:  creator,
-		Source:   source,
-		Builder:  builder,
+		CodeHash:          codeHash,
+		Creator:           creator,
+		Source:            source,
+		Builder:           builder,
+		InstantiateConfig: instantiatePermission,
+		APIVersion:        apiVersion,
+	}
+}
+
+// AccessType is the kind of permission that an AccessConfig grants
+type AccessType string
+
+const (
+	// AccessTypeNobody forbids the action for all accounts
+	AccessTypeNobody AccessType = "Nobody"
+	// AccessTypeOnlyAddress allows the action for a single account only
+	AccessTypeOnlyAddress AccessType = "OnlyAddress"
+	// AccessTypeEverybody allows the action for all accounts
+	AccessTypeEverybody AccessType = "Everybody"
+)
+
+// AccessConfig defines who is allowed to do an action
+type AccessConfig struct {
+	Type AccessType `json:"permission" yaml:"permission"`
+	// Address is only set for AccessTypeOnlyAddress
+	Address sdk.AccAddress `json:"address,omitempty" yaml:"address"`
+}
+
+var (
+	// AllowEverybody is the default access config
+	AllowEverybody = AccessConfig{Type: AccessTypeEverybody}
+	// AllowNobody denies access to all accounts
+	AllowNobody = AccessConfig{Type: AccessTypeNobody}
+)
+
+// OnlyAddress returns an access config that allows the given address only
+func OnlyAddress(addr sdk.AccAddress) AccessConfig {
+	return AccessConfig{Type: AccessTypeOnlyAddress, Address: addr}
+}
+
+// ValidateBasic checks that the type is known and the address is set for AccessTypeOnlyAddress only
+func (a AccessConfig) ValidateBasic() sdk.Error {
+	switch a.Type {
+	case AccessTypeNobody, AccessTypeEverybody:
+		if len(a.Address) != 0 {
+			return sdk.ErrInvalidAddress("address not allowed for this access type")
+		}
+		return nil
+	case AccessTypeOnlyAddress:
+		if a.Address.Empty() {
+			return sdk.ErrInvalidAddress("missing address")
+		}
+		return nil
+	}
+	return sdk.ErrUnknownRequest(fmt.Sprintf("unknown access type: %q", a.Type))
+}
+
+// Allowed returns true when the given address is permitted. Codes that were stored before access configs
+// existed have an empty type and are open to everybody.
+func (a AccessConfig) Allowed(actor sdk.AccAddress) bool {
+	switch a.Type {
+	case AccessTypeNobody:
+		return false
+	case AccessTypeOnlyAddress:
+		return a.Address.Equals(actor)
+	default:
+		return true
 	}
 }
 
@@ -37,7 +128,73 @@ func NewCodeInfo(codeHash []byte, creator sdk.AccAddress, source string, builder
 type ContractInfo struct {
 	CodeID  uint64         `json:"code_id"`
 	Creator sdk.AccAddress `json:"creator"`
+	// Admin is the only account allowed to migrate the contract. Empty when the contract is immutable.
+	Admin   sdk.AccAddress `json:"admin,omitempty"`
+	Label   string         `json:"label"`
 	InitMsg string         `json:"init_msg"`
+	// AdminOnlyExecute rejects executions by anyone but the admin before the contract is called.
+	// Set at instantiation; once the admin is cleared, the contract can not be executed anymore.
+	AdminOnlyExecute bool `json:"admin_only_execute,omitempty"`
+	// Created is the position of the instantiation. Empty for contracts that were created before it was recorded.
+	Created *AbsoluteTxPosition `json:"created,omitempty"`
+}
+
+// CodeStorageStats are the number of stored codes and the sum of their uncompressed wasm bytecode sizes.
+// Codes that were reused by a store with DeduplicateByHash are not counted again.
+type CodeStorageStats struct {
+	CodeCount  uint64 `json:"code_count"`
+	TotalBytes uint64 `json:"total_bytes"`
+}
+
+// AbsoluteTxPosition is the position of a transaction in the chain, used to order contracts by creation
+type AbsoluteTxPosition struct {
+	// BlockHeight is the height of the block that contains the transaction
+	BlockHeight int64 `json:"block_height"`
+	// TxIndex is monotonically increasing within a block. The block gas consumed before the transaction
+	// is used, as the sdk does not expose the transaction index.
+	TxIndex uint64 `json:"tx_index"`
+}
+
+// NewAbsoluteTxPosition returns the position of the current transaction
+func NewAbsoluteTxPosition(ctx sdk.Context) *AbsoluteTxPosition {
+	// the block gas meter is not set in all contexts
+	var index uint64
+	if meter := ctx.BlockGasMeter(); meter != nil {
+		index = meter.GasConsumed()
+	}
+	return &AbsoluteTxPosition{
+		BlockHeight: ctx.BlockHeight(),
+		TxIndex:     index,
+	}
+}
+
+// LessThan returns true when a comes before b. Nil positions come first.
+func (a *AbsoluteTxPosition) LessThan(b *AbsoluteTxPosition) bool {
+	if a == nil {
+		return b != nil
+	}
+	if b == nil {
+		return false
+	}
+	return a.BlockHeight < b.BlockHeight || (a.BlockHeight == b.BlockHeight && a.TxIndex < b.TxIndex)
+}
+
+// ContractCodeHistoryOperationType describes how the code of a contract was set
+type ContractCodeHistoryOperationType string
+
+const (
+	InitContractCodeHistoryType    ContractCodeHistoryOperationType = "Init"
+	MigrateContractCodeHistoryType ContractCodeHistoryOperationType = "Migrate"
+	GenesisContractCodeHistoryType ContractCodeHistoryOperationType = "Genesis"
+)
+
+// ContractCodeHistoryEntry is a single code change of a contract
+type ContractCodeHistoryEntry struct {
+	Operation ContractCodeHistoryOperationType `json:"operation"`
+	CodeID    uint64                           `json:"code_id"`
+	// Updated is the block height of the change
+	Updated int64           `json:"updated"`
+	Msg     json.RawMessage `json:"msg,omitempty"`
 }
 
 // NewParams initializes params for a contract instance
@@ -72,11 +229,14 @@ func NewWasmCoins(cosmosCoins sdk.Coins) (wasmCoins []wasmTypes.Coin) {
 }
 
 // NewContractInfo creates a new instance of a given WASM contract info
-func NewContractInfo(codeID uint64, creator sdk.AccAddress, initMsg string) ContractInfo {
+func NewContractInfo(codeID uint64, creator, admin sdk.AccAddress, initMsg string, label string, createdAt *AbsoluteTxPosition) ContractInfo {
 	return ContractInfo{
 		CodeID:  codeID,
 		Creator: creator,
+		Admin:   admin,
+		Label:   label,
 		InitMsg: initMsg,
+		Created: createdAt,
 	}
 }
 
@@ -93,6 +253,14 @@ func CosmosResult(wasmResult wasmTypes.Result) sdk.Result {
 type WasmConfig struct {
 	SmartQueryGasLimit uint64 `mapstructure:"query_gas_limit"`
 	CacheSize          uint64 `mapstructure:"lru_size"`
+	// QueryDebug returns the full errors from the querier instead of the redacted ones. Keep it off in production.
+	QueryDebug bool `mapstructure:"query_debug"`
+	// ContractMetrics counts the calls and gas per code and contract in memory for the contract-metrics query.
+	// The counters are local to the node and not part of consensus.
+	ContractMetrics bool `mapstructure:"contract_metrics"`
+	// ExecutedContractsIndex keeps the addresses of the contracts executed in the current block in memory for
+	// the executed-this-block query. The index is local to the node and not part of consensus.
+	ExecutedContractsIndex bool `mapstructure:"executed_contracts_index"`
 }
 
 // DefaultWasmConfig returns the default settings for WasmConfig
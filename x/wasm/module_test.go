@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math/big"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -12,6 +14,7 @@ import (
 
 	wasmTypes "github.com/confio/go-cosmwasm/types"
 	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkErrors "github.com/cosmos/cosmos-sdk/types/errors"
 	"github.com/cosmos/cosmos-sdk/types/module"
 	"github.com/cosmos/cosmos-sdk/x/auth"
 	abci "github.com/tendermint/tendermint/abci/types"
@@ -74,6 +77,12 @@ func TestHandleCreate(t *testing.T) {
 			msg:     MsgStoreCode{},
 			isValid: false,
 		},
+		"missing sender": {
+			msg: MsgStoreCode{
+				WASMByteCode: testContract,
+			},
+			isValid: false,
+		},
 		"invalid wasm": {
 			msg: MsgStoreCode{
 				Sender:       addr1,
@@ -119,6 +128,626 @@ func TestHandleCreate(t *testing.T) {
 	}
 }
 
+func TestHandleCreateExceedsMaxWasmCodeSize(t *testing.T) {
+	data, cleanup := setupTest(t)
+	defer cleanup()
+
+	h := data.module.NewHandler()
+	q := data.module.NewQuerierHandler()
+
+	params := DefaultParams()
+	params.MaxWasmCodeSize = uint64(len(testContract) - 1)
+	data.keeper.SetParams(data.ctx, params)
+
+	msg := MsgStoreCode{
+		Sender:       addr1,
+		WASMByteCode: testContract,
+	}
+	res := h(data.ctx, msg)
+	require.False(t, res.IsOK(), "%#v", res)
+	assertCodeList(t, q, data.ctx, 0)
+
+	params.MaxWasmCodeSize = uint64(len(testContract))
+	data.keeper.SetParams(data.ctx, params)
+	res = h(data.ctx, msg)
+	require.True(t, res.IsOK(), "%#v", res)
+	assertCodeList(t, q, data.ctx, 1)
+}
+
+func TestHandleStoreCodeDeduplicateByHash(t *testing.T) {
+	data, cleanup := setupTest(t)
+	defer cleanup()
+
+	h := data.module.NewHandler()
+	q := data.module.NewQuerierHandler()
+
+	msg := MsgStoreCode{
+		Sender:            addr1,
+		WASMByteCode:      testContract,
+		DeduplicateByHash: true,
+	}
+	res := h(data.ctx, msg)
+	require.True(t, res.IsOK(), "%#v", res)
+	require.Equal(t, sdk.Uint64ToBigEndian(1), res.Data)
+	assertEventAttribute(t, res.Events, EventTypeStoreCode, AttributeKeyCodeReused, "false")
+
+	// same code is reused
+	res = h(data.ctx, msg)
+	require.True(t, res.IsOK(), "%#v", res)
+	require.Equal(t, sdk.Uint64ToBigEndian(1), res.Data)
+	assertEventAttribute(t, res.Events, EventTypeStoreCode, AttributeKeyCodeReused, "true")
+	assertCodeList(t, q, data.ctx, 1)
+
+	// without the flag a new copy is stored
+	msg.DeduplicateByHash = false
+	res = h(data.ctx, msg)
+	require.True(t, res.IsOK(), "%#v", res)
+	require.Equal(t, sdk.Uint64ToBigEndian(2), res.Data)
+	assertEventAttribute(t, res.Events, EventTypeStoreCode, AttributeKeyCodeReused, "false")
+	assertCodeList(t, q, data.ctx, 2)
+}
+
+func TestHandleStoreCodeUploadGas(t *testing.T) {
+	data, cleanup := setupTest(t)
+	defer cleanup()
+
+	h := data.module.NewHandler()
+	msg := MsgStoreCode{
+		Sender:       addr1,
+		WASMByteCode: testContract,
+	}
+	gasUsed := func(gasPerByte uint64) uint64 {
+		ctx, _ := data.ctx.CacheContext()
+		params := DefaultParams()
+		params.UploadGasPerByte = gasPerByte
+		data.keeper.SetParams(ctx, params)
+
+		ctx = ctx.WithGasMeter(sdk.NewInfiniteGasMeter())
+		res := h(ctx, msg)
+		require.True(t, res.IsOK(), "%#v", res)
+		return ctx.GasMeter().GasConsumed()
+	}
+	// same digit count, so that reading the params costs the same
+	assert.Equal(t, 3*uint64(len(testContract)), gasUsed(4)-gasUsed(1))
+}
+
+func TestHandleMsgSizeLimits(t *testing.T) {
+	data, cleanup := setupTest(t)
+	defer cleanup()
+
+	h := data.module.NewHandler()
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(data.ctx, data.acctKeeper, deposit)
+
+	res := h(data.ctx, MsgStoreCode{Sender: creator, WASMByteCode: testContract})
+	require.True(t, res.IsOK(), "%#v", res)
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(initMsg{Verifier: creator, Beneficiary: bob})
+	require.NoError(t, err)
+
+	params := DefaultParams()
+	params.MaxInitMsgSize = uint64(len(initMsgBz) - 1)
+	data.keeper.SetParams(data.ctx, params)
+
+	instantiateMsg := MsgInstantiateContract{
+		Sender:  creator,
+		Code:    1,
+		Label:   "demo contract",
+		InitMsg: initMsgBz,
+	}
+	res = h(data.ctx, instantiateMsg)
+	require.False(t, res.IsOK(), "%#v", res)
+
+	params.MaxInitMsgSize = uint64(len(initMsgBz))
+	data.keeper.SetParams(data.ctx, params)
+	res = h(data.ctx, instantiateMsg)
+	require.True(t, res.IsOK(), "%#v", res)
+	contractAddr := sdk.AccAddress(res.Data)
+
+	execMsg := MsgExecuteContract{
+		Sender:   creator,
+		Contract: contractAddr,
+		Msg:      []byte(`{}`),
+	}
+	params.MaxExecuteMsgSize = 1
+	data.keeper.SetParams(data.ctx, params)
+	res = h(data.ctx, execMsg)
+	require.False(t, res.IsOK(), "%#v", res)
+}
+
+func TestHandleLabelAndFundsLimits(t *testing.T) {
+	data, cleanup := setupTest(t)
+	defer cleanup()
+
+	h := data.module.NewHandler()
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000), sdk.NewInt64Coin("other", 100000))
+	creator := createFakeFundedAccount(data.ctx, data.acctKeeper, deposit)
+
+	res := h(data.ctx, MsgStoreCode{Sender: creator, WASMByteCode: testContract})
+	require.True(t, res.IsOK(), "%#v", res)
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(initMsg{Verifier: creator, Beneficiary: bob})
+	require.NoError(t, err)
+
+	params := DefaultParams()
+	params.MaxLabelSize = uint64(len("demo contract") - 1)
+	data.keeper.SetParams(data.ctx, params)
+
+	instantiateMsg := MsgInstantiateContract{
+		Sender:    creator,
+		Code:      1,
+		Label:     "demo contract",
+		InitMsg:   initMsgBz,
+		InitFunds: deposit,
+	}
+	res = h(data.ctx, instantiateMsg)
+	require.False(t, res.IsOK(), "%#v", res)
+
+	params.MaxLabelSize = uint64(len("demo contract"))
+	params.MaxFundsCoins = 1
+	data.keeper.SetParams(data.ctx, params)
+	res = h(data.ctx, instantiateMsg)
+	require.False(t, res.IsOK(), "%#v", res)
+
+	params.MaxFundsCoins = 2
+	data.keeper.SetParams(data.ctx, params)
+	res = h(data.ctx, instantiateMsg)
+	require.True(t, res.IsOK(), "%#v", res)
+	contractAddr := sdk.AccAddress(res.Data)
+
+	params.MaxFundsCoins = 1
+	data.keeper.SetParams(data.ctx, params)
+	execMsg := MsgExecuteContract{
+		Sender:    creator,
+		Contract:  contractAddr,
+		Msg:       []byte(`{}`),
+		SentFunds: sdk.NewCoins(sdk.NewInt64Coin("denom", 1), sdk.NewInt64Coin("other", 1)),
+	}
+	res = h(data.ctx, execMsg)
+	require.False(t, res.IsOK(), "%#v", res)
+}
+
+func TestInstantiate2AndExecuteInOneTx(t *testing.T) {
+	data, cleanup := setupTest(t)
+	defer cleanup()
+
+	h := data.module.NewHandler()
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(data.ctx, data.acctKeeper, deposit)
+
+	res := h(data.ctx, MsgStoreCode{Sender: creator, WASMByteCode: testContract})
+	require.True(t, res.IsOK(), "%#v", res)
+	codeID := uint64(1)
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(initMsg{Verifier: creator, Beneficiary: bob})
+	require.NoError(t, err)
+
+	salt := []byte("salt")
+	contractAddr := PredictableContractAddress(codeID, creator, salt)
+	msgs := []sdk.Msg{
+		MsgInstantiateContract2{
+			Sender:    creator,
+			Code:      codeID,
+			Label:     "demo contract",
+			InitMsg:   initMsgBz,
+			InitFunds: sdk.NewCoins(sdk.NewInt64Coin("denom", 5000)),
+			Salt:      salt,
+		},
+		// the second message targets the address that the first message creates
+		MsgExecuteContract{
+			Sender:   creator,
+			Contract: contractAddr,
+			Msg:      []byte(`{}`),
+		},
+	}
+
+	// run the messages like the baseapp does for a tx: in order on one cached state that is written at the end
+	txCtx, write := data.ctx.CacheContext()
+	for i, msg := range msgs {
+		res := h(txCtx, msg)
+		require.True(t, res.IsOK(), "msg %d: %#v", i, res)
+	}
+	write()
+
+	require.NotNil(t, data.keeper.GetContractInfo(data.ctx, contractAddr))
+	// the release by the verifier moved the funds to the beneficiary
+	assert.Equal(t, sdk.NewCoins(sdk.NewInt64Coin("denom", 5000)), data.acctKeeper.GetAccount(data.ctx, bob).GetCoins())
+}
+
+func TestHandleAcceptedFundDenoms(t *testing.T) {
+	data, cleanup := setupTest(t)
+	defer cleanup()
+
+	h := data.module.NewHandler()
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000), sdk.NewInt64Coin("other", 100000))
+	creator := createFakeFundedAccount(data.ctx, data.acctKeeper, deposit)
+
+	res := h(data.ctx, MsgStoreCode{Sender: creator, WASMByteCode: testContract})
+	require.True(t, res.IsOK(), "%#v", res)
+
+	_, _, bob := keyPubAddr()
+	initMsgBz, err := json.Marshal(initMsg{Verifier: creator, Beneficiary: bob})
+	require.NoError(t, err)
+
+	params := DefaultParams()
+	params.AcceptedFundDenoms = []string{"denom"}
+	data.keeper.SetParams(data.ctx, params)
+
+	instantiateMsg := MsgInstantiateContract{
+		Sender:    creator,
+		Code:      1,
+		Label:     "demo contract",
+		InitMsg:   initMsgBz,
+		InitFunds: sdk.NewCoins(sdk.NewInt64Coin("denom", 1), sdk.NewInt64Coin("other", 1)),
+	}
+	res = h(data.ctx, instantiateMsg)
+	require.False(t, res.IsOK(), "%#v", res)
+
+	instantiateMsg.InitFunds = sdk.NewCoins(sdk.NewInt64Coin("denom", 1))
+	res = h(data.ctx, instantiateMsg)
+	require.True(t, res.IsOK(), "%#v", res)
+	contractAddr := sdk.AccAddress(res.Data)
+
+	execMsg := MsgExecuteContract{
+		Sender:    creator,
+		Contract:  contractAddr,
+		Msg:       []byte(`{}`),
+		SentFunds: sdk.NewCoins(sdk.NewInt64Coin("other", 1)),
+	}
+	res = h(data.ctx, execMsg)
+	require.False(t, res.IsOK(), "%#v", res)
+
+	// empty accepts all denoms
+	params.AcceptedFundDenoms = nil
+	data.keeper.SetParams(data.ctx, params)
+	res = h(data.ctx, execMsg)
+	require.True(t, res.IsOK(), "%#v", res)
+}
+
+func TestValidateBasicRejectsTooManyFundsDenoms(t *testing.T) {
+	_, _, contractAddr := keyPubAddr()
+	funds := func(n int) sdk.Coins {
+		coins := make(sdk.Coins, n)
+		for i := range coins {
+			coins[i] = sdk.NewInt64Coin(fmt.Sprintf("denom%03d", i), 1)
+		}
+		return coins
+	}
+	specs := map[string]struct {
+		msg    sdk.Msg
+		expErr bool
+	}{
+		"instantiate with max denoms": {
+			msg: MsgInstantiateContract{Sender: addr1, Code: 1, Label: "demo contract", InitMsg: []byte(`{}`), InitFunds: funds(MaxFundsCoins)},
+		},
+		"instantiate with too many denoms": {
+			msg:    MsgInstantiateContract{Sender: addr1, Code: 1, Label: "demo contract", InitMsg: []byte(`{}`), InitFunds: funds(MaxFundsCoins + 1)},
+			expErr: true,
+		},
+		"execute with max denoms": {
+			msg: MsgExecuteContract{Sender: addr1, Contract: contractAddr, Msg: []byte(`{}`), SentFunds: funds(MaxFundsCoins)},
+		},
+		"execute with too many denoms": {
+			msg:    MsgExecuteContract{Sender: addr1, Contract: contractAddr, Msg: []byte(`{}`), SentFunds: funds(MaxFundsCoins + 1)},
+			expErr: true,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			err := spec.msg.ValidateBasic()
+			if spec.expErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestValidateBasicRejectsTooLargeFundsAmount(t *testing.T) {
+	_, _, contractAddr := keyPubAddr()
+	// 2^MaxFundsAmountBits is the smallest amount with too many bits
+	tooLarge := sdk.NewIntFromBigInt(new(big.Int).Lsh(big.NewInt(1), MaxFundsAmountBits))
+	max := tooLarge.SubRaw(1)
+	specs := map[string]struct {
+		msg    sdk.Msg
+		expErr bool
+	}{
+		"instantiate with max amount": {
+			msg: MsgInstantiateContract{Sender: addr1, Code: 1, Label: "demo contract", InitMsg: []byte(`{}`), InitFunds: sdk.NewCoins(sdk.NewCoin("denom", max))},
+		},
+		"instantiate with too large amount": {
+			msg:    MsgInstantiateContract{Sender: addr1, Code: 1, Label: "demo contract", InitMsg: []byte(`{}`), InitFunds: sdk.NewCoins(sdk.NewCoin("denom", tooLarge))},
+			expErr: true,
+		},
+		"execute with max amount": {
+			msg: MsgExecuteContract{Sender: addr1, Contract: contractAddr, Msg: []byte(`{}`), SentFunds: sdk.NewCoins(sdk.NewCoin("denom", max))},
+		},
+		"execute with too large amount": {
+			msg:    MsgExecuteContract{Sender: addr1, Contract: contractAddr, Msg: []byte(`{}`), SentFunds: sdk.NewCoins(sdk.NewCoin("denom", tooLarge))},
+			expErr: true,
+		},
+		"execute with unsorted denoms": {
+			msg:    MsgExecuteContract{Sender: addr1, Contract: contractAddr, Msg: []byte(`{}`), SentFunds: sdk.Coins{sdk.NewInt64Coin("denomb", 1), sdk.NewInt64Coin("denoma", 1)}},
+			expErr: true,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			err := spec.msg.ValidateBasic()
+			if spec.expErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestValidateBasicErrorCodes(t *testing.T) {
+	_, _, contractAddr := keyPubAddr()
+	specs := map[string]struct {
+		msg    sdk.Msg
+		expErr *sdkErrors.Error
+	}{
+		"empty wasm code": {
+			msg:    MsgStoreCode{Sender: addr1},
+			expErr: ErrInvalidWasmCode,
+		},
+		"relative source": {
+			msg:    MsgStoreCode{Sender: addr1, WASMByteCode: testContract, Source: "foo/bar"},
+			expErr: ErrInvalidSource,
+		},
+		"invalid builder": {
+			msg:    MsgStoreCode{Sender: addr1, WASMByteCode: testContract, Builder: "cosmwasm-opt:latest"},
+			expErr: ErrInvalidBuilder,
+		},
+		"builder without source": {
+			msg:    MsgStoreCode{Sender: addr1, WASMByteCode: testContract, Builder: "cosmwasm-opt:0.7.0"},
+			expErr: ErrInvalidBuilder,
+		},
+		"missing label": {
+			msg:    MsgInstantiateContract{Sender: addr1, Code: 1, InitMsg: []byte(`{}`)},
+			expErr: ErrInvalidLabel,
+		},
+		"label too long": {
+			msg:    MsgInstantiateContract{Sender: addr1, Code: 1, Label: strings.Repeat("a", MaxLabelSize+1), InitMsg: []byte(`{}`)},
+			expErr: ErrInvalidLabel,
+		},
+		"invalid init msg": {
+			msg:    MsgInstantiateContract{Sender: addr1, Code: 1, Label: "demo contract", InitMsg: []byte("not json")},
+			expErr: ErrInvalidMsg,
+		},
+		"invalid execute msg": {
+			msg:    MsgExecuteContract{Sender: addr1, Contract: contractAddr, Msg: []byte("not json")},
+			expErr: ErrInvalidMsg,
+		},
+		"migrate without code id": {
+			msg:    MsgMigrateContract{Sender: addr1, Contract: contractAddr, MigrateMsg: []byte(`{}`)},
+			expErr: ErrInvalidMsg,
+		},
+		"invalid migrate msg": {
+			msg:    MsgMigrateContract{Sender: addr1, Contract: contractAddr, Code: 1, MigrateMsg: []byte("not json")},
+			expErr: ErrInvalidMsg,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			err := spec.msg.ValidateBasic()
+			require.Error(t, err)
+			assert.Equal(t, sdk.CodespaceType(spec.expErr.Codespace()), err.Codespace())
+			assert.Equal(t, sdk.CodeType(spec.expErr.ABCICode()), err.Code())
+		})
+	}
+}
+
+func TestValidateBasicRejectsInvalidFunds(t *testing.T) {
+	_, _, contractAddr := keyPubAddr()
+	specs := map[string]sdk.Coins{
+		"unsorted denoms":  {sdk.NewInt64Coin("bbb", 1), sdk.NewInt64Coin("aaa", 1)},
+		"duplicate denoms": {sdk.NewInt64Coin("aaa", 1), sdk.NewInt64Coin("aaa", 2)},
+		"invalid denom":    {sdk.Coin{Denom: "A!", Amount: sdk.NewInt(1)}},
+		"zero amount":      {sdk.NewInt64Coin("aaa", 0)},
+		"negative amount":  {sdk.Coin{Denom: "aaa", Amount: sdk.NewInt(-1)}},
+	}
+	for msg, funds := range specs {
+		t.Run(msg, func(t *testing.T) {
+			instantiate := MsgInstantiateContract{Sender: addr1, Code: 1, Label: "demo contract", InitMsg: []byte(`{}`), InitFunds: funds}
+			err := instantiate.ValidateBasic()
+			require.Error(t, err)
+			assert.Equal(t, sdk.CodeInvalidCoins, err.Code())
+
+			execute := MsgExecuteContract{Sender: addr1, Contract: contractAddr, Msg: []byte(`{}`), SentFunds: funds}
+			err = execute.ValidateBasic()
+			require.Error(t, err)
+			assert.Equal(t, sdk.CodeInvalidCoins, err.Code())
+		})
+	}
+}
+
+func TestValidateBasicRejectsInvalidJSON(t *testing.T) {
+	_, _, contractAddr := keyPubAddr()
+	specs := map[string]struct {
+		msg    sdk.Msg
+		expErr bool
+	}{
+		"instantiate with json": {
+			msg:    MsgInstantiateContract{Sender: addr1, Code: 1, Label: "demo contract", InitMsg: []byte(`{"foo":"bar"}`)},
+			expErr: false,
+		},
+		"instantiate with garbage": {
+			msg:    MsgInstantiateContract{Sender: addr1, Code: 1, Label: "demo contract", InitMsg: []byte{0xff, 0x01}},
+			expErr: true,
+		},
+		"instantiate with empty init msg": {
+			msg:    MsgInstantiateContract{Sender: addr1, Code: 1, Label: "demo contract"},
+			expErr: true,
+		},
+		"execute with json": {
+			msg:    MsgExecuteContract{Sender: addr1, Contract: contractAddr, Msg: []byte(`{}`)},
+			expErr: false,
+		},
+		"execute with garbage": {
+			msg:    MsgExecuteContract{Sender: addr1, Contract: contractAddr, Msg: []byte("not json")},
+			expErr: true,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			err := spec.msg.ValidateBasic()
+			if spec.expErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestInstantiateValidateBasicLabel(t *testing.T) {
+	specs := map[string]struct {
+		label  string
+		expErr bool
+	}{
+		"label set":       {label: "demo contract"},
+		"empty label":     {label: "", expErr: true},
+		"label at max":    {label: strings.Repeat("a", MaxLabelSize)},
+		"label above max": {label: strings.Repeat("a", MaxLabelSize+1), expErr: true},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			err := MsgInstantiateContract{Sender: addr1, Code: 1, Label: spec.label, InitMsg: []byte(`{}`)}.ValidateBasic()
+			if spec.expErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestStoreCodeValidateBasicPermission(t *testing.T) {
+	specs := map[string]struct {
+		permission *AccessConfig
+		expErr     bool
+	}{
+		"not set":                   {},
+		"everybody":                 {permission: &AccessConfig{Type: AccessTypeEverybody}},
+		"nobody":                    {permission: &AccessConfig{Type: AccessTypeNobody}},
+		"only address":              {permission: &AccessConfig{Type: AccessTypeOnlyAddress, Address: addr1}},
+		"only address without addr": {permission: &AccessConfig{Type: AccessTypeOnlyAddress}, expErr: true},
+		"everybody with address":    {permission: &AccessConfig{Type: AccessTypeEverybody, Address: addr1}, expErr: true},
+		"unknown type":              {permission: &AccessConfig{Type: "Somebody"}, expErr: true},
+		"empty type":                {permission: &AccessConfig{}, expErr: true},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			err := MsgStoreCode{Sender: addr1, WASMByteCode: testContract, InstantiatePermission: spec.permission}.ValidateBasic()
+			if spec.expErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestStoreCodeValidateBasicBuilder(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("ab", 32)
+	specs := map[string]struct {
+		builder string
+		expErr  bool
+	}{
+		"not set":                {},
+		"version":                {builder: "cosmwasm-opt:0.7.0"},
+		"pre-release version":    {builder: "cosmwasm-opt:0.7.0-beta.1"},
+		"version with digest":    {builder: "cosmwasm-opt:0.7.0@" + digest},
+		"other image":            {builder: "somerandombuildtag-0.6.2", expErr: true},
+		"missing version":        {builder: "cosmwasm-opt:", expErr: true},
+		"partial version":        {builder: "cosmwasm-opt:0.7", expErr: true},
+		"latest":                 {builder: "cosmwasm-opt:latest", expErr: true},
+		"digest without version": {builder: "cosmwasm-opt:@" + digest, expErr: true},
+		"short digest":           {builder: "cosmwasm-opt:0.7.0@sha256:abcdef", expErr: true},
+		"upper case digest":      {builder: "cosmwasm-opt:0.7.0@sha256:" + strings.Repeat("AB", 32), expErr: true},
+		"other digest algorithm": {builder: "cosmwasm-opt:0.7.0@md5:" + strings.Repeat("ab", 16), expErr: true},
+		"trailing garbage":       {builder: "cosmwasm-opt:0.7.0 foo", expErr: true},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			err := MsgStoreCode{Sender: addr1, WASMByteCode: testContract, Source: "https://example.com/source", Builder: spec.builder}.ValidateBasic()
+			if spec.expErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+// TestMsgSignBytes pins the sign bytes of the messages. They are what hardware wallets and other external
+// signers reproduce, so any change here breaks them and must be treated as a breaking change.
+func TestMsgSignBytes(t *testing.T) {
+	sender := sdk.AccAddress(strings.Repeat("s", sdk.AddrLen))
+	admin := sdk.AccAddress(strings.Repeat("a", sdk.AddrLen))
+	contract := sdk.AccAddress(strings.Repeat("c", sdk.AddrLen))
+
+	specs := map[string]struct {
+		src sdk.Msg
+		exp string
+	}{
+		"store code": {
+			src: MsgStoreCode{
+				Sender:       sender,
+				WASMByteCode: []byte{0x1, 0x2, 0x3},
+				Source:       "https://example.com/source",
+				Builder:      "cosmwasm-opt:0.7.0",
+			},
+			exp: `{"type":"wasm/store-code","value":{"builder":"cosmwasm-opt:0.7.0","sender":"` + sender.String() + `","source":"https://example.com/source","wasm_byte_code":"AQID"}}`,
+		},
+		"instantiate": {
+			src: MsgInstantiateContract{
+				Sender:    sender,
+				Admin:     admin,
+				Code:      1,
+				Label:     "demo contract",
+				InitMsg:   []byte(`{"verifier":"x","beneficiary":"y"}`),
+				InitFunds: sdk.NewCoins(sdk.NewInt64Coin("denom", 100)),
+			},
+			exp: `{"type":"wasm/instantiate","value":{"admin":"` + admin.String() + `","code_id":"1","init_funds":[{"amount":"100","denom":"denom"}],"init_msg":{"beneficiary":"y","verifier":"x"},"label":"demo contract","sender":"` + sender.String() + `"}}`,
+		},
+		"execute": {
+			src: MsgExecuteContract{
+				Sender:    sender,
+				Contract:  contract,
+				Msg:       []byte(`{"release":{}}`),
+				SentFunds: sdk.NewCoins(sdk.NewInt64Coin("denom", 5)),
+			},
+			exp: `{"type":"wasm/execute","value":{"contract":"` + contract.String() + `","msg":{"release":{}},"sender":"` + sender.String() + `","sent_funds":[{"amount":"5","denom":"denom"}]}}`,
+		},
+		"migrate": {
+			src: MsgMigrateContract{
+				Sender:     sender,
+				Contract:   contract,
+				Code:       2,
+				MigrateMsg: []byte(`{"foo":"bar"}`),
+			},
+			exp: `{"type":"wasm/migrate","value":{"code_id":"2","contract":"` + contract.String() + `","msg":{"foo":"bar"},"sender":"` + sender.String() + `"}}`,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			assert.Equal(t, spec.exp, string(spec.src.GetSignBytes()))
+		})
+	}
+}
+
 type initMsg struct {
 	Verifier    sdk.AccAddress `json:"verifier"`
 	Beneficiary sdk.AccAddress `json:"beneficiary"`
@@ -146,7 +775,9 @@ func TestHandleInstantiate(t *testing.T) {
 	}
 	res := h(data.ctx, msg)
 	require.True(t, res.IsOK())
-	require.Equal(t, res.Data, []byte("1"))
+	require.Equal(t, sdk.Uint64ToBigEndian(1), res.Data)
+	assertEventAttribute(t, res.Events, EventTypeStoreCode, AttributeKeyCodeID, "1")
+	assertEventAttribute(t, res.Events, EventTypeStoreCode, AttributeKeyCreator, creator.String())
 
 	_, _, bob := keyPubAddr()
 	_, _, fred := keyPubAddr()
@@ -162,6 +793,7 @@ func TestHandleInstantiate(t *testing.T) {
 	initCmd := MsgInstantiateContract{
 		Sender:    creator,
 		Code:      1,
+		Label:     "demo contract",
 		InitMsg:   initMsgBz,
 		InitFunds: nil,
 	}
@@ -169,6 +801,9 @@ func TestHandleInstantiate(t *testing.T) {
 	require.True(t, res.IsOK(), res.Log)
 	contractAddr := sdk.AccAddress(res.Data)
 	require.Equal(t, "cosmos18vd8fpwxzck93qlwghaj6arh4p7c5n89uzcee5", contractAddr.String())
+	assertEventAttribute(t, res.Events, EventTypeInstantiate, AttributeKeyContract, contractAddr.String())
+	assertEventAttribute(t, res.Events, EventTypeInstantiate, AttributeKeyCodeID, "1")
+	assertEventAttribute(t, res.Events, EventTypeInstantiate, AttributeKeyFunds, "")
 
 	assertCodeList(t, q, data.ctx, 1)
 	assertCodeBytes(t, q, data.ctx, 1, testContract)
@@ -182,6 +817,58 @@ func TestHandleInstantiate(t *testing.T) {
 	})
 }
 
+func TestHandleStoreCodeAndInstantiate(t *testing.T) {
+	data, cleanup := setupTest(t)
+	defer cleanup()
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(data.ctx, data.acctKeeper, deposit)
+
+	h := data.module.NewHandler()
+	q := data.module.NewQuerierHandler()
+
+	_, _, bob := keyPubAddr()
+	_, _, fred := keyPubAddr()
+	initMsgBz, err := json.Marshal(initMsg{Verifier: fred, Beneficiary: bob})
+	require.NoError(t, err)
+
+	msg := MsgStoreCodeAndInstantiate{
+		Sender:       creator,
+		WASMByteCode: testContract,
+		Label:        "demo contract",
+		InitMsg:      initMsgBz,
+	}
+	res := h(data.ctx, msg)
+	require.True(t, res.IsOK(), res.Log)
+	contractAddr := sdk.AccAddress(res.Data)
+	assertEventAttribute(t, res.Events, EventTypeStoreCode, AttributeKeyCodeID, "1")
+	assertEventAttribute(t, res.Events, EventTypeStoreCode, AttributeKeyCreator, creator.String())
+	assertEventAttribute(t, res.Events, EventTypeStoreCode, AttributeKeyCodeReused, "false")
+	assertEventAttribute(t, res.Events, EventTypeInstantiate, AttributeKeyContract, contractAddr.String())
+	assertEventAttribute(t, res.Events, EventTypeInstantiate, AttributeKeyCodeID, "1")
+
+	assertCodeList(t, q, data.ctx, 1)
+	assertCodeBytes(t, q, data.ctx, 1, testContract)
+	assertContractList(t, q, data.ctx, []string{contractAddr.String()})
+	assertContractInfo(t, q, data.ctx, contractAddr, 1, creator)
+
+	// the instantiate part is validated as well
+	msg.Label = ""
+	res = h(data.ctx, msg)
+	require.False(t, res.IsOK(), "%#v", res)
+	assertCodeList(t, q, data.ctx, 1)
+
+	// the same code is instantiated again
+	msg.Label = "other contract"
+	msg.DeduplicateByHash = true
+	res = h(data.ctx, msg)
+	require.True(t, res.IsOK(), res.Log)
+	assertEventAttribute(t, res.Events, EventTypeStoreCode, AttributeKeyCodeID, "1")
+	assertEventAttribute(t, res.Events, EventTypeStoreCode, AttributeKeyCodeReused, "true")
+	assertEventAttribute(t, res.Events, EventTypeInstantiate, AttributeKeyCodeID, "1")
+	assertCodeList(t, q, data.ctx, 1)
+}
+
 func TestHandleExecute(t *testing.T) {
 	data, cleanup := setupTest(t)
 	defer cleanup()
@@ -200,7 +887,7 @@ func TestHandleExecute(t *testing.T) {
 	}
 	res := h(data.ctx, msg)
 	require.True(t, res.IsOK())
-	require.Equal(t, res.Data, []byte("1"))
+	require.Equal(t, sdk.Uint64ToBigEndian(1), res.Data)
 
 	_, _, bob := keyPubAddr()
 	initMsg := initMsg{
@@ -213,6 +900,7 @@ func TestHandleExecute(t *testing.T) {
 	initCmd := MsgInstantiateContract{
 		Sender:    creator,
 		Code:      1,
+		Label:     "demo contract",
 		InitMsg:   initMsgBz,
 		InitFunds: deposit,
 	}
@@ -220,6 +908,7 @@ func TestHandleExecute(t *testing.T) {
 	require.True(t, res.IsOK())
 	contractAddr := sdk.AccAddress(res.Data)
 	require.Equal(t, "cosmos18vd8fpwxzck93qlwghaj6arh4p7c5n89uzcee5", contractAddr.String())
+	assertEventAttribute(t, res.Events, EventTypeInstantiate, AttributeKeyFunds, "100000denom")
 
 	// ensure bob doesn't exist
 	bobAcct := data.acctKeeper.GetAccount(data.ctx, bob)
@@ -244,6 +933,9 @@ func TestHandleExecute(t *testing.T) {
 	}
 	res = h(data.ctx, execCmd)
 	require.True(t, res.IsOK())
+	assertEventAttribute(t, res.Events, EventTypeExecute, AttributeKeyContract, contractAddr.String())
+	assertEventAttribute(t, res.Events, EventTypeExecute, AttributeKeySender, fred.String())
+	assertEventAttribute(t, res.Events, EventTypeExecute, AttributeKeyFunds, "5000denom")
 
 	// ensure bob now exists and got both payments released
 	bobAcct = data.acctKeeper.GetAccount(data.ctx, bob)
@@ -286,7 +978,7 @@ func TestHandleExecuteEscrow(t *testing.T) {
 	}
 	res := h(data.ctx, &msg)
 	require.True(t, res.IsOK(), res.Log)
-	require.Equal(t, res.Data, []byte("1"))
+	require.Equal(t, sdk.Uint64ToBigEndian(1), res.Data)
 
 	_, _, bob := keyPubAddr()
 	initMsg := map[string]interface{}{
@@ -301,6 +993,7 @@ func TestHandleExecuteEscrow(t *testing.T) {
 	initCmd := MsgInstantiateContract{
 		Sender:    creator,
 		Code:      1,
+		Label:     "demo contract",
 		InitMsg:   initMsgBz,
 		InitFunds: deposit,
 	}
@@ -349,6 +1042,21 @@ func TestHandleExecuteEscrow(t *testing.T) {
 	// })
 }
 
+func assertEventAttribute(t *testing.T, events sdk.Events, eventType, key, value string) {
+	for _, e := range events {
+		if e.Type != eventType {
+			continue
+		}
+		for _, attr := range e.Attributes {
+			if string(attr.Key) == key {
+				assert.Equal(t, value, string(attr.Value))
+				return
+			}
+		}
+	}
+	t.Errorf("attribute %q not found in %q event: %v", key, eventType, events)
+}
+
 func assertCodeList(t *testing.T, q sdk.Querier, ctx sdk.Context, expectedNum int) {
 	bz, sdkerr := q(ctx, []string{QueryListCode}, abci.RequestQuery{})
 	require.NoError(t, sdkerr)
@@ -358,11 +1066,11 @@ func assertCodeList(t *testing.T, q sdk.Querier, ctx sdk.Context, expectedNum in
 		return
 	}
 
-	var res []CodeInfo
+	var res CodeListResponse
 	err := json.Unmarshal(bz, &res)
 	require.NoError(t, err)
 
-	assert.Equal(t, expectedNum, len(res))
+	assert.Equal(t, expectedNum, len(res.Codes))
 }
 
 func assertCodeBytes(t *testing.T, q sdk.Querier, ctx sdk.Context, codeID uint64, expectedBytes []byte) {
@@ -391,16 +1099,17 @@ func assertContractList(t *testing.T, q sdk.Querier, ctx sdk.Context, addrs []st
 		return
 	}
 
-	var res []string
+	var res ContractListResponse
 	err := json.Unmarshal(bz, &res)
 	require.NoError(t, err)
 
-	assert.Equal(t, addrs, res)
+	assert.Equal(t, addrs, res.Contracts)
+	assert.Equal(t, uint64(len(addrs)), res.Total)
 }
 
 type model struct {
-	Key   string `json:"key"`
-	Value string `json:"val"`
+	Key   []byte `json:"key"`
+	Value []byte `json:"val"`
 }
 
 func assertContractState(t *testing.T, q sdk.Querier, ctx sdk.Context, addr sdk.AccAddress, expected state) {
@@ -412,11 +1121,11 @@ func assertContractState(t *testing.T, q sdk.Querier, ctx sdk.Context, addr sdk.
 	err := json.Unmarshal(bz, &res)
 	require.NoError(t, err)
 	require.Equal(t, 1, len(res), "#v", res)
-	require.Equal(t, "config", res[0].Key)
+	require.Equal(t, []byte("config"), res[0].Key)
 
 	expectedBz, err := json.Marshal(expected)
 	require.NoError(t, err)
-	assert.Equal(t, string(expectedBz), res[0].Value)
+	assert.Equal(t, expectedBz, res[0].Value)
 }
 
 func assertContractInfo(t *testing.T, q sdk.Querier, ctx sdk.Context, addr sdk.AccAddress, codeID uint64, creator sdk.AccAddress) {
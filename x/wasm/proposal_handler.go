@@ -0,0 +1,234 @@
+package wasm
+
+import (
+	"fmt"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkErrors "github.com/cosmos/cosmos-sdk/types/errors"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+	"github.com/cosmos/cosmos-sdk/x/params"
+)
+
+// NewWasmProposalHandler creates a new governance Handler for wasm proposals
+func NewWasmProposalHandler(k Keeper) govtypes.Handler {
+	return func(ctx sdk.Context, content govtypes.Content) sdk.Error {
+		var err error
+		switch c := content.(type) {
+		case StoreCodeProposal:
+			err = handleStoreCodeProposal(ctx, k, c)
+		case *StoreCodeProposal:
+			err = handleStoreCodeProposal(ctx, k, *c)
+		case MigrateContractProposal:
+			err = handleMigrateProposal(ctx, k, c)
+		case *MigrateContractProposal:
+			err = handleMigrateProposal(ctx, k, *c)
+		case PinCodesProposal:
+			err = handlePinCodesProposal(ctx, k, c)
+		case *PinCodesProposal:
+			err = handlePinCodesProposal(ctx, k, *c)
+		case UnpinCodesProposal:
+			err = handleUnpinCodesProposal(ctx, k, c)
+		case *UnpinCodesProposal:
+			err = handleUnpinCodesProposal(ctx, k, *c)
+		case ImportContractStateProposal:
+			err = handleImportContractStateProposal(ctx, k, c)
+		case *ImportContractStateProposal:
+			err = handleImportContractStateProposal(ctx, k, *c)
+		case UpdateInstantiateConfigProposal:
+			err = handleUpdateInstantiateConfigProposal(ctx, k, c)
+		case *UpdateInstantiateConfigProposal:
+			err = handleUpdateInstantiateConfigProposal(ctx, k, *c)
+		case DeprecateCodeProposal:
+			err = handleDeprecateCodeProposal(ctx, k, c)
+		case *DeprecateCodeProposal:
+			err = handleDeprecateCodeProposal(ctx, k, *c)
+		case MigrateAllContractsProposal:
+			err = handleMigrateAllContractsProposal(ctx, k, c)
+		case *MigrateAllContractsProposal:
+			err = handleMigrateAllContractsProposal(ctx, k, *c)
+		default:
+			errMsg := fmt.Sprintf("unrecognized wasm proposal content type: %T", c)
+			return sdk.ErrUnknownRequest(errMsg)
+		}
+		return ToSDKError(err)
+	}
+}
+
+// NewParamChangeProposalHandler wraps the handler of the params module. Param change proposals that touch the
+// wasm subspace fail without any state change when the resulting wasm params do not pass ValidateBasic, as
+// the param set pairs of this sdk version have no validators of their own.
+func NewParamChangeProposalHandler(k Keeper, next govtypes.Handler) govtypes.Handler {
+	return func(ctx sdk.Context, content govtypes.Content) sdk.Error {
+		var changes []params.ParamChange
+		switch c := content.(type) {
+		case params.ParameterChangeProposal:
+			changes = c.Changes
+		case *params.ParameterChangeProposal:
+			changes = c.Changes
+		default:
+			return next(ctx, content)
+		}
+		if !changesWasmParams(changes) {
+			return next(ctx, content)
+		}
+
+		cacheCtx, writeCache := ctx.CacheContext()
+		if err := next(cacheCtx, content); err != nil {
+			return err
+		}
+		if err := k.GetParams(cacheCtx).ValidateBasic(); err != nil {
+			return ToSDKError(sdkErrors.Wrap(ErrInvalidMsg, err.Error()))
+		}
+		writeCache()
+		return nil
+	}
+}
+
+func changesWasmParams(changes []params.ParamChange) bool {
+	for _, c := range changes {
+		if c.Subspace == DefaultParamspace {
+			return true
+		}
+	}
+	return false
+}
+
+func handleStoreCodeProposal(ctx sdk.Context, k Keeper, p StoreCodeProposal) error {
+	if err := p.ValidateBasic(); err != nil {
+		return err
+	}
+	if uint64(len(p.WASMByteCode)) > k.GetParams(ctx).MaxWasmCodeSize {
+		return sdkErrors.Wrapf(ErrCodeTooLarge, "max %d bytes", k.GetParams(ctx).MaxWasmCodeSize)
+	}
+
+	codeID, err := k.Create(ctx, p.RunAs, p.WASMByteCode, p.Source, p.Builder, p.InstantiatePermission)
+	if err != nil {
+		return err
+	}
+
+	ctx.EventManager().EmitEvent(sdk.NewEvent(
+		EventTypeStoreCode,
+		sdk.NewAttribute(AttributeKeyCodeID, fmt.Sprintf("%d", codeID)),
+		sdk.NewAttribute(AttributeKeyCreator, p.RunAs.String()),
+	))
+	return nil
+}
+
+func handleMigrateProposal(ctx sdk.Context, k Keeper, p MigrateContractProposal) error {
+	if err := p.ValidateBasic(); err != nil {
+		return err
+	}
+
+	if err := k.MigrateByGovernance(ctx, p.Contract, p.CodeID, p.MigrateMsg); err != nil {
+		return err
+	}
+
+	ctx.EventManager().EmitEvent(sdk.NewEvent(
+		EventTypeMigrate,
+		sdk.NewAttribute(AttributeKeyContract, p.Contract.String()),
+		sdk.NewAttribute(AttributeKeyCodeID, fmt.Sprintf("%d", p.CodeID)),
+		sdk.NewAttribute(AttributeKeySender, p.RunAs.String()),
+	))
+	return nil
+}
+
+func handleMigrateAllContractsProposal(ctx sdk.Context, k Keeper, p MigrateAllContractsProposal) error {
+	if err := p.ValidateBasic(); err != nil {
+		return err
+	}
+
+	migrated, err := k.MigrateAllContractsByGovernance(ctx, p.OldCodeID, p.NewCodeID, p.MigrateMsg)
+	if err != nil {
+		return err
+	}
+
+	for _, contractAddr := range migrated {
+		ctx.EventManager().EmitEvent(sdk.NewEvent(
+			EventTypeMigrate,
+			sdk.NewAttribute(AttributeKeyContract, contractAddr.String()),
+			sdk.NewAttribute(AttributeKeyCodeID, fmt.Sprintf("%d", p.NewCodeID)),
+			sdk.NewAttribute(AttributeKeySender, p.RunAs.String()),
+		))
+	}
+	return nil
+}
+
+func handlePinCodesProposal(ctx sdk.Context, k Keeper, p PinCodesProposal) error {
+	if err := p.ValidateBasic(); err != nil {
+		return err
+	}
+	for _, v := range p.CodeIDs {
+		if err := k.PinCode(ctx, v); err != nil {
+			return sdkErrors.Wrapf(err, "code id: %d", v)
+		}
+		ctx.EventManager().EmitEvent(sdk.NewEvent(
+			EventTypePinCode,
+			sdk.NewAttribute(AttributeKeyCodeID, fmt.Sprintf("%d", v)),
+		))
+	}
+	return nil
+}
+
+func handleUnpinCodesProposal(ctx sdk.Context, k Keeper, p UnpinCodesProposal) error {
+	if err := p.ValidateBasic(); err != nil {
+		return err
+	}
+	for _, v := range p.CodeIDs {
+		if err := k.UnpinCode(ctx, v); err != nil {
+			return sdkErrors.Wrapf(err, "code id: %d", v)
+		}
+		ctx.EventManager().EmitEvent(sdk.NewEvent(
+			EventTypeUnpinCode,
+			sdk.NewAttribute(AttributeKeyCodeID, fmt.Sprintf("%d", v)),
+		))
+	}
+	return nil
+}
+
+func handleImportContractStateProposal(ctx sdk.Context, k Keeper, p ImportContractStateProposal) error {
+	if err := p.ValidateBasic(); err != nil {
+		return err
+	}
+
+	if err := k.ImportContractState(ctx, p.Contract, p.Models); err != nil {
+		return err
+	}
+
+	ctx.EventManager().EmitEvent(sdk.NewEvent(
+		EventTypeImportContractState,
+		sdk.NewAttribute(AttributeKeyContract, p.Contract.String()),
+	))
+	return nil
+}
+
+func handleUpdateInstantiateConfigProposal(ctx sdk.Context, k Keeper, p UpdateInstantiateConfigProposal) error {
+	if err := p.ValidateBasic(); err != nil {
+		return err
+	}
+
+	if err := k.UpdateInstantiateConfigByGovernance(ctx, p.CodeID, p.NewPermission); err != nil {
+		return err
+	}
+
+	ctx.EventManager().EmitEvent(sdk.NewEvent(
+		EventTypeUpdateInstantiateConfig,
+		sdk.NewAttribute(AttributeKeyCodeID, fmt.Sprintf("%d", p.CodeID)),
+	))
+	return nil
+}
+
+func handleDeprecateCodeProposal(ctx sdk.Context, k Keeper, p DeprecateCodeProposal) error {
+	if err := p.ValidateBasic(); err != nil {
+		return err
+	}
+
+	if err := k.DeprecateCodeByGovernance(ctx, p.CodeID, p.Deprecated); err != nil {
+		return err
+	}
+
+	ctx.EventManager().EmitEvent(sdk.NewEvent(
+		EventTypeDeprecateCode,
+		sdk.NewAttribute(AttributeKeyCodeID, fmt.Sprintf("%d", p.CodeID)),
+	))
+	return nil
+}
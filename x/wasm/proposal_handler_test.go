@@ -0,0 +1,412 @@
+package wasm
+
+import (
+	"encoding/json"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreCodeProposal(t *testing.T) {
+	data, cleanup := setupTest(t)
+	defer cleanup()
+
+	h := NewWasmProposalHandler(data.keeper)
+	q := data.module.NewQuerierHandler()
+
+	specs := map[string]struct {
+		proposal StoreCodeProposal
+		expErr   bool
+	}{
+		"all good": {
+			proposal: StoreCodeProposal{
+				Title:        "Foo",
+				Description:  "Bar",
+				RunAs:        addr1,
+				WASMByteCode: testContract,
+			},
+		},
+		"without run as": {
+			proposal: StoreCodeProposal{
+				Title:        "Foo",
+				Description:  "Bar",
+				WASMByteCode: testContract,
+			},
+			expErr: true,
+		},
+		"without title": {
+			proposal: StoreCodeProposal{
+				Description:  "Bar",
+				RunAs:        addr1,
+				WASMByteCode: testContract,
+			},
+			expErr: true,
+		},
+		"invalid wasm": {
+			proposal: StoreCodeProposal{
+				Title:        "Foo",
+				Description:  "Bar",
+				RunAs:        addr1,
+				WASMByteCode: []byte("foobar"),
+			},
+			expErr: true,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			ctx, _ := data.ctx.CacheContext()
+			err := h(ctx, spec.proposal)
+			if spec.expErr {
+				require.Error(t, err)
+				assertCodeList(t, q, ctx, 0)
+				return
+			}
+			require.NoError(t, err)
+			assertCodeList(t, q, ctx, 1)
+			assertCodeBytes(t, q, ctx, 1, testContract)
+			info := data.keeper.GetCodeInfo(ctx, 1)
+			require.NotNil(t, info)
+			assert.Equal(t, addr1, info.Creator)
+		})
+	}
+}
+
+func TestWasmProposalHandlerRejectsUnknownContent(t *testing.T) {
+	data, cleanup := setupTest(t)
+	defer cleanup()
+
+	h := NewWasmProposalHandler(data.keeper)
+	err := h(data.ctx, nil)
+	require.Error(t, err)
+	assert.Equal(t, sdk.CodeUnknownRequest, err.Code())
+}
+
+func TestMigrateContractProposal(t *testing.T) {
+	data, cleanup := setupTest(t)
+	defer cleanup()
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(data.ctx, data.acctKeeper, deposit)
+
+	originalCodeID, err := data.keeper.Create(data.ctx, creator, testContract, "", "", nil)
+	require.NoError(t, err)
+	newCodeID, err := data.keeper.Create(data.ctx, creator, testContract, "", "", nil)
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	_, _, fred := keyPubAddr()
+	initMsgBz, err := json.Marshal(initMsg{Verifier: fred, Beneficiary: bob})
+	require.NoError(t, err)
+	// no admin, so only governance can migrate
+	contractAddr, err := data.keeper.Instantiate(data.ctx, originalCodeID, creator, nil, initMsgBz, "demo contract", false, nil)
+	require.NoError(t, err)
+
+	h := NewWasmProposalHandler(data.keeper)
+	specs := map[string]struct {
+		proposal  MigrateContractProposal
+		expErr    bool
+		expCodeID uint64
+	}{
+		"all good": {
+			proposal: MigrateContractProposal{
+				Title:       "Foo",
+				Description: "Bar",
+				Contract:    contractAddr,
+				CodeID:      newCodeID,
+				MigrateMsg:  []byte(`{}`),
+				RunAs:       creator,
+			},
+			expCodeID: newCodeID,
+		},
+		"unknown code": {
+			proposal: MigrateContractProposal{
+				Title:       "Foo",
+				Description: "Bar",
+				Contract:    contractAddr,
+				CodeID:      999,
+				MigrateMsg:  []byte(`{}`),
+				RunAs:       creator,
+			},
+			expErr:    true,
+			expCodeID: originalCodeID,
+		},
+		"invalid migrate msg": {
+			proposal: MigrateContractProposal{
+				Title:       "Foo",
+				Description: "Bar",
+				Contract:    contractAddr,
+				CodeID:      newCodeID,
+				MigrateMsg:  []byte("not json"),
+				RunAs:       creator,
+			},
+			expErr:    true,
+			expCodeID: originalCodeID,
+		},
+		"without contract": {
+			proposal: MigrateContractProposal{
+				Title:       "Foo",
+				Description: "Bar",
+				CodeID:      newCodeID,
+				MigrateMsg:  []byte(`{}`),
+				RunAs:       creator,
+			},
+			expErr:    true,
+			expCodeID: originalCodeID,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			ctx, _ := data.ctx.CacheContext()
+			err := h(ctx, spec.proposal)
+			if spec.expErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, spec.expCodeID, data.keeper.GetContractInfo(ctx, contractAddr).CodeID)
+		})
+	}
+}
+
+func TestPinUnpinCodesProposal(t *testing.T) {
+	data, cleanup := setupTest(t)
+	defer cleanup()
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(data.ctx, data.acctKeeper, deposit)
+
+	codeID, err := data.keeper.Create(data.ctx, creator, testContract, "", "", nil)
+	require.NoError(t, err)
+
+	h := NewWasmProposalHandler(data.keeper)
+
+	// unknown codes are rejected
+	err = h(data.ctx, PinCodesProposal{Title: "Foo", Description: "Bar", CodeIDs: []uint64{codeID, 999}})
+	require.Error(t, err)
+
+	// empty list is rejected
+	err = h(data.ctx, PinCodesProposal{Title: "Foo", Description: "Bar"})
+	require.Error(t, err)
+
+	err = h(data.ctx, PinCodesProposal{Title: "Foo", Description: "Bar", CodeIDs: []uint64{codeID}})
+	require.NoError(t, err)
+	assert.True(t, data.keeper.IsPinnedCode(data.ctx, codeID))
+
+	err = h(data.ctx, UnpinCodesProposal{Title: "Foo", Description: "Bar", CodeIDs: []uint64{codeID}})
+	require.NoError(t, err)
+	assert.False(t, data.keeper.IsPinnedCode(data.ctx, codeID))
+}
+
+func TestImportContractStateProposal(t *testing.T) {
+	data, cleanup := setupTest(t)
+	defer cleanup()
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(data.ctx, data.acctKeeper, deposit)
+
+	codeID, err := data.keeper.Create(data.ctx, creator, testContract, "", "", nil)
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	_, _, fred := keyPubAddr()
+	initMsgBz, err := json.Marshal(initMsg{Verifier: fred, Beneficiary: bob})
+	require.NoError(t, err)
+	contractAddr, err := data.keeper.Instantiate(data.ctx, codeID, creator, nil, initMsgBz, "demo contract", false, nil)
+	require.NoError(t, err)
+	_, _, nonContract := keyPubAddr()
+
+	models := []Model{
+		{Key: []byte("foo"), Value: []byte("bar")},
+		{Key: []byte{0x1, 0x2}, Value: []byte("baz")},
+	}
+
+	h := NewWasmProposalHandler(data.keeper)
+	specs := map[string]struct {
+		proposal ImportContractStateProposal
+		expErr   bool
+	}{
+		"all good": {
+			proposal: ImportContractStateProposal{
+				Title:       "Foo",
+				Description: "Bar",
+				Contract:    contractAddr,
+				Models:      models,
+			},
+		},
+		"unknown contract": {
+			proposal: ImportContractStateProposal{
+				Title:       "Foo",
+				Description: "Bar",
+				Contract:    nonContract,
+				Models:      models,
+			},
+			expErr: true,
+		},
+		"duplicate keys": {
+			proposal: ImportContractStateProposal{
+				Title:       "Foo",
+				Description: "Bar",
+				Contract:    contractAddr,
+				Models:      append([]Model{{Key: []byte("foo"), Value: []byte("other")}}, models...),
+			},
+			expErr: true,
+		},
+		"empty key": {
+			proposal: ImportContractStateProposal{
+				Title:       "Foo",
+				Description: "Bar",
+				Contract:    contractAddr,
+				Models:      []Model{{Value: []byte("bar")}},
+			},
+			expErr: true,
+		},
+		"without models": {
+			proposal: ImportContractStateProposal{
+				Title:       "Foo",
+				Description: "Bar",
+				Contract:    contractAddr,
+			},
+			expErr: true,
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			ctx, _ := data.ctx.CacheContext()
+			err := h(ctx, spec.proposal)
+			if spec.expErr {
+				require.Error(t, err)
+				assert.Empty(t, data.keeper.QueryRaw(ctx, contractAddr, []byte("foo")))
+				return
+			}
+			require.NoError(t, err)
+			for _, m := range models {
+				assert.Equal(t, []Model{m}, data.keeper.QueryRaw(ctx, contractAddr, m.Key))
+			}
+			// the state of the contract is kept
+			assert.Len(t, data.keeper.QueryRaw(ctx, contractAddr, []byte("config")), 1)
+		})
+	}
+}
+
+func TestUpdateInstantiateConfigProposal(t *testing.T) {
+	data, cleanup := setupTest(t)
+	defer cleanup()
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(data.ctx, data.acctKeeper, deposit)
+
+	codeID, err := data.keeper.Create(data.ctx, creator, testContract, "", "", nil)
+	require.NoError(t, err)
+
+	h := NewWasmProposalHandler(data.keeper)
+	// governance can lock out the creator without any confirmation
+	err = h(data.ctx, UpdateInstantiateConfigProposal{Title: "Foo", Description: "Bar", CodeID: codeID, NewPermission: AllowNobody})
+	require.NoError(t, err)
+	assert.Equal(t, AllowNobody, data.keeper.GetCodeInfo(data.ctx, codeID).InstantiateConfig)
+
+	err = h(data.ctx, UpdateInstantiateConfigProposal{Title: "Foo", Description: "Bar", CodeID: 999, NewPermission: AllowNobody})
+	assert.Error(t, err)
+	err = h(data.ctx, UpdateInstantiateConfigProposal{Title: "Foo", Description: "Bar", CodeID: codeID})
+	assert.Error(t, err)
+}
+
+func TestDeprecateCodeProposal(t *testing.T) {
+	data, cleanup := setupTest(t)
+	defer cleanup()
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(data.ctx, data.acctKeeper, deposit)
+
+	codeID, err := data.keeper.Create(data.ctx, creator, testContract, "", "", nil)
+	require.NoError(t, err)
+
+	h := NewWasmProposalHandler(data.keeper)
+	err = h(data.ctx, DeprecateCodeProposal{Title: "Foo", Description: "Bar", CodeID: codeID, Deprecated: true})
+	require.NoError(t, err)
+	assert.True(t, data.keeper.GetCodeInfo(data.ctx, codeID).Deprecated)
+
+	err = h(data.ctx, DeprecateCodeProposal{Title: "Foo", Description: "Bar", CodeID: codeID})
+	require.NoError(t, err)
+	assert.False(t, data.keeper.GetCodeInfo(data.ctx, codeID).Deprecated)
+
+	err = h(data.ctx, DeprecateCodeProposal{Title: "Foo", Description: "Bar", CodeID: 999, Deprecated: true})
+	assert.Error(t, err)
+}
+
+func TestMigrateAllContractsProposal(t *testing.T) {
+	data, cleanup := setupTest(t)
+	defer cleanup()
+
+	deposit := sdk.NewCoins(sdk.NewInt64Coin("denom", 100000))
+	creator := createFakeFundedAccount(data.ctx, data.acctKeeper, deposit)
+
+	oldCodeID, err := data.keeper.Create(data.ctx, creator, testContract, "", "", nil)
+	require.NoError(t, err)
+	newCodeID, err := data.keeper.Create(data.ctx, creator, testContract, "", "", nil)
+	require.NoError(t, err)
+
+	_, _, bob := keyPubAddr()
+	_, _, fred := keyPubAddr()
+	initMsgBz, err := json.Marshal(initMsg{Verifier: fred, Beneficiary: bob})
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err := data.keeper.Instantiate(data.ctx, oldCodeID, creator, nil, initMsgBz, "demo contract", false, nil)
+		require.NoError(t, err)
+	}
+	params := data.keeper.GetParams(data.ctx)
+	params.MaxMigrateAllContracts = 2
+	data.keeper.SetParams(data.ctx, params)
+
+	h := NewWasmProposalHandler(data.keeper)
+	proposal := MigrateAllContractsProposal{
+		Title:       "Foo",
+		Description: "Bar",
+		OldCodeID:   oldCodeID,
+		NewCodeID:   newCodeID,
+		MigrateMsg:  []byte(`{}`),
+		RunAs:       creator,
+	}
+
+	// the first proposal is bounded by the param, the second one completes the sweep
+	require.NoError(t, h(data.ctx, proposal))
+	assert.Equal(t, uint64(1), data.keeper.GetContractCountByCode(data.ctx, oldCodeID))
+	assert.Equal(t, uint64(2), data.keeper.GetContractCountByCode(data.ctx, newCodeID))
+
+	require.NoError(t, h(data.ctx, proposal))
+	assert.Equal(t, uint64(0), data.keeper.GetContractCountByCode(data.ctx, oldCodeID))
+	assert.Equal(t, uint64(3), data.keeper.GetContractCountByCode(data.ctx, newCodeID))
+
+	// nothing left to migrate
+	require.NoError(t, h(data.ctx, proposal))
+	assert.Equal(t, uint64(3), data.keeper.GetContractCountByCode(data.ctx, newCodeID))
+
+	specs := map[string]struct {
+		src func(p *MigrateAllContractsProposal)
+	}{
+		"unknown new code": {
+			src: func(p *MigrateAllContractsProposal) { p.NewCodeID = 999 },
+		},
+		"unknown old code": {
+			src: func(p *MigrateAllContractsProposal) { p.OldCodeID = 999 },
+		},
+		"same code": {
+			src: func(p *MigrateAllContractsProposal) { p.NewCodeID = p.OldCodeID },
+		},
+		"invalid migrate msg": {
+			src: func(p *MigrateAllContractsProposal) { p.MigrateMsg = []byte("not json") },
+		},
+		"without run as": {
+			src: func(p *MigrateAllContractsProposal) { p.RunAs = nil },
+		},
+	}
+	for msg, spec := range specs {
+		t.Run(msg, func(t *testing.T) {
+			p := proposal
+			spec.src(&p)
+			ctx, _ := data.ctx.CacheContext()
+			require.Error(t, h(ctx, p))
+		})
+	}
+}